@@ -6,7 +6,10 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
@@ -31,7 +34,12 @@ func main() {
 		logrus.SetLevel(logrus.TraceLevel)
 	}
 
-	if err := plugin.Exec(context.Background(), args); err != nil {
+	// Cancel the operation on interrupt so that running commands are stopped
+	// and temporary credentials are removed before the plugin exits
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := plugin.Exec(ctx, args)
+	stop()
+	if err != nil {
 		logrus.Fatalln(err)
 	}
 }
@@ -0,0 +1,106 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harness/drone-har/plugin/packages"
+	"github.com/sirupsen/logrus"
+)
+
+// droneOutput names the env file read by Drone to expose step outputs
+const droneOutput = "DRONE_OUTPUT"
+
+// resultOutputs converts an operation result into step output variables.
+// Empty values are omitted.
+func resultOutputs(result *packages.Result) map[string]string {
+	outputs := map[string]string{}
+	if result == nil {
+		return outputs
+	}
+
+	set := func(key, value string) {
+		if value != "" {
+			outputs[key] = value
+		}
+	}
+	set("ARTIFACT_PACKAGE_TYPE", result.PackageType.Label())
+	set("ARTIFACT_REGISTRY", result.Registry)
+	set("ARTIFACT_NAME", result.Name)
+	set("ARTIFACT_VERSION", result.Version)
+	set("ARTIFACT_FILENAME", result.Filename)
+	set("ARTIFACT_PATH", result.Path)
+	set("ARTIFACT_URL", result.URL)
+	set("ARTIFACT_SHA256", result.SHA256)
+	set("ARTIFACT_SHA1", result.SHA1)
+	set("ARTIFACT_MD5", result.MD5)
+	if result.Size > 0 {
+		set("ARTIFACT_SIZE", strconv.FormatInt(result.Size, 10))
+	}
+
+	// Multi-file operations list every file they handled
+	if len(result.Files) > 0 {
+		var paths, urls []string
+		for _, file := range result.Files {
+			if file == nil {
+				continue
+			}
+			if file.Path != "" {
+				paths = append(paths, file.Path)
+			}
+			if file.URL != "" {
+				urls = append(urls, file.URL)
+			}
+		}
+		set("ARTIFACT_FILE_COUNT", strconv.Itoa(len(result.Files)))
+		set("ARTIFACT_FILES", strings.Join(paths, ","))
+		set("ARTIFACT_URLS", strings.Join(urls, ","))
+	}
+
+	for key, value := range result.Metadata {
+		set(strings.ToUpper(key), value)
+	}
+	return outputs
+}
+
+// writeOutputs appends the result to the Drone output file as KEY=value
+// lines. Nothing is written when the file is not configured.
+func writeOutputs(path string, result *packages.Result) error {
+	if path == "" || result == nil {
+		return nil
+	}
+
+	outputs := resultOutputs(result)
+	keys := make([]string, 0, len(outputs))
+	for key := range outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		// Values must fit on a single line of the env file
+		value := strings.NewReplacer("\r", " ", "\n", " ").Replace(outputs[key])
+		fmt.Fprintf(&b, "%s=%s\n", key, value)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	logrus.Printf("Exported %d output variables", len(keys))
+	return nil
+}
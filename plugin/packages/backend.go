@@ -0,0 +1,182 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Supported backend names
+const (
+	BackendCLI  = "cli"
+	BackendHTTP = "http"
+)
+
+// Backend performs registry operations on behalf of the package handlers.
+// Handlers validate and resolve what should be transferred, the backend
+// decides how it reaches the registry.
+type Backend interface {
+	// Push uploads a single file to the registry
+	Push(ctx context.Context, req PushRequest) (*Result, error)
+
+	// Pull downloads a single file from the registry
+	Pull(ctx context.Context, req PullRequest) (*Result, error)
+
+	// Get retrieves artifact information
+	Get(ctx context.Context, req ArtifactRequest) (*Result, error)
+
+	// Delete removes an artifact, or a single version of it, from the registry
+	Delete(ctx context.Context, req ArtifactRequest) (*Result, error)
+
+	// VersionFiles lists the files of an artifact version with the
+	// checksums stored by the registry
+	VersionFiles(ctx context.Context, req ArtifactRequest) ([]RegistryFile, error)
+
+	// Read fetches a package endpoint the operations above do not cover,
+	// such as the dist-tags of an npm package. Endpoints answering with a
+	// non-2xx status fail with a *StatusError.
+	Read(ctx context.Context, req EndpointRequest) ([]byte, error)
+
+	// Write uploads content to a package endpoint, such as a file of the
+	// Maven repository layout
+	Write(ctx context.Context, req EndpointRequest) error
+
+	// SetDistTag points an npm dist-tag of a package at a version
+	SetDistTag(ctx context.Context, req DistTagRequest) error
+
+	// RemoveDistTag deletes an npm dist-tag of a package
+	RemoveDistTag(ctx context.Context, req DistTagRequest) error
+
+	// Close releases resources held by the backend, such as credentials
+	// written to disk. It must be called once the operation completes.
+	Close() error
+}
+
+// PushRequest describes a single file upload
+type PushRequest struct {
+	PackageType PackageType
+	Config      Config
+
+	// FilePath is the local file to upload
+	FilePath string
+
+	// Name and Version identify the artifact. Generic packages pass them
+	// explicitly, other package types derive them from the file itself.
+	Name    string
+	Version string
+
+	// Path is the location of the file inside a generic package
+	Path string
+}
+
+// targetFilename returns the name of the pushed file inside the package,
+// its relative path for directory uploads
+func (r PushRequest) targetFilename() string {
+	if r.Path != "" {
+		return r.Path
+	}
+	if r.Config.Filename != "" {
+		return r.Config.Filename
+	}
+	return filepath.Base(r.FilePath)
+}
+
+// PullRequest describes a single file download
+type PullRequest struct {
+	PackageType PackageType
+	Config      Config
+
+	Name     string
+	Version  string
+	Filename string
+
+	// Path is the registry path of the file, e.g. <name>/<version>/<filename>
+	Path string
+
+	// Destination is the local directory or file to download into
+	Destination string
+}
+
+// ArtifactRequest identifies an artifact for get and delete operations
+type ArtifactRequest struct {
+	PackageType PackageType
+	Config      Config
+
+	Name string
+
+	// Version is optional; when empty the operation applies to the whole artifact
+	Version string
+}
+
+// EndpointRequest addresses a package endpoint of the registry, below
+// <pkg_url>/pkg/<account>/<registry>/<package type>/
+type EndpointRequest struct {
+	PackageType PackageType
+	Config      Config
+
+	// Path is the escaped path of the endpoint below the package type,
+	// e.g. -/package/my-pkg/dist-tags for the dist-tags of an npm package
+	Path string
+
+	// Open returns the content of a write, it is called again for every
+	// retry. Size is the length of the content.
+	Open        func() (io.ReadCloser, error)
+	Size        int64
+	ContentType string
+}
+
+// DistTagRequest identifies an npm dist-tag of a package
+type DistTagRequest struct {
+	Config Config
+
+	Name string
+	Tag  string
+
+	// Version is the version the tag points at, unused for removals
+	Version string
+}
+
+// Result describes the outcome of a registry operation
+type Result struct {
+	PackageType PackageType `json:"package_type,omitempty"`
+	Registry    string      `json:"registry,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Version     string      `json:"version,omitempty"`
+	Filename    string      `json:"filename,omitempty"`
+	Path        string      `json:"path,omitempty"`
+	URL         string      `json:"url,omitempty"`
+	Size        int64       `json:"size,omitempty"`
+	SHA256      string      `json:"sha256,omitempty"`
+	SHA1        string      `json:"sha1,omitempty"`
+	MD5         string      `json:"md5,omitempty"`
+
+	// Metadata holds additional package specific outputs, keyed by the
+	// name of the output variable
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Files holds the result of every file of a multi-file operation
+	Files []*Result `json:"files,omitempty"`
+
+	// Raw holds the response returned by the registry, if any
+	Raw json.RawMessage `json:"raw,omitempty"`
+}
+
+// NewBackend returns the backend registered under the given name,
+// defaulting to the Harness CLI backend when name is empty
+func NewBackend(name string) (Backend, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", BackendCLI:
+		return NewCLIBackend(nil), nil
+	case BackendHTTP:
+		return NewHTTPBackend(nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s. Supported backends: %s, %s", name, BackendCLI, BackendHTTP)
+	}
+}
@@ -17,9 +17,9 @@ type CargoHandler struct {
 }
 
 // NewCargoHandler creates a new Cargo package handler
-func NewCargoHandler() *CargoHandler {
+func NewCargoHandler(backend Backend) *CargoHandler {
 	return &CargoHandler{
-		BaseHandler: NewBaseHandler(Cargo),
+		BaseHandler: NewBaseHandler(Cargo, backend),
 	}
 }
 
@@ -44,44 +44,43 @@ func (h *CargoHandler) Validate(config Config) error {
 }
 
 // Push uploads Cargo packages to the registry
-func (h *CargoHandler) Push(ctx context.Context, config Config) error {
+func (h *CargoHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing Cargo push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
 // pushSingleFile handles pushing a single file for Cargo packages
-func (h *CargoHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Cargo)
-	cmdArgs, err := buildPushCommand(Cargo, config, "", filePath, artifactName, false)
-	if err != nil {
-		return err
-	}
-
-	return executeCommand(cmdArgs, fmt.Sprintf("push Cargo artifact '%s' to registry '%s'", artifactName, config.Registry))
+func (h *CargoHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Cargo,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+	})
 }
 
 // Pull downloads Cargo packages from the registry
-func (h *CargoHandler) Pull(ctx context.Context, config Config) error {
+func (h *CargoHandler) Pull(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Cargo pull logic
-	return fmt.Errorf("Cargo pull is not yet implemented")
+	return nil, fmt.Errorf("Cargo pull is not yet implemented")
 }
 
 // Get retrieves Cargo package information
-func (h *CargoHandler) Get(ctx context.Context, config Config) error {
+func (h *CargoHandler) Get(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Cargo get logic
-	return fmt.Errorf("Cargo get is not yet implemented")
+	return nil, fmt.Errorf("Cargo get is not yet implemented")
 }
 
 // Delete removes Cargo packages from the registry
-func (h *CargoHandler) Delete(ctx context.Context, config Config) error {
+func (h *CargoHandler) Delete(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Cargo delete logic
-	return fmt.Errorf("Cargo delete is not yet implemented")
+	return nil, fmt.Errorf("Cargo delete is not yet implemented")
 }
@@ -0,0 +1,172 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// checksumsFilename is the name of the companion file listing the SHA-256
+// checksums of pushed files
+const checksumsFilename = "SHA256SUMS"
+
+// Checksums holds the size and digests of a file
+type Checksums struct {
+	Size   int64
+	SHA256 string
+	SHA1   string
+	MD5    string
+}
+
+// checksumWriter computes every supported digest of the data written to it
+type checksumWriter struct {
+	size   int64
+	sha256 hash.Hash
+	sha1   hash.Hash
+	md5    hash.Hash
+}
+
+func newChecksumWriter() *checksumWriter {
+	return &checksumWriter{
+		sha256: sha256.New(),
+		sha1:   sha1.New(),
+		md5:    md5.New(),
+	}
+}
+
+func (w *checksumWriter) Write(p []byte) (int, error) {
+	w.sha256.Write(p)
+	w.sha1.Write(p)
+	w.md5.Write(p)
+	w.size += int64(len(p))
+	return len(p), nil
+}
+
+// Checksums returns the digests of the data written so far
+func (w *checksumWriter) Checksums() Checksums {
+	return Checksums{
+		Size:   w.size,
+		SHA256: hex.EncodeToString(w.sha256.Sum(nil)),
+		SHA1:   hex.EncodeToString(w.sha1.Sum(nil)),
+		MD5:    hex.EncodeToString(w.md5.Sum(nil)),
+	}
+}
+
+// fileChecksums reads a file and returns its size and digests
+func fileChecksums(path string) (Checksums, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Checksums{}, err
+	}
+	defer file.Close()
+
+	w := newChecksumWriter()
+	if _, err := io.Copy(w, file); err != nil {
+		return Checksums{}, err
+	}
+	return w.Checksums(), nil
+}
+
+// logChecksums prints the digests of a pushed file
+func logChecksums(ctx context.Context, filePath string, checksums Checksums) {
+	logger := loggerFrom(ctx)
+	logger.Printf("Checksums of %s (%d bytes):", filePath, checksums.Size)
+	logger.Printf("  SHA-256: %s", checksums.SHA256)
+	logger.Printf("  SHA-1:   %s", checksums.SHA1)
+	logger.Printf("  MD5:     %s", checksums.MD5)
+}
+
+// applyChecksums copies the digests into the result
+func (r *Result) applyChecksums(checksums Checksums) {
+	r.Size = checksums.Size
+	r.SHA256 = checksums.SHA256
+	r.SHA1 = checksums.SHA1
+	r.MD5 = checksums.MD5
+}
+
+// verifyPull checks the SHA-256 checksum of a downloaded file against the
+// expected_sha256 setting or, with verify_checksum, against the checksum
+// the backend lists for the file. The file is removed when the checksums
+// differ.
+func verifyPull(ctx context.Context, backend Backend, config Config, result *Result) error {
+	expected := strings.TrimSpace(config.ExpectedSHA256)
+	source := "expected_sha256"
+	if expected == "" && config.VerifyChecksum {
+		var sum string
+		files, err := backend.VersionFiles(ctx, ArtifactRequest{
+			PackageType: result.PackageType,
+			Config:      config,
+			Name:        result.Name,
+			Version:     result.Version,
+		})
+		if err == nil {
+			sum, err = fileSHA256(files, result.Name, result.Version, result.Filename)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get checksum of '%s' from registry: %w", result.Filename, err)
+		}
+		if sum == "" {
+			return fmt.Errorf("registry did not report a SHA-256 checksum for '%s'", result.Filename)
+		}
+		expected = sum
+		source = "registry"
+	}
+	if expected == "" {
+		return nil
+	}
+	return verifyFileSHA256(ctx, result, expected, source)
+}
+
+// verifyFileSHA256 checks the SHA-256 checksum of a downloaded file, source
+// names where the expected checksum comes from. The file is removed when
+// the checksums differ.
+func verifyFileSHA256(ctx context.Context, result *Result, expected, source string) error {
+	info, err := os.Stat(result.Path)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("downloaded file not found at '%s', cannot verify checksum", result.Path)
+	}
+	checksums, err := fileChecksums(result.Path)
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum of '%s': %w", result.Path, err)
+	}
+	result.applyChecksums(checksums)
+
+	if !strings.EqualFold(checksums.SHA256, expected) {
+		if err := os.Remove(result.Path); err != nil {
+			loggerFrom(ctx).Printf("Warning: failed to remove '%s': %v", result.Path, err)
+		}
+		return fmt.Errorf("checksum mismatch for '%s': expected SHA-256 %s (%s), got %s, the file was removed",
+			result.Path, strings.ToLower(expected), source, checksums.SHA256)
+	}
+
+	loggerFrom(ctx).Printf("✓ Verified SHA-256 checksum of %s against %s", result.Path, source)
+	return nil
+}
+
+// checksumsFile renders SHA256SUMS content in the format of sha256sum,
+// sums maps the path of each file inside the package to its checksum
+func checksumsFile(sums map[string]string) []byte {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+	return []byte(b.String())
+}
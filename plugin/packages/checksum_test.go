@@ -0,0 +1,141 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	helloSHA1   = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+)
+
+// writeHello writes a file containing "hello" and returns its path
+func writeHello(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileChecksums(t *testing.T) {
+	checksums, err := fileChecksums(writeHello(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Checksums{Size: 5, SHA256: helloSHA256, SHA1: helloSHA1, MD5: helloMD5}
+	if checksums != want {
+		t.Errorf("fileChecksums() = %+v, want %+v", checksums, want)
+	}
+}
+
+func TestVerifyPull_ExpectedSHA256(t *testing.T) {
+	captureLogs(t)
+
+	t.Run("match", func(t *testing.T) {
+		result := &Result{Path: writeHello(t)}
+		config := Config{ExpectedSHA256: strings.ToUpper(helloSHA256)}
+		if err := verifyPull(context.Background(), NewHTTPBackend(nil), config, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.SHA256 != helloSHA256 {
+			t.Errorf("result checksum was not set: %q", result.SHA256)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		result := &Result{Path: writeHello(t)}
+		config := Config{ExpectedSHA256: strings.Repeat("0", 64)}
+		err := verifyPull(context.Background(), NewHTTPBackend(nil), config, result)
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("expected checksum mismatch, got %v", err)
+		}
+		if _, err := os.Stat(result.Path); !os.IsNotExist(err) {
+			t.Errorf("mismatching file was not removed: %v", err)
+		}
+	})
+}
+
+func TestVerifyPull_RegistryChecksum(t *testing.T) {
+	captureLogs(t)
+
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.EscapedPath()
+		fmt.Fprintf(w, `{"data": {"files": [
+			{"name": "other.txt", "checksums": ["SHA-256: %s"]},
+			{"name": "hello.txt", "checksums": ["SHA-1: %s", "SHA-256: %s"]}
+		]}}`, strings.Repeat("0", 64), helloSHA1, helloSHA256)
+	}))
+	defer server.Close()
+
+	config := Config{
+		Token:          "pat.test",
+		Account:        "acct",
+		Registry:       "generic-local",
+		ApiURL:         server.URL,
+		VerifyChecksum: true,
+	}
+	result := &Result{Name: "my-app", Version: "1.0.0", Filename: "hello.txt", Path: writeHello(t)}
+	if err := verifyPull(context.Background(), NewHTTPBackend(server.Client()), config, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "/gateway/har/api/v1/registry/acct%2Fgeneric-local/+/artifact/my-app/+/version/1.0.0/files"
+	if requested != want {
+		t.Errorf("unexpected request path\n got: %s\nwant: %s", requested, want)
+	}
+}
+
+func TestGenericHandler_PushChecksums(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "dist/a.txt", "dist/sub/b.txt"))
+
+	var sums string
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		if strings.Join(cmd.Args[len(cmd.Args)-2:], " ") == "--path "+checksumsFilename {
+			// args: hc artifact --org o --project p push generic <registry> <file>
+			data, err := os.ReadFile(cmd.Args[9])
+			sums = string(data)
+			return err
+		}
+		return nil
+	}
+
+	config := testConfig()
+	config.Source = "dist"
+	config.UploadChecksums = true
+
+	handler, _ := factory.GetHandler("generic")
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := fileChecksums(filepath.Join("dist", "a.txt"))
+	b, _ := fileChecksums(filepath.Join("dist", "sub", "b.txt"))
+	want := fmt.Sprintf("%s  a.txt\n%s  sub/b.txt\n", a.SHA256, b.SHA256)
+	if sums != want {
+		t.Errorf("unexpected checksums file\n got: %q\nwant: %q", sums, want)
+	}
+	if got := pushedPaths(runner); len(got) != 3 || got[2] != checksumsFilename {
+		t.Errorf("expected the checksums file to be pushed last, got %v", got)
+	}
+	if !strings.HasSuffix(result.Metadata["ARTIFACT_CHECKSUMS_URL"], "/1.0.0/"+checksumsFilename) {
+		t.Errorf("unexpected checksums URL %q", result.Metadata["ARTIFACT_CHECKSUMS_URL"])
+	}
+}
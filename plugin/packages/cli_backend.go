@@ -0,0 +1,459 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CLIBackend performs registry operations by shelling out to the Harness CLI (hc).
+// The CLI runs with a private home directory holding its credentials, so the
+// configuration of the user running the plugin is never read or modified.
+type CLIBackend struct {
+	runner Runner
+
+	// registry reads and writes the package endpoints the Harness CLI has
+	// no command for, such as package metadata and dist-tags
+	registry *HTTPBackend
+
+	// mu guards the per-run configuration directory, which is shared by
+	// files pushed in parallel
+	mu        sync.Mutex
+	configDir string
+	authData  []byte
+}
+
+// NewCLIBackend creates a new Harness CLI backend that executes commands
+// with the given runner. A nil runner executes real child processes.
+func NewCLIBackend(runner Runner) *CLIBackend {
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	return &CLIBackend{
+		runner:   runner,
+		registry: NewHTTPBackend(nil),
+	}
+}
+
+// Push uploads a single file with 'hc artifact push'
+func (b *CLIBackend) Push(ctx context.Context, req PushRequest) (*Result, error) {
+	checksums, err := fileChecksums(req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute checksums of '%s': %w", req.FilePath, err)
+	}
+	logChecksums(ctx, req.FilePath, checksums)
+
+	cmdArgs := buildPushCommand(req)
+
+	operation := fmt.Sprintf("push artifact '%s' to registry '%s'", req.Name, req.Config.Registry)
+	if req.PackageType != Generic {
+		operation = fmt.Sprintf("push %s artifact '%s' to registry '%s'", req.PackageType.Label(), req.Name, req.Config.Registry)
+	}
+
+	if _, err := b.executeCommand(ctx, req.Config, cmdArgs, operation); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		PackageType: req.PackageType,
+		Registry:    req.Config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+		Filename:    path.Base(req.targetFilename()),
+		Path:        req.FilePath,
+	}
+	result.applyChecksums(checksums)
+	if req.PackageType == Generic {
+		result.URL = packageURL(req.Config, "generic", req.Name, req.Version, req.targetFilename())
+	}
+	return result, nil
+}
+
+// Pull downloads a single file with 'hc artifact pull'
+func (b *CLIBackend) Pull(ctx context.Context, req PullRequest) (*Result, error) {
+	config := req.Config
+
+	// Build Harness CLI command
+	cmdArgs := []string{getHarnessBin(), "artifact", "pull", string(req.PackageType), config.Registry, req.Path, req.Destination}
+
+	// Add required flags
+	cmdArgs = append(cmdArgs, "--token", config.Token)
+	cmdArgs = append(cmdArgs, "--account", config.Account)
+	cmdArgs = append(cmdArgs, "--pkg-url", config.PkgURL)
+
+	// Add optional flags
+	cmdArgs = appendScopeFlags(cmdArgs, config)
+
+	// Add format flag for consistent output
+	cmdArgs = append(cmdArgs, "--format", "json")
+
+	output, err := b.executeCommand(ctx, config, cmdArgs, fmt.Sprintf("pull artifact '%s' (version '%s', file '%s') from registry '%s' to '%s'",
+		req.Name, req.Version, req.Filename, config.Registry, req.Destination))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+		Filename:    req.Filename,
+		Path:        req.Destination,
+	}
+	applyCommandOutput(result, output)
+
+	// Describe the downloaded file when the CLI did not report it
+	if req.Filename != "" {
+		local := filepath.Join(req.Destination, req.Filename)
+		if checksums, err := fileChecksums(local); err == nil {
+			result.Path = local
+			if result.SHA256 == "" {
+				result.applyChecksums(checksums)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Get retrieves artifact information with 'hc artifact get'
+func (b *CLIBackend) Get(ctx context.Context, req ArtifactRequest) (*Result, error) {
+	config := req.Config
+
+	// Use 'hc artifact get' command with name as positional arg and registry as flag
+	cmdArgs := []string{getHarnessBin(), "artifact", "get", req.Name}
+
+	// Add required flags
+	cmdArgs = append(cmdArgs, "--registry", config.Registry)
+	cmdArgs = append(cmdArgs, "--token", config.Token)
+	cmdArgs = append(cmdArgs, "--account", config.Account)
+	cmdArgs = appendScopeFlags(cmdArgs, config)
+	cmdArgs = append(cmdArgs, "--format", "json")
+
+	output, err := b.executeCommand(ctx, config, cmdArgs, fmt.Sprintf("get info for artifact '%s' in registry '%s'", req.Name, config.Registry))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+	}
+	applyCommandOutput(result, output)
+	return result, nil
+}
+
+// Delete removes an artifact with 'hc artifact delete'
+func (b *CLIBackend) Delete(ctx context.Context, req ArtifactRequest) (*Result, error) {
+	config := req.Config
+
+	// Use 'hc artifact delete' with name as argument and registry as flag
+	cmdArgs := []string{getHarnessBin(), "artifact", "delete", req.Name}
+
+	// Add required flags
+	cmdArgs = append(cmdArgs, "--registry", config.Registry)
+	cmdArgs = append(cmdArgs, "--token", config.Token)
+	cmdArgs = append(cmdArgs, "--account", config.Account)
+
+	// Add optional flags
+	cmdArgs = appendScopeFlags(cmdArgs, config)
+	if req.Version != "" {
+		cmdArgs = append(cmdArgs, "--version", req.Version)
+	}
+
+	// Add format flag for consistent output
+	cmdArgs = append(cmdArgs, "--format", "json")
+
+	operation := fmt.Sprintf("delete artifact '%s' from registry '%s'", req.Name, config.Registry)
+	if req.Version != "" {
+		operation = fmt.Sprintf("delete version '%s' of artifact '%s' from registry '%s'", req.Version, req.Name, config.Registry)
+	}
+
+	output, err := b.executeCommand(ctx, config, cmdArgs, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+	}
+	applyCommandOutput(result, output)
+	return result, nil
+}
+
+// appendScopeFlags adds the optional org, project and API URL flags
+func appendScopeFlags(cmdArgs []string, config Config) []string {
+	if config.Org != "" {
+		cmdArgs = append(cmdArgs, "--org", config.Org)
+	}
+	if config.Project != "" {
+		cmdArgs = append(cmdArgs, "--project", config.Project)
+	}
+	if config.ApiURL != "" {
+		cmdArgs = append(cmdArgs, "--api-url", config.ApiURL)
+	}
+	return cmdArgs
+}
+
+// AuthConfig represents the Harness authentication configuration
+type AuthConfig struct {
+	BaseURL   string `json:"base_url"`
+	Token     string `json:"token"`
+	AccountID string `json:"account_id"`
+}
+
+// Close removes the configuration directory and the credentials it holds
+func (b *CLIBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.configDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(b.configDir); err != nil {
+		return fmt.Errorf("failed to remove harness config directory: %w", err)
+	}
+	b.configDir = ""
+	b.authData = nil
+	return nil
+}
+
+// commandEnv returns the environment of a Harness CLI command. The home
+// directory points at a temporary directory created for this run, holding
+// .harness/auth.json with the authentication details. Each plugin run gets
+// its own directory, so steps running concurrently on the same host never
+// share or overwrite credentials.
+func (b *CLIBackend) commandEnv(ctx context.Context, config Config) ([]string, error) {
+	// Prepare auth configuration
+	authConfig := AuthConfig{
+		BaseURL:   config.PkgURL,
+		Token:     fmt.Sprintf("CIManager %s", config.Token),
+		AccountID: config.Account,
+	}
+	authData, err := json.MarshalIndent(authConfig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal auth config: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.configDir == "" {
+		// MkdirTemp creates the directory readable by the current user only
+		dir, err := os.MkdirTemp("", "drone-har-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create harness config directory: %w", err)
+		}
+		b.configDir = dir
+		b.authData = nil
+	}
+
+	// The file is only rewritten when its content changes, so commands
+	// running in parallel never observe a partially written file
+	if !bytes.Equal(authData, b.authData) {
+		harnessDir := filepath.Join(b.configDir, ".harness")
+		if err := os.MkdirAll(harnessDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create .harness directory: %w", err)
+		}
+
+		authFile := filepath.Join(harnessDir, "auth.json")
+		if err := os.WriteFile(authFile, authData, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write auth file: %w", err)
+		}
+		b.authData = authData
+
+		loggerFrom(ctx).Printf("Created auth file: %s", authFile)
+	}
+
+	env := os.Environ()
+	env = setEnv(env, "HOME", b.configDir)
+	env = setEnv(env, "USERPROFILE", b.configDir)
+	return env, nil
+}
+
+// setEnv returns env with key set to value, replacing any existing entry
+func setEnv(env []string, key, value string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		// Environment variable names are case insensitive on Windows
+		if name == key || (runtime.GOOS == "windows" && strings.EqualFold(name, key)) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return append(result, key+"="+value)
+}
+
+// VersionFiles lists the files of an artifact version through the registry
+// API, the Harness CLI does not report their checksums
+func (b *CLIBackend) VersionFiles(ctx context.Context, req ArtifactRequest) ([]RegistryFile, error) {
+	return b.registry.VersionFiles(ctx, req)
+}
+
+// Read fetches a package endpoint over HTTP, the Harness CLI has no command
+// for package indexes and metadata
+func (b *CLIBackend) Read(ctx context.Context, req EndpointRequest) ([]byte, error) {
+	return b.registry.Read(ctx, req)
+}
+
+// Write uploads content to a package endpoint over HTTP, the Harness CLI
+// only pushes whole packages
+func (b *CLIBackend) Write(ctx context.Context, req EndpointRequest) error {
+	return b.registry.Write(ctx, req)
+}
+
+// SetDistTag points an npm dist-tag at a version over HTTP, the Harness CLI
+// has no dist-tag command
+func (b *CLIBackend) SetDistTag(ctx context.Context, req DistTagRequest) error {
+	return b.registry.SetDistTag(ctx, req)
+}
+
+// RemoveDistTag deletes an npm dist-tag over HTTP
+func (b *CLIBackend) RemoveDistTag(ctx context.Context, req DistTagRequest) error {
+	return b.registry.RemoveDistTag(ctx, req)
+}
+
+// buildPushCommand builds the push command for any package type. The
+// credentials are read by the Harness CLI from the auth file prepared by
+// commandEnv.
+func buildPushCommand(req PushRequest) []string {
+	config := req.Config
+
+	cmdArgs := []string{getHarnessBin(), "artifact"}
+
+	// Add context flags (no token needed as it's in auth.json)
+	if config.Org != "" {
+		cmdArgs = append(cmdArgs, "--org", config.Org)
+	}
+	if config.Project != "" {
+		cmdArgs = append(cmdArgs, "--project", config.Project)
+	}
+
+	// Add the rest of the command: push, package-type, registry, filepath
+	// All package types need the file path to know what to push
+	cmdArgs = append(cmdArgs, "push", strings.ToLower(string(req.PackageType)), config.Registry, req.FilePath)
+
+	// Add other required flags
+	if req.PackageType == Generic {
+		// Only generic packages need explicit name and version
+		cmdArgs = append(cmdArgs, "--name", req.Name)
+		cmdArgs = append(cmdArgs, "--version", req.Version)
+	}
+	if req.PackageType == Maven {
+		cmdArgs = append(cmdArgs, "--pom-file", config.PomFile)
+	}
+	cmdArgs = append(cmdArgs, "--pkg-url", config.PkgURL)
+
+	// Add remaining optional flags
+	if config.ApiURL != "" {
+		cmdArgs = append(cmdArgs, "--api-url", config.ApiURL)
+	}
+	if config.Filename != "" {
+		cmdArgs = append(cmdArgs, "--filename", config.Filename)
+	}
+
+	// Add package specific trailing flags
+	if req.PackageType == Generic && req.Path != "" {
+		cmdArgs = append(cmdArgs, "--path", req.Path)
+	}
+	if req.PackageType == NPM && config.Tag != "" {
+		cmdArgs = append(cmdArgs, "--tag", config.Tag)
+	}
+	if req.PackageType == Go {
+		cmdArgs = append(cmdArgs, "--version", req.Version)
+	}
+
+	return cmdArgs
+}
+
+func getHarnessBin() string {
+	if runtime.GOOS == "windows" {
+		if _, err := os.Stat("C:/bin/hc.exe"); err == nil {
+			return "C:/bin/hc.exe"
+		}
+	}
+	return "hc"
+}
+
+// executeCommand executes a Harness CLI command through the backend runner,
+// retrying transient failures according to the configured retry policy. The
+// command is stopped when the context is cancelled or the per-file timeout
+// expires. The redacted stdout of the successful attempt is returned.
+func (b *CLIBackend) executeCommand(ctx context.Context, config Config, cmdArgs []string, operation string) ([]byte, error) {
+	logger := loggerFrom(ctx)
+	stdout, stderr := commandOutput(ctx)
+
+	// Secrets never reach the logs, neither in the command line nor in its output
+	redactor := newRedactor(config)
+	cmdStr := strings.Join(redactor.Args(cmdArgs), " ")
+	logger.Printf("Executing command: %s", cmdStr)
+
+	trace(stdout, cmdStr)
+
+	env, err := b.commandEnv(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare harness config: %w", err)
+	}
+
+	var output bytes.Buffer
+	err = runOperation(ctx, config, operation, func(ctx context.Context) error {
+		output.Reset()
+		tail := &tailBuffer{max: 8192}
+		outWriter := redactor.Writer(io.MultiWriter(stdout, &output))
+		errWriter := redactor.Writer(io.MultiWriter(stderr, tail))
+		err := b.runner.Run(ctx, Command{
+			Args:   cmdArgs,
+			Env:    env,
+			Stdout: outWriter,
+			Stderr: errWriter,
+		})
+		outWriter.Close()
+		errWriter.Close()
+		if err == nil {
+			return nil
+		}
+		cmdErr := &CommandError{Err: err, ExitCode: -1, Stderr: tail.String()}
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			cmdErr.ExitCode = exitErr.ExitCode()
+		}
+		return cmdErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+
+	logger.Printf("Successfully completed: %s\n", operation)
+	return output.Bytes(), nil
+}
+
+// trace writes each command to stdout with the command wrapped in an xml
+// tag so that it can be extracted and displayed in the logs. The command
+// line must already be redacted.
+func trace(stdout io.Writer, cmdStr string) {
+	// Only show trace in debug mode to reduce noise
+	if logrus.GetLevel() >= logrus.DebugLevel {
+		fmt.Fprintf(stdout, "+ %s\n", cmdStr)
+	}
+}
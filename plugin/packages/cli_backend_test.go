@@ -0,0 +1,397 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingRunner records every command instead of executing it
+type recordingRunner struct {
+	mu    sync.Mutex
+	calls []Command
+
+	// run, when set, is invoked for every command to fake its behavior
+	run func(cmd Command) error
+}
+
+func (r *recordingRunner) Run(ctx context.Context, cmd Command) error {
+	r.mu.Lock()
+	r.calls = append(r.calls, cmd)
+	r.mu.Unlock()
+	if r.run != nil {
+		return r.run(cmd)
+	}
+	return nil
+}
+
+// args returns the argv of every recorded command
+func (r *recordingRunner) args() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var args [][]string
+	for _, call := range r.calls {
+		args = append(args, call.Args)
+	}
+	return args
+}
+
+// newTestFactory returns a handler factory backed by a recording runner.
+// The backend is closed when the test completes.
+func newTestFactory(t *testing.T) (*HandlerFactory, *recordingRunner) {
+	t.Helper()
+	runner := &recordingRunner{}
+	backend := NewCLIBackend(runner)
+	t.Cleanup(func() { backend.Close() })
+	return NewHandlerFactory(backend), runner
+}
+
+func testConfig() Config {
+	return Config{
+		Token:    "test-token",
+		Account:  "test-account",
+		Org:      "test-org",
+		Project:  "test-project",
+		ApiURL:   "https://app.harness.io",
+		PkgURL:   "https://pkg.harness.io",
+		Registry: "test-registry",
+		Name:     "test-artifact",
+		Version:  "1.0.0",
+		Source:   "dist/artifact.bin",
+	}
+}
+
+func TestCLIBackend_PushCommands(t *testing.T) {
+	hc := getHarnessBin()
+	tests := []struct {
+		packageType PackageType
+		config      func(*Config)
+		setup       func(t *testing.T)
+		want        []string
+	}{
+		{
+			packageType: Generic,
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", "generic", "test-registry", "dist/artifact.bin",
+				"--name", "test-artifact", "--version", "1.0.0",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io"},
+		},
+		{
+			packageType: Generic,
+			config: func(c *Config) {
+				c.Version = ""
+				c.Filename = "renamed.bin"
+			},
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", "generic", "test-registry", "dist/artifact.bin",
+				"--name", "test-artifact", "--version", "v1",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io",
+				"--filename", "renamed.bin"},
+		},
+		{
+			packageType: Maven,
+			config: func(c *Config) {
+				c.PomFile = "pom.xml"
+			},
+			setup: func(t *testing.T) {
+				pom := `<project><groupId>io.harness</groupId><artifactId>test-artifact</artifactId><version>1.0.0</version></project>`
+				if err := os.WriteFile("pom.xml", []byte(pom), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", "maven", "test-registry", "dist/artifact.bin",
+				"--pom-file", "pom.xml",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io",
+				"--filename", "test-artifact-1.0.0.bin"},
+		},
+		{
+			packageType: NPM,
+			config: func(c *Config) {
+				c.Tag = "next"
+			},
+			setup: func(t *testing.T) {
+				writeNPMTarball(t, "dist/artifact.bin", `{"name": "test-artifact", "version": "1.0.0"}`)
+			},
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", "npm", "test-registry", "dist/artifact.bin",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io",
+				"--tag", "next"},
+		},
+		{
+			packageType: Python,
+			config: func(c *Config) {
+				c.Source = "dist/test_artifact-1.0.0-py3-none-any.whl"
+			},
+			setup: func(t *testing.T) {
+				writeWheel(t, "dist/test_artifact-1.0.0-py3-none-any.whl", "Metadata-Version: 2.1\nName: test-artifact\nVersion: 1.0.0\n")
+			},
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", "python", "test-registry", "dist/test_artifact-1.0.0-py3-none-any.whl",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io"},
+		},
+		{
+			packageType: Go,
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", "go", "test-registry", "dist/artifact.bin",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io",
+				"--version", "1.0.0"},
+		},
+	}
+
+	// Package types without package specific flags share the same command shape
+	for _, packageType := range []PackageType{Dart, Composer, RPM, Cargo, NuGet, Conda} {
+		tests = append(tests, struct {
+			packageType PackageType
+			config      func(*Config)
+			setup       func(t *testing.T)
+			want        []string
+		}{
+			packageType: packageType,
+			want: []string{hc, "artifact", "--org", "test-org", "--project", "test-project",
+				"push", strings.ToLower(string(packageType)), "test-registry", "dist/artifact.bin",
+				"--pkg-url", "https://pkg.harness.io", "--api-url", "https://app.harness.io"},
+		})
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.packageType), func(t *testing.T) {
+			t.Chdir(writeTree(t, "dist/artifact.bin"))
+			if test.setup != nil {
+				test.setup(t)
+			}
+			factory, runner := newTestFactory(t)
+			handler, err := factory.GetHandler(string(test.packageType))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config := testConfig()
+			if test.config != nil {
+				test.config(&config)
+			}
+			if _, err := handler.Push(context.Background(), config); err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+
+			got := runner.args()
+			if len(got) != 1 {
+				t.Fatalf("expected 1 command, got %d: %v", len(got), got)
+			}
+			if !reflect.DeepEqual(got[0], test.want) {
+				t.Errorf("unexpected command\n got: %q\nwant: %q", got[0], test.want)
+			}
+		})
+	}
+}
+
+func TestCLIBackend_GenericCommands(t *testing.T) {
+	hc := getHarnessBin()
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{
+			command: "pull",
+			want: []string{hc, "artifact", "pull", "GENERIC", "test-registry", "test-artifact/1.0.0/artifact.bin", "downloads",
+				"--token", "test-token", "--account", "test-account", "--pkg-url", "https://pkg.harness.io",
+				"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+				"--format", "json"},
+		},
+		{
+			command: "get",
+			want: []string{hc, "artifact", "get", "test-artifact",
+				"--registry", "test-registry", "--token", "test-token", "--account", "test-account",
+				"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+				"--format", "json"},
+		},
+		{
+			command: "delete",
+			want: []string{hc, "artifact", "delete", "test-artifact",
+				"--registry", "test-registry", "--token", "test-token", "--account", "test-account",
+				"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+				"--format", "json"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.command, func(t *testing.T) {
+			factory, runner := newTestFactory(t)
+			handler, err := factory.GetHandler("generic")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config := testConfig()
+			config.Filename = "artifact.bin"
+			config.Destination = "downloads"
+
+			switch test.command {
+			case "pull":
+				_, err = handler.Pull(context.Background(), config)
+			case "get":
+				_, err = handler.Get(context.Background(), config)
+			case "delete":
+				_, err = handler.Delete(context.Background(), config)
+			}
+			if err != nil {
+				t.Fatalf("%s failed: %v", test.command, err)
+			}
+
+			got := runner.args()
+			if len(got) != 1 {
+				t.Fatalf("expected 1 command, got %d: %v", len(got), got)
+			}
+			if !reflect.DeepEqual(got[0], test.want) {
+				t.Errorf("unexpected command\n got: %q\nwant: %q", got[0], test.want)
+			}
+		})
+	}
+}
+
+func TestCLIBackend_UnimplementedCommands(t *testing.T) {
+	for _, packageType := range []PackageType{Dart, Composer, RPM, Cargo, NuGet, Conda} {
+		t.Run(string(packageType), func(t *testing.T) {
+			factory, runner := newTestFactory(t)
+			handler, err := factory.GetHandler(string(packageType))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config := testConfig()
+			for name, op := range map[string]func(context.Context, Config) (*Result, error){
+				"pull":   handler.Pull,
+				"get":    handler.Get,
+				"delete": handler.Delete,
+			} {
+				if _, err := op(context.Background(), config); err == nil || !strings.Contains(err.Error(), "not yet implemented") {
+					t.Errorf("%s: expected not implemented error, got %v", name, err)
+				}
+			}
+			if got := runner.args(); len(got) != 0 {
+				t.Errorf("expected no commands, got %v", got)
+			}
+		})
+	}
+}
+
+func TestCLIBackend_CommandError(t *testing.T) {
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		return context.DeadlineExceeded
+	}
+
+	handler, _ := factory.GetHandler("generic")
+	_, err := handler.Get(context.Background(), testConfig())
+	if err == nil {
+		t.Fatal("expected error from failing command")
+	}
+	if !strings.Contains(err.Error(), "failed to get info for artifact 'test-artifact' in registry 'test-registry'") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCLIBackend_IsolatedAuthConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Chdir(writeTree(t, "dist/artifact.bin"))
+
+	var envHomes []string
+	runner := &recordingRunner{}
+	runner.run = func(cmd Command) error {
+		var cmdHome string
+		for _, entry := range cmd.Env {
+			if value, ok := strings.CutPrefix(entry, "HOME="); ok {
+				cmdHome = value
+			}
+		}
+		envHomes = append(envHomes, cmdHome)
+
+		// The credentials must be readable by the command while it runs
+		data, err := os.ReadFile(filepath.Join(cmdHome, ".harness", "auth.json"))
+		if err != nil {
+			return err
+		}
+		var auth AuthConfig
+		if err := json.Unmarshal(data, &auth); err != nil {
+			return err
+		}
+		if auth.Token != "CIManager test-token" || auth.AccountID != "test-account" {
+			t.Errorf("unexpected auth config: %+v", auth)
+		}
+		return nil
+	}
+
+	backend := NewCLIBackend(runner)
+	handler := NewGenericHandler(backend)
+	for i := 0; i < 2; i++ {
+		if _, err := handler.Push(context.Background(), testConfig()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(envHomes) != 2 || envHomes[0] != envHomes[1] {
+		t.Fatalf("expected both commands to share the run config directory, got %q", envHomes)
+	}
+	if envHomes[0] == "" || envHomes[0] == home {
+		t.Fatalf("command must not use the user home directory, got %q", envHomes[0])
+	}
+	if _, err := os.Stat(filepath.Join(home, ".harness")); !os.IsNotExist(err) {
+		t.Errorf("user configuration was modified: %v", err)
+	}
+
+	if err := backend.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(envHomes[0]); !os.IsNotExist(err) {
+		t.Errorf("config directory was not removed: %v", err)
+	}
+}
+
+func TestCLIBackend_ConcurrentRunsUseSeparateConfig(t *testing.T) {
+	first, second := NewCLIBackend(&recordingRunner{}), NewCLIBackend(&recordingRunner{})
+	defer first.Close()
+	defer second.Close()
+
+	firstEnv, err := first.commandEnv(context.Background(), testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := testConfig()
+	config.Token = "other-token"
+	if _, err := second.commandEnv(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if first.configDir == second.configDir {
+		t.Fatalf("runs share config directory %s", first.configDir)
+	}
+
+	// Writing the second run's credentials must not affect the first run
+	data, err := os.ReadFile(filepath.Join(first.configDir, ".harness", "auth.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "CIManager test-token") {
+		t.Errorf("credentials of the first run were overwritten: %s", data)
+	}
+
+	var homes []string
+	for _, entry := range firstEnv {
+		if strings.HasPrefix(entry, "HOME=") {
+			homes = append(homes, entry)
+		}
+	}
+	if want := []string{"HOME=" + first.configDir}; !reflect.DeepEqual(homes, want) {
+		t.Errorf("HOME entries = %q, want %q", homes, want)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// defaultApiURL is used for registry management calls when no API URL is configured
+const defaultApiURL = "https://app.harness.io"
+
+// Client is a minimal HTTP client for the Harness Artifact Registry.
+// Package endpoints live under <pkg_url>/pkg/<account>/<registry>/, while
+// registry management endpoints live under <api_url>/gateway/har/api/v1/.
+type Client struct {
+	httpClient *http.Client
+	config     Config
+}
+
+// StatusError is returned when the registry answers with a non-2xx status
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// newClient creates a registry client for the given configuration
+func newClient(httpClient *http.Client, config Config) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient: httpClient,
+		config:     config,
+	}
+}
+
+// packageURL returns the URL of a package endpoint in the configured registry
+func (c *Client) packageURL(elems ...string) string {
+	return packageURL(c.config, elems...)
+}
+
+// packageURL returns the URL of a package endpoint in the registry of config
+func packageURL(config Config, elems ...string) string {
+	parts := []string{strings.TrimSuffix(config.PkgURL, "/"), "pkg", url.PathEscape(config.Account), url.PathEscape(config.Registry)}
+	for _, elem := range elems {
+		parts = append(parts, escapePath(elem))
+	}
+	return strings.Join(parts, "/")
+}
+
+// endpointURL returns the URL of an endpoint below the root of a package
+// type, path is already escaped
+func endpointURL(config Config, packageType PackageType, path string) string {
+	return packageURL(config, strings.ToLower(string(packageType))) + "/" + path
+}
+
+// apiURL returns the URL of a registry management endpoint. The registry
+// reference is built from the account, org, project and registry names.
+func (c *Client) apiURL(elems ...string) string {
+	base := c.config.ApiURL
+	if base == "" {
+		base = defaultApiURL
+	}
+
+	ref := []string{c.config.Account}
+	if c.config.Org != "" {
+		ref = append(ref, c.config.Org)
+	}
+	if c.config.Project != "" {
+		ref = append(ref, c.config.Project)
+	}
+	ref = append(ref, c.config.Registry)
+
+	parts := []string{strings.TrimSuffix(base, "/"), "gateway", "har", "api", "v1", "registry", url.PathEscape(strings.Join(ref, "/")), "+"}
+	for _, elem := range elems {
+		parts = append(parts, escapePath(elem))
+	}
+	return strings.Join(parts, "/")
+}
+
+// do sends the request and returns the response when the status is 2xx.
+// The caller is responsible for closing the response body.
+func (c *Client) do(ctx context.Context, method, rawURL string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if sized, ok := body.(*sizedReader); ok {
+		req.ContentLength = sized.size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{
+			Method:     method,
+			URL:        rawURL,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(data)),
+		}
+	}
+	return resp, nil
+}
+
+// getJSON fetches a registry endpoint and decodes its JSON response into v
+func (c *Client) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, rawURL, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// RegistryFile is a file of an artifact version as listed by the registry
+type RegistryFile struct {
+	Name string `json:"name"`
+
+	// Checksums are listed as "<algorithm>: <hex digest>"
+	Checksums []string `json:"checksums,omitempty"`
+}
+
+// versionFiles lists the files of an artifact version
+func (c *Client) versionFiles(ctx context.Context, name, version string) ([]RegistryFile, error) {
+	var response struct {
+		Data struct {
+			Files []RegistryFile `json:"files"`
+		} `json:"data"`
+	}
+	if err := c.getJSON(ctx, c.apiURL("artifact", name, "+", "version", version, "files"), &response); err != nil {
+		return nil, err
+	}
+	return response.Data.Files, nil
+}
+
+// fileSHA256 returns the SHA-256 checksum listed for a file of a version,
+// or an empty string when the file has none
+func fileSHA256(files []RegistryFile, name, version, filename string) (string, error) {
+	for _, file := range files {
+		if file.Name != filename && path.Base(file.Name) != filename {
+			continue
+		}
+		for _, checksum := range file.Checksums {
+			algorithm, digest, ok := strings.Cut(checksum, ":")
+			if ok && normalizeOutputKey(strings.TrimSpace(algorithm)) == "sha256" {
+				return strings.ToLower(strings.TrimSpace(digest)), nil
+			}
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("file '%s' not found in version '%s' of artifact '%s'", filename, version, name)
+}
+
+// authorize sets the authentication header. Personal and service account
+// tokens use the API key header, anything else is treated as a CI token.
+func (c *Client) authorize(req *http.Request) {
+	token := c.config.Token
+	if strings.HasPrefix(token, "pat.") || strings.HasPrefix(token, "sat.") {
+		req.Header.Set("x-api-key", token)
+		return
+	}
+	req.Header.Set("Authorization", "CIManager "+token)
+}
+
+// sizedReader lets net/http send a Content-Length for streamed bodies
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+// escapePath escapes every segment of a slash separated path
+func escapePath(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
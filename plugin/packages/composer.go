@@ -17,9 +17,9 @@ type ComposerHandler struct {
 }
 
 // NewComposerHandler creates a new Composer package handler
-func NewComposerHandler() *ComposerHandler {
+func NewComposerHandler(backend Backend) *ComposerHandler {
 	return &ComposerHandler{
-		BaseHandler: NewBaseHandler(Composer),
+		BaseHandler: NewBaseHandler(Composer, backend),
 	}
 }
 
@@ -44,44 +44,43 @@ func (h *ComposerHandler) Validate(config Config) error {
 }
 
 // Push uploads Composer packages to the registry
-func (h *ComposerHandler) Push(ctx context.Context, config Config) error {
+func (h *ComposerHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing Composer push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
 // pushSingleFile handles pushing a single file for Composer packages
-func (h *ComposerHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Composer)
-	cmdArgs, err := buildPushCommand(Composer, config, "", filePath, artifactName, false)
-	if err != nil {
-		return err
-	}
-
-	return executeCommand(cmdArgs, fmt.Sprintf("push Composer artifact '%s' to registry '%s'", artifactName, config.Registry))
+func (h *ComposerHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Composer,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+	})
 }
 
 // Pull downloads Composer packages from the registry
-func (h *ComposerHandler) Pull(ctx context.Context, config Config) error {
+func (h *ComposerHandler) Pull(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Composer pull logic
-	return fmt.Errorf("Composer pull is not yet implemented")
+	return nil, fmt.Errorf("Composer pull is not yet implemented")
 }
 
 // Get retrieves Composer package information
-func (h *ComposerHandler) Get(ctx context.Context, config Config) error {
+func (h *ComposerHandler) Get(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Composer get logic
-	return fmt.Errorf("Composer get is not yet implemented")
+	return nil, fmt.Errorf("Composer get is not yet implemented")
 }
 
 // Delete removes Composer packages from the registry
-func (h *ComposerHandler) Delete(ctx context.Context, config Config) error {
+func (h *ComposerHandler) Delete(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Composer delete logic
-	return fmt.Errorf("Composer delete is not yet implemented")
+	return nil, fmt.Errorf("Composer delete is not yet implemented")
 }
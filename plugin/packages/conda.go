@@ -12,16 +12,15 @@ import (
 )
 
 // CondaHandler handles Conda package operations
-type CondaHandler struct{}
-
-// NewCondaHandler creates a new Conda handler
-func NewCondaHandler() *CondaHandler {
-	return &CondaHandler{}
+type CondaHandler struct {
+	BaseHandler
 }
 
-// GetPackageType returns the package type for Conda packages
-func (h *CondaHandler) GetPackageType() PackageType {
-	return Conda
+// NewCondaHandler creates a new Conda package handler
+func NewCondaHandler(backend Backend) *CondaHandler {
+	return &CondaHandler{
+		BaseHandler: NewBaseHandler(Conda, backend),
+	}
 }
 
 // Validate checks if the configuration is valid for Conda packages
@@ -45,42 +44,41 @@ func (h *CondaHandler) Validate(config Config) error {
 }
 
 // Push uploads Conda packages to the registry
-func (h *CondaHandler) Push(ctx context.Context, config Config) error {
+func (h *CondaHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
 // pushSingleFile handles pushing a single file for Conda packages
-func (h *CondaHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Conda)
-	cmdArgs, err := buildPushCommand(Conda, config, "", filePath, artifactName, false)
-	if err != nil {
-		return err
-	}
-
-	return executeCommand(cmdArgs, fmt.Sprintf("push Conda artifact '%s' to registry '%s'", artifactName, config.Registry))
+func (h *CondaHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Conda,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+	})
 }
 
 // Pull downloads Conda packages from the registry
-func (h *CondaHandler) Pull(ctx context.Context, config Config) error {
+func (h *CondaHandler) Pull(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Conda pull logic
-	return fmt.Errorf("Conda pull is not yet implemented")
+	return nil, fmt.Errorf("Conda pull is not yet implemented")
 }
 
 // Get retrieves information about Conda packages
-func (h *CondaHandler) Get(ctx context.Context, config Config) error {
+func (h *CondaHandler) Get(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Conda get logic
-	return fmt.Errorf("Conda get is not yet implemented")
+	return nil, fmt.Errorf("Conda get is not yet implemented")
 }
 
 // Delete removes Conda packages from the registry
-func (h *CondaHandler) Delete(ctx context.Context, config Config) error {
+func (h *CondaHandler) Delete(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Conda delete logic
-	return fmt.Errorf("Conda delete is not yet implemented")
+	return nil, fmt.Errorf("Conda delete is not yet implemented")
 }
@@ -17,9 +17,9 @@ type DartHandler struct {
 }
 
 // NewDartHandler creates a new Dart package handler
-func NewDartHandler() *DartHandler {
+func NewDartHandler(backend Backend) *DartHandler {
 	return &DartHandler{
-		BaseHandler: NewBaseHandler(Dart),
+		BaseHandler: NewBaseHandler(Dart, backend),
 	}
 }
 
@@ -44,44 +44,43 @@ func (h *DartHandler) Validate(config Config) error {
 }
 
 // Push uploads Dart packages to the registry
-func (h *DartHandler) Push(ctx context.Context, config Config) error {
+func (h *DartHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing Dart push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
 // pushSingleFile handles pushing a single file for Dart packages
-func (h *DartHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Dart)
-	cmdArgs, err := buildPushCommand(Dart, config, "", filePath, artifactName, false)
-	if err != nil {
-		return err
-	}
-
-	return executeCommand(cmdArgs, fmt.Sprintf("push Dart artifact '%s' to registry '%s'", artifactName, config.Registry))
+func (h *DartHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Dart,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+	})
 }
 
 // Pull downloads Dart packages from the registry
-func (h *DartHandler) Pull(ctx context.Context, config Config) error {
+func (h *DartHandler) Pull(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Dart pull logic
-	return fmt.Errorf("Dart pull is not yet implemented")
+	return nil, fmt.Errorf("Dart pull is not yet implemented")
 }
 
 // Get retrieves Dart package information
-func (h *DartHandler) Get(ctx context.Context, config Config) error {
+func (h *DartHandler) Get(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Dart get logic
-	return fmt.Errorf("Dart get is not yet implemented")
+	return nil, fmt.Errorf("Dart get is not yet implemented")
 }
 
 // Delete removes Dart packages from the registry
-func (h *DartHandler) Delete(ctx context.Context, config Config) error {
+func (h *DartHandler) Delete(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement Dart delete logic
-	return fmt.Errorf("Dart delete is not yet implemented")
+	return nil, fmt.Errorf("Dart delete is not yet implemented")
 }
@@ -14,25 +14,26 @@ type HandlerFactory struct {
 	handlers map[PackageType]PackageHandler
 }
 
-// NewHandlerFactory creates a new handler factory with all registered handlers
-func NewHandlerFactory() *HandlerFactory {
+// NewHandlerFactory creates a new handler factory with all registered handlers.
+// Every handler performs its registry operations through the given backend.
+func NewHandlerFactory(backend Backend) *HandlerFactory {
 	factory := &HandlerFactory{
 		handlers: make(map[PackageType]PackageHandler),
 	}
-	
+
 	// Register all available handlers
-	factory.registerHandler(NewGenericHandler())
-	factory.registerHandler(NewNPMHandler())
-	factory.registerHandler(NewDartHandler())
-	factory.registerHandler(NewComposerHandler())
-	factory.registerHandler(NewPythonHandler())
-	factory.registerHandler(NewGoHandler())
-	factory.registerHandler(NewCargoHandler())
-	factory.registerHandler(NewRPMHandler())
-	factory.registerHandler(NewNuGetHandler())
-	factory.registerHandler(NewMavenHandler())
-	factory.registerHandler(NewCondaHandler())
-	
+	factory.registerHandler(NewGenericHandler(backend))
+	factory.registerHandler(NewNPMHandler(backend))
+	factory.registerHandler(NewDartHandler(backend))
+	factory.registerHandler(NewComposerHandler(backend))
+	factory.registerHandler(NewPythonHandler(backend))
+	factory.registerHandler(NewGoHandler(backend))
+	factory.registerHandler(NewCargoHandler(backend))
+	factory.registerHandler(NewRPMHandler(backend))
+	factory.registerHandler(NewNuGetHandler(backend))
+	factory.registerHandler(NewMavenHandler(backend))
+	factory.registerHandler(NewCondaHandler(backend))
+
 	return factory
 }
 
@@ -44,19 +45,19 @@ func (f *HandlerFactory) registerHandler(handler PackageHandler) {
 // GetHandler returns the appropriate handler for the given package type
 func (f *HandlerFactory) GetHandler(packageType string) (PackageHandler, error) {
 	// Normalize package type to uppercase
-	normalizedType := PackageType(packageType)
-	
+	normalizedType := PackageType(strings.ToUpper(packageType))
+
 	// Default to generic if empty
 	if normalizedType == "" {
 		normalizedType = Generic
 	}
-	
+
 	handler, exists := f.handlers[normalizedType]
 	if !exists {
-		return nil, fmt.Errorf("unsupported package type: %s. Supported types: %s", 
+		return nil, fmt.Errorf("unsupported package type: %s. Supported types: %s",
 			packageType, f.GetSupportedTypes())
 	}
-	
+
 	return handler, nil
 }
 
@@ -0,0 +1,148 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.jar", "app.jar", true},
+		{"*.jar", "lib/nested/app.jar", true},
+		{"*.jar", "app.jar.sha1", false},
+		{"lib/*.jar", "lib/app.jar", true},
+		{"lib/*.jar", "lib/nested/app.jar", false},
+		{"lib/**/*.jar", "lib/app.jar", true},
+		{"lib/**/*.jar", "lib/a/b/app.jar", true},
+		{"**/docs/**", "a/docs/readme.md", true},
+		{"**/docs/**", "docs", true},
+		{"./bin/*", "bin/tool", true},
+		{"", "anything", false},
+	}
+
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+// writeTree creates the given files, relative to a new temporary directory
+func writeTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// pushedPaths returns the --path value of every recorded push command
+func pushedPaths(runner *recordingRunner) []string {
+	var paths []string
+	for _, args := range runner.args() {
+		for i, arg := range args {
+			if arg == "--path" && i+1 < len(args) {
+				paths = append(paths, args[i+1])
+			}
+		}
+	}
+	return paths
+}
+
+func TestGenericHandler_PushDirectory(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(*Config)
+		want   []string
+	}{
+		{
+			name: "all files",
+			want: []string{"bin/app", "lib/a.jar", "lib/nested/b.jar", "readme.md"},
+		},
+		{
+			name: "include",
+			config: func(c *Config) {
+				c.Include = []string{"*.jar"}
+			},
+			want: []string{"lib/a.jar", "lib/nested/b.jar"},
+		},
+		{
+			name: "exclude directory",
+			config: func(c *Config) {
+				c.Exclude = []string{"lib/nested", "*.md"}
+			},
+			want: []string{"bin/app", "lib/a.jar"},
+		},
+		{
+			name: "hidden files",
+			config: func(c *Config) {
+				c.IncludeHidden = true
+				c.Include = []string{".env", ".config/**"}
+			},
+			want: []string{".config/settings.json", ".env"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := writeTree(t, "readme.md", "bin/app", "lib/a.jar", "lib/nested/b.jar", ".env", ".config/settings.json")
+			factory, runner := newTestFactory(t)
+			handler, _ := factory.GetHandler("generic")
+
+			config := testConfig()
+			config.Source = root
+			if test.config != nil {
+				test.config(&config)
+			}
+
+			if _, err := handler.Push(context.Background(), config); err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+			if got := pushedPaths(runner); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("pushed paths = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGenericHandler_PushDirectoryFailure(t *testing.T) {
+	root := writeTree(t, "a.txt", "b.txt", "c.txt")
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		if strings.HasSuffix(strings.Join(cmd.Args, " "), "--path b.txt") {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+	handler, _ := factory.GetHandler("generic")
+
+	config := testConfig()
+	config.Source = root
+	_, err := handler.Push(context.Background(), config)
+	if err == nil || !strings.Contains(err.Error(), "failed to push 1 of 3 files") {
+		t.Fatalf("expected aggregated failure, got %v", err)
+	}
+	if got := len(runner.args()); got != 3 {
+		t.Errorf("expected every file to be attempted, got %d commands", got)
+	}
+}
@@ -6,7 +6,13 @@ package packages
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,9 +23,9 @@ type GoHandler struct {
 }
 
 // NewGoHandler creates a new Go package handler
-func NewGoHandler() *GoHandler {
+func NewGoHandler(backend Backend) *GoHandler {
 	return &GoHandler{
-		BaseHandler: NewBaseHandler(Go),
+		BaseHandler: NewBaseHandler(Go, backend),
 	}
 }
 
@@ -31,9 +37,6 @@ func (h *GoHandler) Validate(config Config) error {
 	if config.Source == "" {
 		return fmt.Errorf("source file path must be set")
 	}
-	if config.Version == "" {
-		return fmt.Errorf("package version must be set")
-	}
 	if config.Token == "" {
 		return fmt.Errorf("authentication token must be set")
 	}
@@ -46,47 +49,325 @@ func (h *GoHandler) Validate(config Config) error {
 	return nil
 }
 
-// Push uploads Go packages to the registry
-func (h *GoHandler) Push(ctx context.Context, config Config) error {
+// Push uploads Go modules to the registry. source is a prebuilt file pushed
+// as is, or a module directory holding a go.mod whose module zip, .mod and
+// .info files are built and pushed for the version.
+func (h *GoHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing Go push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
+	if info, err := os.Stat(config.Source); err == nil && info.IsDir() {
+		return h.pushModule(ctx, config)
+	}
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	// Prebuilt files carry no module path to derive a name and version for
+	if config.Version == "" {
+		return nil, fmt.Errorf("package version must be set")
+	}
+	if config.Name == "" {
+		return nil, fmt.Errorf("package name must be set")
+	}
+	if err := checkGoModulePath(config.Name); err != nil {
+		return nil, err
+	}
+	if err := checkGoMajorVersion(config.Name, "v"+strings.TrimPrefix(config.Version, "v")); err != nil {
+		return nil, err
+	}
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
-// pushSingleFile handles pushing a single file for Go packages
-func (h *GoHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Go)
-	cmdArgs, err := buildPushCommand(Go, config, "", filePath, artifactName, false)
+// pushModule builds the files a module proxy serves for a version from a
+// module directory and pushes them. Without a version setting the version
+// comes from the pipeline tag, or is the pseudo-version of the commit.
+func (h *GoHandler) pushModule(ctx context.Context, config Config) (*Result, error) {
+	if !isGoModule(config.Source) {
+		return nil, fmt.Errorf("'%s' is not a module directory, %s not found", config.Source, goModFilename)
+	}
+	module, err := loadGoModule(config.Source)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	module.Version, module.Time, err = goModuleVersion(ctx, config, module)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGoMajorVersion(module.Path, module.Version); err != nil {
+		return nil, err
+	}
+	if config.Name != "" && config.Name != module.Path {
+		return nil, fmt.Errorf("name '%s' does not match the module path '%s' of %s", config.Name, module.Path, goModFilename)
+	}
+	// Files are named after the version, clients request them by name
+	if config.Filename != "" {
+		logrus.Printf("⚠ Warning: filename '%s' is ignored for Go modules", config.Filename)
+		config.Filename = ""
 	}
 
-	cmdArgs = append(cmdArgs, "--version", config.Version)
+	files, err := module.files()
+	if err != nil {
+		return nil, err
+	}
+	outDir, err := os.MkdirTemp("", "go-module-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+	build, err := module.build(files, outDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build module zip: %w", err)
+	}
+	logrus.Printf("Built module %s@%s with %d files (%s)", module.Path, module.Version, len(files), build.Sum)
 
-	return executeCommand(cmdArgs, fmt.Sprintf("push Go artifact '%s' to registry '%s'", artifactName, config.Registry))
+	// The files are pushed one by one and the .info file last, it announces
+	// the version to clients
+	uploads := []string{build.Zip, build.Mod, build.Info}
+	jobs := make([]uploadJob, len(uploads))
+	results := make([]*Result, len(uploads))
+	for i, file := range uploads {
+		jobs[i] = uploadJob{
+			Label: filepath.Base(file),
+			Push: func(ctx context.Context) error {
+				result, err := h.backend.Push(ctx, PushRequest{
+					PackageType: Go,
+					Config:      config,
+					FilePath:    file,
+					Name:        module.Path,
+					Version:     module.Version,
+				})
+				results[i] = result
+				return err
+			},
+		}
+	}
+	config.Parallelism = 1
+	config.FailFast = true
+	if err := runUploads(ctx, config, fmt.Sprintf("Go module %s@%s", module.Path, module.Version), jobs); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		PackageType: Go,
+		Registry:    config.Registry,
+		Name:        module.Path,
+		Version:     module.Version,
+		Files:       results,
+		Metadata: map[string]string{
+			"GO_MODULE_PATH":    module.Path,
+			"GO_MODULE_VERSION": module.Version,
+			"GO_MODULE_SUM":     build.Sum,
+			"GO_MOD_SUM":        build.ModSum,
+		},
+	}
+	for _, file := range results {
+		result.Size += file.Size
+	}
+	return result, nil
 }
 
-// Pull downloads Go packages from the registry
-func (h *GoHandler) Pull(ctx context.Context, config Config) error {
-	// TODO: Implement Go pull logic
-	return fmt.Errorf("Go pull is not yet implemented")
+// pushSingleFile handles pushing a single file for Go packages
+func (h *GoHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Go,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+		Version:     config.Version,
+	})
 }
 
-// Get retrieves Go package information
-func (h *GoHandler) Get(ctx context.Context, config Config) error {
-	// TODO: Implement Go get logic
-	return fmt.Errorf("Go get is not yet implemented")
+// Pull downloads a module version from the module proxy endpoints of the
+// registry into the destination, laid out as a module proxy tree usable
+// with GOPROXY=file://<destination>. Without a version the latest one is
+// pulled.
+func (h *GoHandler) Pull(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Go pull command")
+
+	if err := validateGoRequest(config); err != nil {
+		return nil, err
+	}
+	if config.Destination == "" {
+		return nil, fmt.Errorf("destination path must be set")
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+
+	version := "v" + strings.TrimPrefix(config.Version, "v")
+	if config.Version == "" {
+		versions, err := goVersions(ctx, h.backend, config, config.Name)
+		if err != nil {
+			return nil, err
+		}
+		if version = goLatestVersion(versions); version == "" {
+			return nil, fmt.Errorf("module '%s' has no versions in registry '%s'", config.Name, config.Registry)
+		}
+		logrus.Printf("Latest version of %s is %s", config.Name, version)
+	} else if !goVersionPattern.MatchString(version) {
+		return nil, fmt.Errorf("version '%s' must be a semantic version, e.g. v1.2.3 or v1.2.3-rc.1", version)
+	}
+
+	// The .info file comes first and fails fast for unknown versions, the
+	// version is only listed once its files are complete
+	dir := goModuleDir(config.Destination, config.Name)
+	files := make(map[string]*Result)
+	var results []*Result
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		result, err := h.backend.Pull(ctx, PullRequest{
+			PackageType: Go,
+			Config:      config,
+			Name:        config.Name,
+			Version:     version,
+			Filename:    goProxyEscape(version) + ext,
+			Path:        goProxyPath(config.Name, version+ext),
+			Destination: dir,
+		})
+		if err != nil {
+			// A partial version would be trusted by GOPROXY=file:// clients
+			for _, result := range results {
+				os.Remove(result.Path)
+			}
+			return nil, err
+		}
+		files[ext] = result
+		results = append(results, result)
+	}
+
+	sum, modSum, err := checkGoDownload(config.Name, version, files[".info"].Path, files[".mod"].Path, files[".zip"].Path)
+	if err != nil {
+		for _, result := range results {
+			os.Remove(result.Path)
+		}
+		return nil, fmt.Errorf("%w, the files were removed", err)
+	}
+	if expected := strings.TrimSpace(config.ExpectedSHA256); expected != "" {
+		if err := verifyFileSHA256(ctx, files[".zip"], expected, "expected_sha256"); err != nil {
+			for _, result := range results {
+				os.Remove(result.Path)
+			}
+			return nil, fmt.Errorf("%w, the .info and .mod files were removed too", err)
+		}
+	}
+	if err := addGoListVersion(filepath.Join(dir, "list"), version); err != nil {
+		return nil, fmt.Errorf("failed to update the version list of %s: %w", config.Name, err)
+	}
+	logrus.Printf("Pulled %s@%s (%s) into %s", config.Name, version, sum, dir)
+
+	result := &Result{
+		PackageType: Go,
+		Registry:    config.Registry,
+		Name:        config.Name,
+		Version:     version,
+		Path:        dir,
+		Files:       results,
+		Metadata: map[string]string{
+			"GO_MODULE_PATH":    config.Name,
+			"GO_MODULE_VERSION": version,
+			"GO_MODULE_SUM":     sum,
+			"GO_MOD_SUM":        modSum,
+		},
+	}
+	for _, file := range results {
+		result.Size += file.Size
+	}
+	return result, nil
+}
+
+// Get prints the versions of a module as JSON, or the version and its time
+// when a version is set
+func (h *GoHandler) Get(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Go get command")
+
+	if err := validateGoRequest(config); err != nil {
+		return nil, err
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+
+	summary := goModuleSummary{Path: config.Name, Versions: []goVersionSummary{}}
+	var latest string
+	if config.Version != "" {
+		info, err := goInfo(ctx, h.backend, config, config.Name, "v"+strings.TrimPrefix(config.Version, "v"))
+		if err != nil {
+			return nil, err
+		}
+		summary.Versions = append(summary.Versions, goVersionSummary{Version: info.Version, Time: info.Time.UTC().Format(time.RFC3339)})
+	} else {
+		versions, err := goVersions(ctx, h.backend, config, config.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			summary.Versions = append(summary.Versions, goVersionSummary{Version: version})
+		}
+		latest = goLatestVersion(versions)
+	}
+
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode module summary: %w", err)
+	}
+	printJSON(raw)
+
+	version := latest
+	if config.Version != "" {
+		version = summary.Versions[0].Version
+	}
+	result := &Result{
+		PackageType: Go,
+		Registry:    config.Registry,
+		Name:        config.Name,
+		Version:     version,
+		URL:         packageURL(config, "go"),
+		Metadata: map[string]string{
+			"GO_MODULE_PATH":   config.Name,
+			"GO_VERSION_COUNT": strconv.Itoa(len(summary.Versions)),
+		},
+		Raw: raw,
+	}
+	if latest != "" {
+		result.Metadata["GO_LATEST_VERSION"] = latest
+	}
+	return result, nil
+}
+
+// Delete removes a version of a module from the registry
+func (h *GoHandler) Delete(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Go delete command")
+
+	if err := validateGoRequest(config); err != nil {
+		return nil, err
+	}
+	if config.Version == "" {
+		return nil, fmt.Errorf("package version must be set")
+	}
+
+	return h.backend.Delete(ctx, ArtifactRequest{
+		PackageType: Go,
+		Config:      config,
+		Name:        config.Name,
+		Version:     "v" + strings.TrimPrefix(config.Version, "v"),
+	})
 }
 
-// Delete removes Go packages from the registry
-func (h *GoHandler) Delete(ctx context.Context, config Config) error {
-	// TODO: Implement Go delete logic
-	return fmt.Errorf("Go delete is not yet implemented")
+// validateGoRequest checks the settings shared by pull, get and delete
+func validateGoRequest(config Config) error {
+	if config.Registry == "" {
+		return fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return fmt.Errorf("package name must be set")
+	}
+	if config.Token == "" {
+		return fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return fmt.Errorf("account ID must be set")
+	}
+	return checkGoModulePath(config.Name)
 }
@@ -0,0 +1,378 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// goModFilename is the module file of a Go module
+const goModFilename = "go.mod"
+
+// Limits enforced by golang.org/x/mod/zip, the go command rejects larger
+// module zips and files
+const (
+	maxGoModuleZipSize = 500 << 20
+	maxGoModSize       = 16 << 20
+	maxGoLicenseSize   = 16 << 20
+)
+
+var (
+	// goVersionPattern matches a canonical Go module version: semantic
+	// versioning with a v prefix, +incompatible is the only build metadata
+	goVersionPattern = regexp.MustCompile(`^v(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+		`(-(0|[1-9][0-9]*|[0-9]*[A-Za-z-][0-9A-Za-z-]*)(\.(0|[1-9][0-9]*|[0-9]*[A-Za-z-][0-9A-Za-z-]*))*)?(\+incompatible)?$`)
+
+	// goReservedNames are file names Windows cannot create, with or without
+	// an extension
+	goReservedNames = []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "COM4", "COM5", "COM6",
+		"COM7", "COM8", "COM9", "LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
+)
+
+// goModule is a Go module read from a module directory
+type goModule struct {
+	// Dir is the module root, holding go.mod
+	Dir string
+
+	// Path is the module path declared in go.mod
+	Path    string
+	Version string
+	GoMod   []byte
+
+	// Time is the commit time of the version, the build time when unknown
+	Time time.Time
+}
+
+// goModuleFile is a file of the module zip
+type goModuleFile struct {
+	// Name is the slash separated path relative to the module root
+	Name string
+
+	// File is the local path
+	File string
+	Size int64
+}
+
+// goModuleBuild holds the files served by a module proxy for a version
+type goModuleBuild struct {
+	Zip  string
+	Mod  string
+	Info string
+
+	// Sum and ModSum are the go.sum hashes of the zip and of go.mod
+	Sum    string
+	ModSum string
+}
+
+// isGoModule reports whether the directory holds a go.mod
+func isGoModule(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, goModFilename))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// loadGoModule reads the module path from the go.mod of a module directory
+func loadGoModule(dir string) (*goModule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, goModFilename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", goModFilename, err)
+	}
+	if len(data) > maxGoModSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", goModFilename, maxGoModSize)
+	}
+	modulePath, err := parseGoModulePath(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in '%s': %w", goModFilename, dir, err)
+	}
+	return &goModule{Dir: dir, Path: modulePath, GoMod: data}, nil
+}
+
+// parseGoModulePath returns the path of the module directive of a go.mod
+func parseGoModulePath(data []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "module" {
+			continue
+		}
+		if len(fields) != 2 {
+			return "", fmt.Errorf("invalid module directive '%s'", strings.TrimSpace(line))
+		}
+		modulePath := fields[1]
+		if strings.HasPrefix(modulePath, `"`) || strings.HasPrefix(modulePath, "`") {
+			unquoted, err := strconv.Unquote(modulePath)
+			if err != nil {
+				return "", fmt.Errorf("invalid module path %s", modulePath)
+			}
+			modulePath = unquoted
+		}
+		if err := checkGoModulePath(modulePath); err != nil {
+			return "", err
+		}
+		return modulePath, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module directive found")
+}
+
+// checkGoModulePath rejects module paths that cannot be used in the URLs
+// and file names of the module proxy protocol
+func checkGoModulePath(modulePath string) error {
+	if modulePath == "" || strings.ContainsAny(modulePath, " \\@!") || strings.HasPrefix(modulePath, "/") || strings.HasSuffix(modulePath, "/") {
+		return fmt.Errorf("invalid module path '%s'", modulePath)
+	}
+	for _, elem := range strings.Split(modulePath, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("invalid module path '%s'", modulePath)
+		}
+	}
+	return nil
+}
+
+// files returns the files of the module zip, following the rules of
+// golang.org/x/mod/zip: version control directories, nested modules,
+// vendored packages and irregular files such as symbolic links are left
+// out. File paths must be valid on every platform and must not collide
+// when compared case-insensitively.
+func (m *goModule) files() ([]goModuleFile, error) {
+	var files []goModuleFile
+	collisions := map[string]string{}
+	var total int64
+	err := filepath.WalkDir(m.Dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file == m.Dir {
+			return nil
+		}
+		if entry.IsDir() {
+			switch entry.Name() {
+			case ".bzr", ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+			if isGoModule(file) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.Dir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if isVendoredPackage(name) {
+			return nil
+		}
+		if err := checkGoFilePath(name); err != nil {
+			return fmt.Errorf("file '%s' cannot be part of a module zip: %w", name, err)
+		}
+		if other, ok := collisions[strings.ToLower(name)]; ok {
+			return fmt.Errorf("files '%s' and '%s' collide on case-insensitive file systems", other, name)
+		}
+		collisions[strings.ToLower(name)] = name
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if name == "LICENSE" && info.Size() > maxGoLicenseSize {
+			return fmt.Errorf("LICENSE is larger than %d bytes", maxGoLicenseSize)
+		}
+		if total += info.Size(); total > maxGoModuleZipSize {
+			return fmt.Errorf("module files are larger than %d bytes in total", maxGoModuleZipSize)
+		}
+		files = append(files, goModuleFile{Name: name, File: file, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// isVendoredPackage reports whether the file belongs to a vendored package.
+// Like golang.org/x/mod/zip, files directly in vendor/ such as modules.txt
+// are kept, and a nested vendor directory is matched with the offset of the
+// top-level one, which the go command keeps for checksum compatibility.
+func isVendoredPackage(name string) bool {
+	var i int
+	if strings.HasPrefix(name, "vendor/") {
+		i += len("vendor/")
+	} else if j := strings.Index(name, "/vendor/"); j >= 0 {
+		i += len("/vendor/")
+	} else {
+		return false
+	}
+	return strings.Contains(name[i:], "/")
+}
+
+// checkGoFilePath validates a file path like module.CheckFilePath: every
+// element must be non-empty, must not end with a dot, must only hold
+// letters, digits and safe punctuation, and must not be a name reserved on
+// Windows
+func checkGoFilePath(name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("invalid UTF-8")
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == "" || strings.Trim(elem, ".") == "" {
+			return fmt.Errorf("empty or dot-only path element")
+		}
+		if strings.HasSuffix(elem, ".") {
+			return fmt.Errorf("trailing dot in path element '%s'", elem)
+		}
+		for _, r := range elem {
+			if !goFileNameRuneOK(r) {
+				return fmt.Errorf("invalid character %q in path element '%s'", r, elem)
+			}
+		}
+		short, _, _ := strings.Cut(elem, ".")
+		for _, reserved := range goReservedNames {
+			if strings.EqualFold(short, reserved) {
+				return fmt.Errorf("'%s' is a reserved file name on Windows", elem)
+			}
+		}
+		// Windows short names like GIT~1 could alias other files
+		if tilde := strings.LastIndexByte(short, '~'); tilde >= 0 && tilde < len(short)-1 {
+			if strings.Trim(short[tilde+1:], "0123456789") == "" {
+				return fmt.Errorf("trailing tilde and digits in path element '%s'", elem)
+			}
+		}
+	}
+	return nil
+}
+
+// goFileNameRuneOK reports whether the rune may appear in a file name of a
+// module zip
+func goFileNameRuneOK(r rune) bool {
+	if r < utf8.RuneSelf {
+		const allowed = "!#$%&()+,-.=@[]^_{}~ "
+		return '0' <= r && r <= '9' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || strings.ContainsRune(allowed, r)
+	}
+	return unicode.IsLetter(r)
+}
+
+// build writes <version>.zip, <version>.mod and <version>.info into outDir.
+// Zip entries are prefixed with <module path>@<version>/.
+func (m *goModule) build(files []goModuleFile, outDir string) (*goModuleBuild, error) {
+	build := &goModuleBuild{
+		Zip:  filepath.Join(outDir, m.Version+".zip"),
+		Mod:  filepath.Join(outDir, m.Version+".mod"),
+		Info: filepath.Join(outDir, m.Version+".info"),
+	}
+
+	out, err := os.Create(build.Zip)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	prefix := m.Path + "@" + m.Version + "/"
+	sums := map[string][]byte{}
+	zw := zip.NewWriter(out)
+	for _, file := range files {
+		w, err := zw.Create(prefix + file.Name)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := copyGoModuleFile(w, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add '%s' to module zip: %w", file.Name, err)
+		}
+		sums[prefix+file.Name] = sum
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
+	modSum := sha256.Sum256(m.GoMod)
+	build.Sum = goHash1(sums)
+	build.ModSum = goHash1(map[string][]byte{goModFilename: modSum[:]})
+
+	modTime := m.Time
+	if modTime.IsZero() {
+		modTime = now()
+	}
+	info, err := json.Marshal(struct {
+		Version string
+		Time    time.Time
+	}{m.Version, modTime.UTC().Truncate(time.Second)})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(build.Mod, m.GoMod, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(build.Info, info, 0644); err != nil {
+		return nil, err
+	}
+	return build, nil
+}
+
+// copyGoModuleFile copies a file into the zip and returns its SHA-256
+func copyGoModuleFile(w io.Writer, file goModuleFile) ([]byte, error) {
+	f, err := os.Open(file.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// The file may not grow past the size counted against the limits
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), io.LimitReader(f, file.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	if n != file.Size {
+		return nil, fmt.Errorf("file changed while building the module zip")
+	}
+	return hash.Sum(nil), nil
+}
+
+// goHash1 returns the "h1:" hash recorded in go.sum, the dirhash.Hash1 of
+// the files: the SHA-256 of the sorted "<sha256>  <name>" lines, base64
+// encoded. sums maps file names to their SHA-256.
+func goHash1(sums map[string][]byte) string {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(summary, "%x  %s\n", sums[name], name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil))
+}
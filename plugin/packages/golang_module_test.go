@@ -0,0 +1,228 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeGoModule writes a module directory with the go.mod and the files
+func writeGoModule(t *testing.T, goMod string, files ...string) string {
+	t.Helper()
+	dir := writeTree(t, files...)
+	if err := os.WriteFile(filepath.Join(dir, goModFilename), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestParseGoModulePath(t *testing.T) {
+	for input, want := range map[string]string{
+		"module example.com/m\n":                       "example.com/m",
+		"// comment\nmodule   example.com/m/v2 // x\n": "example.com/m/v2",
+		"module \"example.com/quoted\"\n\ngo 1.21\n":   "example.com/quoted",
+	} {
+		got, err := parseGoModulePath([]byte(input))
+		if err != nil || got != want {
+			t.Errorf("parseGoModulePath(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+
+	for input, want := range map[string]string{
+		"go 1.21\n":                   "no module directive",
+		"module a b\n":                "invalid module directive",
+		"module example.com/m@v1\n":   "invalid module path",
+		"module \"example.com/m\n":    "invalid module path",
+		"module /abs\n":               "invalid module path",
+		"module example.com/trail/\n": "invalid module path",
+	} {
+		if _, err := parseGoModulePath([]byte(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestGoModuleFiles(t *testing.T) {
+	dir := writeGoModule(t, "module example.com/m\n",
+		"m.go", ".gitignore", "LICENSE", "internal/x.go", "testdata/.hidden/data.txt",
+		".git/config", "sub/.hg/store", "nested/go.mod", "nested/n.go",
+		"vendor/modules.txt", "vendor/example.com/dep/dep.go", "pkg/vendor/v.go")
+	if err := os.Symlink("m.go", filepath.Join(dir, "link.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := loadGoModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.Path != "example.com/m" {
+		t.Errorf("unexpected module path %s", module.Path)
+	}
+	files, err := module.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	want := []string{".gitignore", "LICENSE", "go.mod", "internal/x.go", "m.go", "testdata/.hidden/data.txt", "vendor/modules.txt"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected files\n got: %q\nwant: %q", names, want)
+	}
+}
+
+func TestIsVendoredPackage(t *testing.T) {
+	for name, want := range map[string]bool{
+		"vendor/modules.txt":        false,
+		"vendor/example.com/a.go":   true,
+		"x/vendor/a.go":             true,
+		"pkg/vendor/modules.txt":    true,
+		"longer/path/vendor/b/a.go": true,
+		"vendors/a.go":              false,
+	} {
+		if got := isVendoredPackage(name); got != want {
+			t.Errorf("isVendoredPackage(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCheckGoFilePath(t *testing.T) {
+	for _, name := range []string{"a.go", "dir/file name.txt", ".github/workflows/ci.yml", "héllo.txt", "a~b.go"} {
+		if err := checkGoFilePath(name); err != nil {
+			t.Errorf("%q: unexpected error %v", name, err)
+		}
+	}
+	for name, want := range map[string]string{
+		"dir/../a.go": "dot-only",
+		"file.":       "trailing dot",
+		"a:b.go":      "invalid character",
+		"a*.go":       "invalid character",
+		"aux.go":      "reserved file name",
+		"Com1":        "reserved file name",
+		"GIT~1/x":     "trailing tilde",
+	} {
+		if err := checkGoFilePath(name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", name, want, err)
+		}
+	}
+}
+
+func TestGoModuleFiles_Errors(t *testing.T) {
+	tests := []struct {
+		files []string
+		err   string
+	}{
+		{files: []string{"README", "readme"}, err: "collide on case-insensitive file systems"},
+		{files: []string{"docs/what?.md"}, err: "file 'docs/what?.md' cannot be part of a module zip"},
+	}
+	for _, test := range tests {
+		module, err := loadGoModule(writeGoModule(t, "module example.com/m\n", test.files...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := module.files(); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected error containing %q, got %v", test.files, test.err, err)
+		}
+	}
+}
+
+func TestGoModuleBuild(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 3, 1, 12, 30, 45, 123, time.FixedZone("CET", 3600)) }
+	t.Cleanup(func() { now = time.Now })
+
+	goMod := "module example.com/m\n\ngo 1.21\n"
+	module, err := loadGoModule(writeGoModule(t, goMod, "m.go", "internal/x.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	module.Version = "v1.2.0-rc.1"
+	files, err := module.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	build, err := module.build(files, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(build.Zip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	want := []string{"example.com/m@v1.2.0-rc.1/go.mod", "example.com/m@v1.2.0-rc.1/internal/x.go", "example.com/m@v1.2.0-rc.1/m.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected zip entries\n got: %q\nwant: %q", names, want)
+	}
+
+	if data, _ := os.ReadFile(build.Mod); string(data) != goMod {
+		t.Errorf("unexpected .mod file %q", data)
+	}
+	if data, _ := os.ReadFile(build.Info); string(data) != `{"Version":"v1.2.0-rc.1","Time":"2024-03-01T11:30:45Z"}` {
+		t.Errorf("unexpected .info file %s", data)
+	}
+	if filepath.Base(build.Zip) != "v1.2.0-rc.1.zip" || filepath.Base(build.Info) != "v1.2.0-rc.1.info" {
+		t.Errorf("unexpected file names %+v", build)
+	}
+
+	// go.sum hashes, as computed by the go command
+	if build.ModSum != "h1:ONeDgCa5UF/jJRjGzpOKmUiezgFEk4IPFZ96frvroW0=" {
+		t.Errorf("unexpected go.mod hash %s", build.ModSum)
+	}
+	if build.Sum != "h1:TbqEkwa+HDso0UoHiAQ/Vlvk/9BGhKqFV+79OoA8CPo=" {
+		t.Errorf("unexpected module hash %s", build.Sum)
+	}
+}
+
+func TestGoHandler_PushModule(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeGoModule(t, "module example.com/m\n", "m.go"))
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("go")
+
+	config := testConfig()
+	config.Source = "."
+	config.Name = ""
+	config.Version = "v1.0.0"
+	config.Parallelism = 4
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pushed []string
+	for _, args := range runner.args() {
+		pushed = append(pushed, filepath.Base(args[9])+" "+args[len(args)-1])
+	}
+	if want := []string{"v1.0.0.zip v1.0.0", "v1.0.0.mod v1.0.0", "v1.0.0.info v1.0.0"}; !reflect.DeepEqual(pushed, want) {
+		t.Errorf("unexpected pushes %q", pushed)
+	}
+	if result.Name != "example.com/m" || len(result.Files) != 3 || result.Metadata["GO_MODULE_PATH"] != "example.com/m" ||
+		result.Metadata["GO_MODULE_VERSION"] != "v1.0.0" || !strings.HasPrefix(result.Metadata["GO_MODULE_SUM"], "h1:") {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Name = "example.com/other"
+	if _, err := handler.Push(context.Background(), config); err == nil || !strings.Contains(err.Error(), "does not match the module path") {
+		t.Errorf("expected module path mismatch, got %v", err)
+	}
+	config.Source = t.TempDir()
+	config.Name = ""
+	if _, err := handler.Push(context.Background(), config); err == nil || !strings.Contains(err.Error(), "go.mod not found") {
+		t.Errorf("expected missing go.mod error, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxGoListSize limits the size of the version list of a module
+const maxGoListSize = 16 << 20
+
+// goPseudoVersionPattern matches pseudo-versions, which the @v/list
+// endpoint does not list
+var goPseudoVersionPattern = regexp.MustCompile(`^v[0-9]+\.(0\.0-|[0-9]+\.[0-9]+-([^+]*\.)?0\.)[0-9]{14}-[A-Za-z0-9]+(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// goVersionInfo is the content of the .info file of a version
+type goVersionInfo struct {
+	Version string
+	Time    time.Time
+}
+
+// goVersionSummary describes a version in the get output
+type goVersionSummary struct {
+	Version string `json:"version"`
+	Time    string `json:"time,omitempty"`
+}
+
+// goModuleSummary is the JSON document printed by the Go get command
+type goModuleSummary struct {
+	Path     string             `json:"path"`
+	Versions []goVersionSummary `json:"versions"`
+}
+
+// goProxyEscape applies the case encoding of the module proxy protocol:
+// every upper-case letter is replaced by an exclamation mark followed by the
+// lower-case letter, so paths stay distinct on case-insensitive file systems
+func goProxyEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// goProxyPath returns the path of a file of a module below the root of the
+// module proxy, e.g. example.com/!my!mod/@v/v1.0.0.zip
+func goProxyPath(modulePath, file string) string {
+	return goProxyEscape(modulePath) + "/@v/" + goProxyEscape(file)
+}
+
+// goVersions lists the versions of a module from its @v/list endpoint,
+// sorted by semantic version precedence
+func goVersions(ctx context.Context, backend Backend, config Config, modulePath string) ([]string, error) {
+	list, err := backend.Read(ctx, EndpointRequest{
+		PackageType: Go,
+		Config:      config,
+		Path:        escapePath(goProxyPath(modulePath, "list")),
+	})
+	if isGoNotFound(err) {
+		return nil, fmt.Errorf("module '%s' not found in registry '%s'", modulePath, config.Registry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of module '%s' in registry '%s': %w", modulePath, config.Registry, err)
+	}
+	if len(list) > maxGoListSize {
+		return nil, fmt.Errorf("version list of module '%s' exceeds %d bytes", modulePath, maxGoListSize)
+	}
+	return parseGoVersionList(list), nil
+}
+
+// goInfo fetches the .info file of a module version
+func goInfo(ctx context.Context, backend Backend, config Config, modulePath, version string) (*goVersionInfo, error) {
+	data, err := backend.Read(ctx, EndpointRequest{
+		PackageType: Go,
+		Config:      config,
+		Path:        escapePath(goProxyPath(modulePath, version+".info")),
+	})
+	if isGoNotFound(err) {
+		return nil, fmt.Errorf("version '%s' of module '%s' not found in registry '%s'", version, modulePath, config.Registry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version '%s' of module '%s' from registry '%s': %w", version, modulePath, config.Registry, err)
+	}
+	info := new(goVersionInfo)
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("invalid .info file of %s@%s: %w", modulePath, version, err)
+	}
+	return info, nil
+}
+
+// isGoNotFound reports whether a module proxy answered that a module or
+// version does not exist, with 404 or 410 like the go command expects
+func isGoNotFound(err error) bool {
+	var status *StatusError
+	return errors.As(err, &status) && (status.StatusCode == http.StatusNotFound || status.StatusCode == http.StatusGone)
+}
+
+// parseGoVersionList returns the valid versions of an @v/list response,
+// one version per line optionally followed by other fields, in order of
+// precedence
+func parseGoVersionList(list []byte) []string {
+	seen := map[string]bool{}
+	var versions []string
+	for _, line := range strings.Split(string(list), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || seen[fields[0]] || !goVersionPattern.MatchString(fields[0]) {
+			continue
+		}
+		seen[fields[0]] = true
+		versions = append(versions, fields[0])
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareSemver(versions[i][1:], versions[j][1:]) < 0
+	})
+	return versions
+}
+
+// goLatestVersion returns the version the go command resolves "latest" to
+// from a sorted version list: the highest release, or the highest
+// pre-release when there is no release
+func goLatestVersion(versions []string) string {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !strings.Contains(strings.TrimSuffix(versions[i], "+incompatible"), "-") {
+			return versions[i]
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	return versions[len(versions)-1]
+}
+
+// checkGoDownload verifies the downloaded files of a module version: the
+// .info file must describe the version, the .mod file must declare the
+// module path, and every file of the zip must be stored below
+// <module path>@<version>/. It returns the go.sum hashes of the zip and
+// the .mod file.
+func checkGoDownload(modulePath, version, infoFile, modFile, zipFile string) (string, string, error) {
+	data, err := os.ReadFile(infoFile)
+	if err != nil {
+		return "", "", err
+	}
+	var info goVersionInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return "", "", fmt.Errorf("invalid .info file of %s@%s: %w", modulePath, version, err)
+	}
+	if info.Version != version {
+		return "", "", fmt.Errorf(".info file of %s@%s describes version '%s'", modulePath, version, info.Version)
+	}
+
+	goMod, err := os.ReadFile(modFile)
+	if err != nil {
+		return "", "", err
+	}
+	if declared, err := parseGoModulePath(goMod); err != nil || declared != modulePath {
+		return "", "", fmt.Errorf(".mod file of %s@%s does not declare module %s", modulePath, version, modulePath)
+	}
+	modSum := sha256.Sum256(goMod)
+
+	zr, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid module zip of %s@%s: %w", modulePath, version, err)
+	}
+	defer zr.Close()
+	prefix := modulePath + "@" + version + "/"
+	sums := map[string][]byte{}
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, prefix) || strings.HasSuffix(f.Name, "/") {
+			return "", "", fmt.Errorf("module zip of %s@%s holds '%s', outside of %s", modulePath, version, f.Name, prefix)
+		}
+		if sums[f.Name] != nil {
+			return "", "", fmt.Errorf("module zip of %s@%s holds '%s' twice", modulePath, version, f.Name)
+		}
+		sum, err := zipFileSHA256(f)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read '%s' from module zip of %s@%s: %w", f.Name, modulePath, version, err)
+		}
+		sums[f.Name] = sum
+	}
+	return goHash1(sums), goHash1(map[string][]byte{goModFilename: modSum[:]}), nil
+}
+
+// zipFileSHA256 returns the SHA-256 digest of a file of a zip archive
+func zipFileSHA256(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// addGoListVersion records a version in the @v/list file of a local module
+// proxy tree, keeping the versions pulled before. Pseudo-versions are not
+// listed, like the module proxy protocol requires.
+func addGoListVersion(listFile, version string) error {
+	data, err := os.ReadFile(listFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	versions := parseGoVersionList(data)
+	if !goPseudoVersionPattern.MatchString(version) {
+		versions = parseGoVersionList(append(data, "\n"+version+"\n"...))
+	}
+
+	var b strings.Builder
+	for _, v := range versions {
+		b.WriteString(v + "\n")
+	}
+	return os.WriteFile(listFile, []byte(b.String()), 0644)
+}
+
+// goModuleDir returns the directory of the files of a module in a local
+// module proxy tree
+func goModuleDir(root, modulePath string) string {
+	return filepath.Join(root, filepath.FromSlash(goProxyEscape(modulePath)), "@v")
+}
@@ -0,0 +1,257 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGoProxy serves the module proxy endpoints of example.com/My/Mod with
+// the files built for every version
+func newGoProxy(t *testing.T, list string, versions ...string) *httptest.Server {
+	t.Helper()
+	module, err := loadGoModule(writeGoModule(t, "module example.com/My/Mod\n", "mod.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := module.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := map[string]string{}
+	for _, version := range versions {
+		module.Version = version
+		module.Time = time.Date(2024, 3, 1, 11, 30, 45, 0, time.UTC)
+		build, err := module.build(files, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range []string{build.Zip, build.Mod, build.Info} {
+			served[goProxyEscape(filepath.Base(file))] = file
+		}
+	}
+
+	root := "/pkg/acct/go-local/go/example.com/!my/!mod/@v/"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutPrefix(r.URL.Path, root)
+		switch {
+		case ok && name == "list":
+			w.Write([]byte(list))
+		case ok && served[name] != "":
+			http.ServeFile(w, r, served[name])
+		default:
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func goProxyConfig(server *httptest.Server) Config {
+	return Config{
+		Token:    "pat.test",
+		Account:  "acct",
+		Registry: "go-local",
+		PkgURL:   server.URL,
+		Name:     "example.com/My/Mod",
+	}
+}
+
+func TestGoProxyEscape(t *testing.T) {
+	if got := goProxyPath("github.com/Azure/azure-sdk", "v1.0.0-RC1.zip"); got != "github.com/!azure/azure-sdk/@v/v1.0.0-!r!c1.zip" {
+		t.Errorf("unexpected proxy path %s", got)
+	}
+}
+
+func TestParseGoVersionList(t *testing.T) {
+	versions := parseGoVersionList([]byte("v1.10.0\nv1.9.0 2024-03-01T00:00:00Z\n\nv2.0.0-rc.1\nlatest\nv1.9.0\nv1.2\n"))
+	if want := []string{"v1.9.0", "v1.10.0", "v2.0.0-rc.1"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("unexpected versions %q", versions)
+	}
+	if latest := goLatestVersion(versions); latest != "v1.10.0" {
+		t.Errorf("unexpected latest version %s", latest)
+	}
+	if latest := goLatestVersion([]string{"v0.1.0-alpha", "v0.1.0-beta"}); latest != "v0.1.0-beta" {
+		t.Errorf("unexpected latest pre-release %s", latest)
+	}
+}
+
+func TestGoHandler_Pull(t *testing.T) {
+	captureLogs(t)
+	server := newGoProxy(t, "v1.0.0\nv1.1.0\nv1.2.0-rc.1\n", "v1.0.0", "v1.1.0", "v0.0.0-20240301113045-abcdefabcdef")
+	handler := NewGoHandler(NewHTTPBackend(server.Client()))
+	config := goProxyConfig(server)
+	config.Destination = t.TempDir()
+
+	result, err := handler.Pull(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(config.Destination, "example.com", "!my", "!mod", "@v")
+	if result.Version != "v1.1.0" || result.Path != dir || len(result.Files) != 3 ||
+		result.Metadata["GO_MODULE_VERSION"] != "v1.1.0" || !strings.HasPrefix(result.Metadata["GO_MODULE_SUM"], "h1:") {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "1.0.0"
+	if _, err := handler.Pull(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	config.Version = "v0.0.0-20240301113045-abcdefabcdef"
+	if _, err := handler.Pull(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{"list", "v0.0.0-20240301113045-abcdefabcdef.info", "v0.0.0-20240301113045-abcdefabcdef.mod", "v0.0.0-20240301113045-abcdefabcdef.zip",
+		"v1.0.0.info", "v1.0.0.mod", "v1.0.0.zip", "v1.1.0.info", "v1.1.0.mod", "v1.1.0.zip"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected files\n got: %q\nwant: %q", names, want)
+	}
+	if list, _ := os.ReadFile(filepath.Join(dir, "list")); string(list) != "v1.0.0\nv1.1.0\n" {
+		t.Errorf("unexpected version list %q", list)
+	}
+
+	config.Version = "v1.2.0-rc.1"
+	if _, err := handler.Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+	config.Version = "v1.0.0"
+	config.ExpectedSHA256 = strings.Repeat("0", 64)
+	if _, err := handler.Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		if _, err := os.Stat(filepath.Join(dir, "v1.0.0"+ext)); !os.IsNotExist(err) {
+			t.Errorf("expected v1.0.0%s to be removed, got %v", ext, err)
+		}
+	}
+	config.Name = "example.com/other"
+	config.Version = ""
+	if _, err := handler.Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "module 'example.com/other' not found") {
+		t.Errorf("expected missing module error, got %v", err)
+	}
+}
+
+func TestGoHandler_PullIncomplete(t *testing.T) {
+	captureLogs(t)
+	proxy := newGoProxy(t, "v1.0.0\n", "v1.0.0")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".zip") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		proxy.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	config := goProxyConfig(server)
+	config.Version = "v1.0.0"
+	config.Destination = t.TempDir()
+	if _, err := NewGoHandler(NewHTTPBackend(server.Client())).Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected missing .zip error, got %v", err)
+	}
+	dir := filepath.Join(config.Destination, "example.com", "!my", "!mod", "@v")
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected the downloaded files to be removed, got %d files", len(entries))
+	}
+}
+
+func TestCheckGoDownload(t *testing.T) {
+	module, err := loadGoModule(writeGoModule(t, "module example.com/m\n", "m.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	module.Version = "v1.0.0"
+	files, _ := module.files()
+	build, err := module.build(files, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum, modSum, err := checkGoDownload("example.com/m", "v1.0.0", build.Info, build.Mod, build.Zip)
+	if err != nil || sum != build.Sum || modSum != build.ModSum {
+		t.Errorf("unexpected hashes %s %s, want %s %s: %v", sum, modSum, build.Sum, build.ModSum, err)
+	}
+	if _, _, err := checkGoDownload("example.com/m", "v1.1.0", build.Info, build.Mod, build.Zip); err == nil || !strings.Contains(err.Error(), "describes version 'v1.0.0'") {
+		t.Errorf("expected version mismatch, got %v", err)
+	}
+	if _, _, err := checkGoDownload("example.com/other", "v1.0.0", build.Info, build.Mod, build.Zip); err == nil || !strings.Contains(err.Error(), "does not declare module example.com/other") {
+		t.Errorf("expected module path mismatch, got %v", err)
+	}
+}
+
+func TestGoHandler_Get(t *testing.T) {
+	captureLogs(t)
+	server := newGoProxy(t, "v1.10.0\nv1.9.0\nv2.0.0-rc.1\n", "v1.9.0")
+	handler := NewGoHandler(NewHTTPBackend(server.Client()))
+	config := goProxyConfig(server)
+
+	result, err := handler.Get(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary goModuleSummary
+	if err := json.Unmarshal(result.Raw, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Versions) != 3 || summary.Versions[0].Version != "v1.9.0" || summary.Path != "example.com/My/Mod" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	outputs := map[string]string{"GO_MODULE_PATH": "example.com/My/Mod", "GO_VERSION_COUNT": "3", "GO_LATEST_VERSION": "v1.10.0"}
+	if result.Version != "v1.10.0" || !reflect.DeepEqual(result.Metadata, outputs) || result.URL != server.URL+"/pkg/acct/go-local/go" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "1.9.0"
+	if result, err = handler.Get(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Raw) != `{"path":"example.com/My/Mod","versions":[{"version":"v1.9.0","time":"2024-03-01T11:30:45Z"}]}` {
+		t.Errorf("unexpected output %s", result.Raw)
+	}
+	config.Version = "v1.8.0"
+	if _, err := handler.Get(context.Background(), config); err == nil || !strings.Contains(err.Error(), "version 'v1.8.0' of module 'example.com/My/Mod' not found") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+}
+
+func TestCLIBackend_GoDelete(t *testing.T) {
+	captureLogs(t)
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("go")
+
+	config := testConfig()
+	config.Name = "example.com/m"
+	config.Version = "1.0.0"
+	if _, err := handler.Delete(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if got := runner.args(); len(got) != 1 || got[0][3] != "example.com/m" || got[0][len(got[0])-3] != "v1.0.0" {
+		t.Errorf("unexpected commands %q", got)
+	}
+
+	config.Version = ""
+	if _, err := handler.Delete(context.Background(), config); err == nil || !strings.Contains(err.Error(), "package version must be set") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+	config.Version = "v1.0.0"
+	config.Name = "example.com/m@v1"
+	if _, err := handler.Delete(context.Background(), config); err == nil || !strings.Contains(err.Error(), "invalid module path") {
+		t.Errorf("expected invalid module path error, got %v", err)
+	}
+}
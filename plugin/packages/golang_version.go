@@ -0,0 +1,254 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// goPseudoVersionTime is the timestamp layout of pseudo-versions
+const goPseudoVersionTime = "20060102150405"
+
+// goModuleVersion resolves the version a module directory is published as:
+// the version setting, the pipeline tag, or a pseudo-version of the
+// commit. It also returns the commit time when git can tell it, used as
+// the time of the .info file.
+func goModuleVersion(ctx context.Context, config Config, module *goModule) (string, time.Time, error) {
+	commit := config.CommitSHA
+	if commit == "" {
+		commit = "HEAD"
+	}
+	commitTime, timeErr := gitCommitTime(ctx, module.Dir, commit)
+
+	switch {
+	case config.Version != "":
+		version := "v" + strings.TrimPrefix(config.Version, "v")
+		if !goVersionPattern.MatchString(version) {
+			return "", time.Time{}, fmt.Errorf("version '%s' must be a semantic version, e.g. v1.2.3 or v1.2.3-rc.1", version)
+		}
+		return version, commitTime, nil
+
+	case config.GitTag != "":
+		prefix, err := goTagPrefix(module)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		version, ok := strings.CutPrefix(config.GitTag, prefix)
+		if !ok || !goVersionPattern.MatchString(version) {
+			return "", time.Time{}, fmt.Errorf("tag '%s' is not a version tag of module %s, expected a tag like %sv1.2.3", config.GitTag, module.Path, prefix)
+		}
+		logrus.Printf("Using version %s from tag %s", version, config.GitTag)
+		return version, commitTime, nil
+	}
+
+	// Untagged commits are published as pseudo-versions, which need the
+	// commit time and the tags of the history
+	if timeErr != nil {
+		return "", time.Time{}, fmt.Errorf("version is not set and no tag is built, computing a pseudo-version failed: %w", timeErr)
+	}
+	version, err := goPseudoVersion(ctx, module, commit, commitTime)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to compute pseudo-version: %w", err)
+	}
+	logrus.Printf("Using pseudo-version %s of commit %s", version, commit)
+	return version, commitTime, nil
+}
+
+// goTagPrefix returns the prefix of the version tags of a module: the
+// directory of the module in the repository, without a major version
+// subdirectory such as v2/ for a module path ending in /v2
+func goTagPrefix(module *goModule) (string, error) {
+	dir, err := filepath.Abs(module.Dir)
+	if err != nil {
+		return "", err
+	}
+	root := dir
+	for {
+		if _, err := os.Lstat(filepath.Join(root, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			// Not a checkout, the module is assumed to be at the root
+			return "", nil
+		}
+		root = parent
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	subdir := filepath.ToSlash(rel)
+	if subdir == "." {
+		return "", nil
+	}
+	if major, err := goPathMajor(module.Path); err == nil && strings.HasPrefix(major, "/") && path.Base(subdir) == major[1:] {
+		subdir = path.Dir(subdir)
+	}
+	if subdir == "." {
+		return "", nil
+	}
+	return subdir + "/", nil
+}
+
+// goPathMajor returns the major version suffix of a module path: "/v2" for
+// example.com/m/v2, ".v3" for gopkg.in/yaml.v3, or "" for paths without one
+func goPathMajor(modulePath string) (string, error) {
+	if strings.HasPrefix(modulePath, "gopkg.in/") {
+		i := strings.LastIndex(modulePath, ".v")
+		if i < 0 || !isDigits(modulePath[i+2:]) {
+			return "", fmt.Errorf("module path '%s' must end with a .vN major version suffix", modulePath)
+		}
+		return modulePath[i:], nil
+	}
+
+	last := modulePath[strings.LastIndex(modulePath, "/")+1:]
+	if len(last) < 2 || last[0] != 'v' || !isDigits(last[1:]) || !strings.Contains(modulePath, "/") {
+		return "", nil
+	}
+	if last == "v0" || last == "v1" || last[1] == '0' {
+		return "", fmt.Errorf("module path '%s' has an invalid major version suffix /%s", modulePath, last)
+	}
+	return "/" + last, nil
+}
+
+// checkGoMajorVersion enforces the major version suffix rules: versions
+// v2 and later are published under a path ending in /vN, v0 and v1 under a
+// path without suffix. +incompatible versions are reserved to modules
+// without go.mod.
+func checkGoMajorVersion(modulePath, version string) error {
+	suffix, err := goPathMajor(modulePath)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(version, "+incompatible") {
+		return fmt.Errorf("version '%s' is +incompatible, which only applies to modules without %s", version, goModFilename)
+	}
+
+	major := goVersionMajor(version)
+	switch {
+	case suffix == "" && major != "v0" && major != "v1":
+		return fmt.Errorf("version %s of module %s requires the module path %s/%s in %s", version, modulePath, modulePath, major, goModFilename)
+	case suffix != "" && suffix[1:] != major:
+		return fmt.Errorf("version %s does not match the major version suffix %s of module %s, expected a %s version", version, suffix, modulePath, suffix[1:])
+	}
+	return nil
+}
+
+// goVersionMajor returns the major version of a version, e.g. "v2"
+func goVersionMajor(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
+
+// goPseudoVersion computes the pseudo-version of a commit, based on the
+// highest version tag of the module in its history. A tag on the commit
+// itself is used as is.
+func goPseudoVersion(ctx context.Context, module *goModule, commit string, commitTime time.Time) (string, error) {
+	prefix, err := goTagPrefix(module)
+	if err != nil {
+		return "", err
+	}
+	rev, err := gitOutput(ctx, module.Dir, "rev-parse", "--verify", commit+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	// Only tags of the major version of the module path qualify
+	suffix, _ := goPathMajor(module.Path)
+	compatible := func(version string) bool {
+		if !goVersionPattern.MatchString(version) || strings.HasSuffix(version, "+incompatible") {
+			return false
+		}
+		major := goVersionMajor(version)
+		if suffix == "" {
+			return major == "v0" || major == "v1"
+		}
+		return major == suffix[1:]
+	}
+	highest := func(args ...string) (string, error) {
+		out, err := gitOutput(ctx, module.Dir, append([]string{"tag", "--list", prefix + "v*"}, args...)...)
+		if err != nil {
+			return "", err
+		}
+		var best string
+		for _, tag := range strings.Fields(out) {
+			version := strings.TrimPrefix(tag, prefix)
+			if compatible(version) && (best == "" || compareSemver(version[1:], best[1:]) > 0) {
+				best = version
+			}
+		}
+		return best, nil
+	}
+
+	tagged, err := highest("--points-at", rev)
+	if err != nil || tagged != "" {
+		return tagged, err
+	}
+	base, err := highest("--merged", rev)
+	if err != nil {
+		return "", err
+	}
+
+	timestamp := commitTime.UTC().Format(goPseudoVersionTime)
+	short := rev[:12]
+	switch {
+	case base == "":
+		major := "v0"
+		if suffix != "" {
+			major = suffix[1:]
+		}
+		return fmt.Sprintf("%s.0.0-%s-%s", major, timestamp, short), nil
+	case strings.Contains(base, "-"):
+		// vX.Y.Z-pre.0.yyyymmddhhmmss-abcdefabcdef sorts after vX.Y.Z-pre
+		return fmt.Sprintf("%s.0.%s-%s", base, timestamp, short), nil
+	}
+	parts := strings.Split(base, ".")
+	patch, _ := strconv.Atoi(parts[2])
+	return fmt.Sprintf("%s.%s.%d-0.%s-%s", parts[0], parts[1], patch+1, timestamp, short), nil
+}
+
+// gitCommitTime returns the committer time of a commit
+func gitCommitTime(ctx context.Context, dir, commit string) (time.Time, error) {
+	out, err := gitOutput(ctx, dir, "show", "-s", "--format=%ct", commit+"^{commit}")
+	if err != nil {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid commit time '%s'", out)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// gitOutput runs git in dir and returns its trimmed output. Checkouts owned
+// by another user, common in CI containers, are trusted.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-c", "safe.directory=*", "-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRepo creates a repository holding a root module, a module in sub/mod
+// and a v2 module in lib/v2
+func gitRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := writeTree(t, "go.mod", "sub/mod/go.mod", "lib/v2/go.mod")
+	for file, content := range map[string]string{
+		"go.mod":         "module example.com/r\n",
+		"sub/mod/go.mod": "module example.com/r/sub/mod\n",
+		"lib/v2/go.mod":  "module example.com/r/lib/v2\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2024-03-01T12:30:45+01:00", "GIT_AUTHOR_DATE=2024-03-01T12:30:45+01:00")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	return dir, git
+}
+
+func TestGoPathMajor(t *testing.T) {
+	for modulePath, want := range map[string]string{
+		"example.com/m":       "",
+		"example.com/m/v2":    "/v2",
+		"example.com/m/v10":   "/v10",
+		"example.com/m/vx":    "",
+		"v2":                  "",
+		"gopkg.in/yaml.v3":    ".v3",
+		"gopkg.in/src-d/x.v4": ".v4",
+	} {
+		if got, err := goPathMajor(modulePath); err != nil || got != want {
+			t.Errorf("goPathMajor(%q) = %q, %v, want %q", modulePath, got, err, want)
+		}
+	}
+	for _, modulePath := range []string{"example.com/m/v1", "example.com/m/v0", "example.com/m/v02", "gopkg.in/yaml"} {
+		if _, err := goPathMajor(modulePath); err == nil {
+			t.Errorf("goPathMajor(%q): expected error", modulePath)
+		}
+	}
+}
+
+func TestCheckGoMajorVersion(t *testing.T) {
+	for _, test := range []struct{ path, version, err string }{
+		{"example.com/m", "v0.1.0", ""},
+		{"example.com/m", "v1.2.3", ""},
+		{"example.com/m/v2", "v2.0.0-rc.1", ""},
+		{"gopkg.in/yaml.v3", "v3.0.1", ""},
+		{"example.com/m", "v2.0.0", "requires the module path example.com/m/v2"},
+		{"example.com/m/v2", "v1.5.0", "does not match the major version suffix /v2"},
+		{"example.com/m/v2", "v3.0.0", "expected a v2 version"},
+		{"gopkg.in/yaml.v3", "v2.4.0", "does not match the major version suffix .v3"},
+		{"example.com/m", "v2.0.0+incompatible", "only applies to modules without go.mod"},
+		{"example.com/m/v1", "v1.0.0", "invalid major version suffix"},
+	} {
+		err := checkGoMajorVersion(test.path, test.version)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s@%s: expected error %q, got %v", test.path, test.version, test.err, err)
+		}
+	}
+}
+
+func TestGoModuleVersion(t *testing.T) {
+	captureLogs(t)
+	dir, git := gitRepo(t)
+	ctx := context.Background()
+	commitTime := time.Date(2024, 3, 1, 11, 30, 45, 0, time.UTC)
+
+	resolve := func(subdir string, config Config) (string, error) {
+		t.Helper()
+		module, err := loadGoModule(filepath.Join(dir, subdir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, modTime, err := goModuleVersion(ctx, config, module)
+		if err == nil && !modTime.Equal(commitTime) {
+			t.Errorf("unexpected commit time %v", modTime)
+		}
+		return version, err
+	}
+	expect := func(subdir string, config Config, want string) {
+		t.Helper()
+		if got, err := resolve(subdir, config); err != nil || got != want {
+			t.Errorf("%s %+v: got %q, %v, want %q", subdir, config, got, err, want)
+		}
+	}
+
+	first := git("rev-parse", "HEAD")
+	expect(".", Config{Version: "1.4.0"}, "v1.4.0")
+	expect(".", Config{GitTag: "v1.2.3"}, "v1.2.3")
+	expect("sub/mod", Config{GitTag: "sub/mod/v0.3.0"}, "v0.3.0")
+	expect("lib/v2", Config{GitTag: "lib/v2.0.1"}, "v2.0.1")
+	expect(".", Config{}, "v0.0.0-20240301113045-"+first[:12])
+	expect("lib/v2", Config{}, "v2.0.0-20240301113045-"+first[:12])
+
+	for _, test := range []struct{ subdir, tag string }{
+		{"sub/mod", "v1.2.3"},
+		{".", "sub/mod/v1.2.3"},
+		{".", "release-1"},
+		{".", "v1.2.3+build"},
+	} {
+		if _, err := resolve(test.subdir, Config{GitTag: test.tag}); err == nil || !strings.Contains(err.Error(), "is not a version tag of module") {
+			t.Errorf("%s %s: expected tag error, got %v", test.subdir, test.tag, err)
+		}
+	}
+	if _, err := resolve(".", Config{Version: "v1.2"}); err == nil || !strings.Contains(err.Error(), "must be a semantic version") {
+		t.Errorf("expected version error, got %v", err)
+	}
+
+	// Tags of the commit are used as is, later commits get pseudo-versions
+	// based on the highest tag of the module and major version
+	git("tag", "v1.2.3")
+	git("tag", "v2.0.0")
+	git("tag", "sub/mod/v0.3.0-rc.1")
+	git("tag", "lib/v1.9.0")
+	expect(".", Config{}, "v1.2.3")
+	expect(".", Config{CommitSHA: first}, "v1.2.3")
+
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	second := git("rev-parse", "HEAD")
+	expect(".", Config{}, "v1.2.4-0.20240301113045-"+second[:12])
+	expect("sub/mod", Config{}, "v0.3.0-rc.1.0.20240301113045-"+second[:12])
+	expect("lib/v2", Config{}, "v2.0.0-20240301113045-"+second[:12])
+	expect(".", Config{CommitSHA: first}, "v1.2.3")
+
+	if _, err := resolve(".", Config{CommitSHA: "0123456789abcdef0123456789abcdef01234567"}); err == nil || !strings.Contains(err.Error(), "computing a pseudo-version failed") {
+		t.Errorf("expected unknown commit error, got %v", err)
+	}
+}
+
+func TestGoHandler_PushModuleTag(t *testing.T) {
+	captureLogs(t)
+	dir, _ := gitRepo(t)
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("go")
+
+	config := testConfig()
+	config.Source = filepath.Join(dir, "sub", "mod")
+	config.Name = ""
+	config.Version = ""
+	config.GitTag = "sub/mod/v1.0.1"
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != "example.com/r/sub/mod" || result.Version != "v1.0.1" || len(runner.args()) != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.GitTag = "sub/mod/v2.0.0"
+	if _, err := handler.Push(context.Background(), config); err == nil || !strings.Contains(err.Error(), "requires the module path example.com/r/sub/mod/v2") {
+		t.Errorf("expected major version error, got %v", err)
+	}
+
+	config.Source = writeTree(t, "prebuilt.zip") + "/prebuilt.zip"
+	config.GitTag = ""
+	if _, err := handler.Push(context.Background(), config); err == nil || !strings.Contains(err.Error(), "package version must be set") {
+		t.Errorf("expected version error for a prebuilt file, got %v", err)
+	}
+}
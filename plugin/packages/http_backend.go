@@ -0,0 +1,383 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxEndpointSize limits the size of a package endpoint read into memory
+const maxEndpointSize = 64 << 20
+
+// HTTPBackend performs registry operations by calling the Harness Artifact
+// Registry endpoints directly, so the Harness CLI is not required.
+type HTTPBackend struct {
+	httpClient *http.Client
+
+	// mu guards published, the Maven versions whose POM and artifact
+	// metadata were published during this run
+	mu        sync.Mutex
+	published map[string]bool
+}
+
+// NewHTTPBackend creates a new HTTP backend. A nil client uses http.DefaultClient.
+func NewHTTPBackend(httpClient *http.Client) *HTTPBackend {
+	return &HTTPBackend{
+		httpClient: httpClient,
+		published:  map[string]bool{},
+	}
+}
+
+// Push uploads a single file with the upload protocol of its package type.
+// Package types without a standard upload protocol require the Harness CLI
+// backend.
+func (b *HTTPBackend) Push(ctx context.Context, req PushRequest) (*Result, error) {
+	switch req.PackageType {
+	case Generic:
+		return b.pushGeneric(ctx, req)
+	case NPM:
+		return b.pushNPM(ctx, req)
+	case Maven:
+		return b.pushMaven(ctx, req)
+	case Python:
+		return b.pushPython(ctx, req)
+	case Go:
+		return b.pushGo(ctx, req)
+	}
+	return nil, fmt.Errorf("%s push is not supported by the %s backend, use the %s backend instead",
+		req.PackageType.Label(), BackendHTTP, BackendCLI)
+}
+
+// pushGeneric uploads a file of a generic package
+func (b *HTTPBackend) pushGeneric(ctx context.Context, req PushRequest) (*Result, error) {
+	config := req.Config
+	filename := req.targetFilename()
+
+	info, err := os.Stat(req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", req.FilePath, err)
+	}
+
+	operation := fmt.Sprintf("push artifact '%s' to registry '%s'", req.Name, config.Registry)
+	loggerFrom(ctx).Printf("Uploading %s (%d bytes)", req.FilePath, info.Size())
+
+	client := newClient(b.httpClient, config)
+	target := client.packageURL("generic", req.Name, req.Version, filename)
+
+	var checksums Checksums
+	var raw []byte
+	err = runOperation(ctx, config, operation, func(ctx context.Context) error {
+		file, err := os.Open(req.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to open '%s': %w", req.FilePath, err)
+		}
+		defer file.Close()
+
+		digests := newChecksumWriter()
+		body := &sizedReader{Reader: io.TeeReader(file, digests), size: info.Size()}
+		resp, err := client.do(ctx, http.MethodPut, target, body, "application/octet-stream")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		raw, _ = io.ReadAll(resp.Body)
+		checksums = digests.Checksums()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+
+	logChecksums(ctx, req.FilePath, checksums)
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	result := &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+		Filename:    path.Base(filename),
+		Path:        req.FilePath,
+		URL:         target,
+		Raw:         jsonOrNil(raw),
+	}
+	result.applyChecksums(checksums)
+	return result, nil
+}
+
+// Pull downloads a single file into the destination directory
+func (b *HTTPBackend) Pull(ctx context.Context, req PullRequest) (*Result, error) {
+	config := req.Config
+	operation := fmt.Sprintf("pull artifact '%s' (version '%s', file '%s') from registry '%s' to '%s'",
+		req.Name, req.Version, req.Filename, config.Registry, req.Destination)
+
+	if err := os.MkdirAll(req.Destination, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination '%s': %w", req.Destination, err)
+	}
+	target := filepath.Join(req.Destination, path.Base(req.Path))
+
+	client := newClient(b.httpClient, config)
+	source := client.packageURL(strings.ToLower(string(req.PackageType)), req.Path)
+
+	var size int64
+	var sum string
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		resp, err := client.do(ctx, http.MethodGet, source, nil, "")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		size, sum, err = writeFile(target, resp.Body)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	return &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+		Filename:    path.Base(req.Path),
+		Path:        target,
+		URL:         source,
+		Size:        size,
+		SHA256:      sum,
+	}, nil
+}
+
+// Get retrieves the versions of an artifact and prints them as JSON
+func (b *HTTPBackend) Get(ctx context.Context, req ArtifactRequest) (*Result, error) {
+	config := req.Config
+	operation := fmt.Sprintf("get info for artifact '%s' in registry '%s'", req.Name, config.Registry)
+
+	client := newClient(b.httpClient, config)
+	source := client.apiURL("artifact", req.Name, "+", "versions")
+
+	var raw []byte
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		resp, err := client.do(ctx, http.MethodGet, source, nil, "")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		raw, err = io.ReadAll(resp.Body)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+	printJSON(raw)
+
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	return &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		URL:         source,
+		Raw:         jsonOrNil(raw),
+	}, nil
+}
+
+// Delete removes an artifact, or a single version when one is set
+func (b *HTTPBackend) Delete(ctx context.Context, req ArtifactRequest) (*Result, error) {
+	config := req.Config
+	client := newClient(b.httpClient, config)
+
+	operation := fmt.Sprintf("delete artifact '%s' from registry '%s'", req.Name, config.Registry)
+	target := client.apiURL("artifact", req.Name, "+")
+	if req.Version != "" {
+		operation = fmt.Sprintf("delete version '%s' of artifact '%s' from registry '%s'", req.Version, req.Name, config.Registry)
+		target = client.apiURL("artifact", req.Name, "+", "version", req.Version)
+	}
+
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		resp, err := client.do(ctx, http.MethodDelete, target, nil, "")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	return &Result{
+		PackageType: req.PackageType,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+		URL:         target,
+	}, nil
+}
+
+// VersionFiles lists the files of an artifact version with their checksums
+func (b *HTTPBackend) VersionFiles(ctx context.Context, req ArtifactRequest) ([]RegistryFile, error) {
+	config := req.Config
+	operation := fmt.Sprintf("list files of version '%s' of artifact '%s' in registry '%s'", req.Version, req.Name, config.Registry)
+
+	client := newClient(b.httpClient, config)
+	var files []RegistryFile
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		var err error
+		files, err = client.versionFiles(ctx, req.Name, req.Version)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+	return files, nil
+}
+
+// Read fetches a package endpoint of the registry
+func (b *HTTPBackend) Read(ctx context.Context, req EndpointRequest) ([]byte, error) {
+	config := req.Config
+	client := newClient(b.httpClient, config)
+	source := endpointURL(config, req.PackageType, req.Path)
+
+	var data []byte
+	err := runOperation(ctx, config, "get "+source, func(ctx context.Context) error {
+		resp, err := client.do(ctx, http.MethodGet, source, nil, "")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxEndpointSize+1))
+		if err == nil && len(data) > maxEndpointSize {
+			return fmt.Errorf("%s exceeds %d bytes", source, maxEndpointSize)
+		}
+		return err
+	})
+	return data, err
+}
+
+// Write uploads content to a package endpoint of the registry
+func (b *HTTPBackend) Write(ctx context.Context, req EndpointRequest) error {
+	config := req.Config
+	client := newClient(b.httpClient, config)
+	target := endpointURL(config, req.PackageType, req.Path)
+
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return runOperation(ctx, config, "upload "+target, func(ctx context.Context) error {
+		body, err := req.Open()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		resp, err := client.do(ctx, http.MethodPut, target, &sizedReader{Reader: body, size: req.Size}, contentType)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+}
+
+// SetDistTag points an npm dist-tag at a version
+func (b *HTTPBackend) SetDistTag(ctx context.Context, req DistTagRequest) error {
+	config := req.Config
+	operation := fmt.Sprintf("tag %s@%s as '%s' in registry '%s'", req.Name, req.Version, req.Tag, config.Registry)
+
+	client := newClient(b.httpClient, config)
+	body, _ := json.Marshal(req.Version)
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		resp, err := client.do(ctx, http.MethodPut, client.npmDistTagURL(req.Name, req.Tag), bytes.NewReader(body), "application/json")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", operation, err)
+	}
+	return nil
+}
+
+// RemoveDistTag deletes an npm dist-tag
+func (b *HTTPBackend) RemoveDistTag(ctx context.Context, req DistTagRequest) error {
+	config := req.Config
+	operation := fmt.Sprintf("remove tag '%s' from %s in registry '%s'", req.Tag, req.Name, config.Registry)
+
+	client := newClient(b.httpClient, config)
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		resp, err := client.do(ctx, http.MethodDelete, client.npmDistTagURL(req.Name, req.Tag), nil, "")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", operation, err)
+	}
+	return nil
+}
+
+// Close is a no-op, the HTTP backend keeps no state on disk
+func (b *HTTPBackend) Close() error {
+	return nil
+}
+
+// writeFile streams r into path through a temporary file and returns the
+// number of bytes written together with their SHA-256 checksum
+func writeFile(path string, r io.Reader) (int64, string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// printJSON writes a JSON document to stdout, indented when possible
+func printJSON(raw []byte) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		os.Stdout.Write(raw)
+		fmt.Fprintln(os.Stdout)
+		return
+	}
+	fmt.Fprintln(os.Stdout, out.String())
+}
+
+// jsonOrNil returns raw when it holds a valid JSON document
+func jsonOrNil(raw []byte) json.RawMessage {
+	if len(bytes.TrimSpace(raw)) == 0 || !json.Valid(raw) {
+		return nil
+	}
+	return json.RawMessage(raw)
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTTPBackend_PushAndPullGeneric(t *testing.T) {
+	stored := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "pat.test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			stored[r.URL.Path] = data
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			data, ok := stored[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	config := Config{
+		Token:    "pat.test",
+		Account:  "acct",
+		Registry: "generic-local",
+		PkgURL:   server.URL,
+	}
+
+	source := filepath.Join(t.TempDir(), "app.txt")
+	if err := os.WriteFile(source, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := NewHTTPBackend(server.Client())
+	pushed, err := backend.Push(context.Background(), PushRequest{
+		PackageType: Generic,
+		Config:      config,
+		FilePath:    source,
+		Name:        "my-app",
+		Version:     "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if _, ok := stored["/pkg/acct/generic-local/generic/my-app/1.0.0/app.txt"]; !ok {
+		t.Fatalf("file not uploaded to the expected path, got %v", stored)
+	}
+	if pushed.Size != 5 || pushed.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected push result: %+v", pushed)
+	}
+
+	destination := t.TempDir()
+	pulled, err := backend.Pull(context.Background(), PullRequest{
+		PackageType: Generic,
+		Config:      config,
+		Name:        "my-app",
+		Version:     "1.0.0",
+		Filename:    "app.txt",
+		Path:        "my-app/1.0.0/app.txt",
+		Destination: destination,
+	})
+	if err != nil {
+		t.Fatalf("pull failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(destination, "app.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("unexpected downloaded content %q: %v", data, err)
+	}
+	if pulled.SHA256 != pushed.SHA256 {
+		t.Errorf("checksum mismatch between push and pull: %s != %s", pushed.SHA256, pulled.SHA256)
+	}
+}
+
+func TestHTTPBackend_ReadAndWriteEndpoint(t *testing.T) {
+	stored := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			stored[r.URL.Path] = data
+			if r.Header.Get("Content-Type") != "application/xml" {
+				t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+			}
+		case http.MethodGet:
+			data, ok := stored[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	config := Config{Token: "pat.test", Account: "acct", Registry: "maven-local", PkgURL: server.URL}
+	backend := NewHTTPBackend(server.Client())
+	err := backend.Write(context.Background(), EndpointRequest{
+		PackageType: Maven,
+		Config:      config,
+		Path:        "com/example/app/maven-metadata.xml",
+		Open:        func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("<metadata/>")), nil },
+		Size:        11,
+		ContentType: "application/xml",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored["/pkg/acct/maven-local/maven/com/example/app/maven-metadata.xml"]; !ok {
+		t.Fatalf("endpoint not written to the expected path, got %v", stored)
+	}
+
+	data, err := backend.Read(context.Background(), EndpointRequest{PackageType: Maven, Config: config, Path: "com/example/app/maven-metadata.xml"})
+	if err != nil || string(data) != "<metadata/>" {
+		t.Errorf("unexpected content %q: %v", data, err)
+	}
+	var status *StatusError
+	if _, err := backend.Read(context.Background(), EndpointRequest{PackageType: Maven, Config: config, Path: "missing"}); !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Errorf("expected not found status, got %v", err)
+	}
+}
+
+func TestHTTPBackend_StatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("access denied"))
+	}))
+	defer server.Close()
+
+	backend := NewHTTPBackend(server.Client())
+	_, err := backend.Delete(context.Background(), ArtifactRequest{
+		PackageType: Generic,
+		Config: Config{
+			Token:    "ci-token",
+			Account:  "acct",
+			Registry: "generic-local",
+			ApiURL:   server.URL,
+		},
+		Name: "my-app",
+	})
+	if err == nil {
+		t.Fatal("expected error for forbidden response")
+	}
+	if !strings.Contains(err.Error(), "403 Forbidden: access denied") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHTTPBackend_PushUnsupportedType(t *testing.T) {
+	backend := NewHTTPBackend(nil)
+	_, err := backend.Push(context.Background(), PushRequest{PackageType: Cargo})
+	if err == nil || !strings.Contains(err.Error(), "Cargo push is not supported by the http backend") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
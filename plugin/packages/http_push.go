@@ -0,0 +1,350 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// send uploads an in-memory request body and returns the response body
+func (b *HTTPBackend) send(ctx context.Context, config Config, operation, method, target, contentType string, body []byte) ([]byte, error) {
+	client := newClient(b.httpClient, config)
+	var raw []byte
+	err := runOperation(ctx, config, operation, func(ctx context.Context) error {
+		resp, err := client.do(ctx, method, target, bytes.NewReader(body), contentType)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		raw, _ = io.ReadAll(resp.Body)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+	return raw, nil
+}
+
+// pushNPM publishes a package tarball the way 'npm publish' does: the
+// package document holding the version manifest and the tarball as an
+// attachment is sent to the package endpoint
+func (b *HTTPBackend) pushNPM(ctx context.Context, req PushRequest) (*Result, error) {
+	config := req.Config
+	checksums, err := fileChecksums(req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute checksums of '%s': %w", req.FilePath, err)
+	}
+	logChecksums(ctx, req.FilePath, checksums)
+
+	doc, manifest, err := npmPublishDocument(config, req.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	operation := fmt.Sprintf("publish %s@%s to registry '%s'", manifest.Name, manifest.Version, config.Registry)
+	raw, err := b.send(ctx, config, operation, http.MethodPut, npmPackumentURL(config, manifest.Name), "application/json", doc)
+	if err != nil {
+		return nil, err
+	}
+
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	tarballPath := npmTarballPath(manifest.Name, manifest.Version)
+	result := &Result{
+		PackageType: NPM,
+		Registry:    config.Registry,
+		Name:        manifest.Name,
+		Version:     manifest.Version,
+		Filename:    path.Base(tarballPath),
+		Path:        req.FilePath,
+		URL:         packageURL(config, "npm") + "/" + tarballPath,
+		Raw:         jsonOrNil(raw),
+	}
+	result.applyChecksums(checksums)
+	return result, nil
+}
+
+// npmPublishDocument builds the body of a publish request for a tarball.
+// The version manifest is the package.json of the tarball with the dist
+// metadata npm adds, the dist-tag is the tag setting or latest.
+func npmPublishDocument(config Config, tarball string) ([]byte, *npmManifest, error) {
+	packageJSON, err := readTarballPackageJSON(tarball)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, err := parseNPMManifest(packageJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid package.json in npm tarball '%s': %w", tarball, err)
+	}
+	var version map[string]interface{}
+	if err := json.Unmarshal(packageJSON, &version); err != nil {
+		return nil, nil, fmt.Errorf("invalid package.json in npm tarball '%s': %w", tarball, err)
+	}
+	data, err := os.ReadFile(tarball)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read npm tarball '%s': %w", tarball, err)
+	}
+
+	shasum := sha1.Sum(data)
+	integrity := sha512.Sum512(data)
+	tarballPath := npmTarballPath(manifest.Name, manifest.Version)
+	version["_id"] = manifest.Name + "@" + manifest.Version
+	version["dist"] = map[string]string{
+		"shasum":    hex.EncodeToString(shasum[:]),
+		"integrity": "sha512-" + base64.StdEncoding.EncodeToString(integrity[:]),
+		"tarball":   packageURL(config, "npm") + "/" + tarballPath,
+	}
+
+	tag := config.Tag
+	if tag == "" {
+		tag = defaultDistTag
+	}
+	doc, err := json.Marshal(map[string]interface{}{
+		"_id":         manifest.Name,
+		"name":        manifest.Name,
+		"description": manifest.Description,
+		"dist-tags":   map[string]string{tag: manifest.Version},
+		"versions":    map[string]interface{}{manifest.Version: version},
+		"_attachments": map[string]interface{}{
+			path.Base(tarballPath): map[string]interface{}{
+				"content_type": "application/octet-stream",
+				"data":         base64.StdEncoding.EncodeToString(data),
+				"length":       len(data),
+			},
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode publish request of %s@%s: %w", manifest.Name, manifest.Version, err)
+	}
+	return doc, manifest, nil
+}
+
+// pushMaven uploads a file of a release to the Maven repository layout with
+// its .sha1 and .md5 checksum files. The coordinates are read from the POM,
+// which is published with the artifact metadata after the first file of
+// the version.
+func (b *HTTPBackend) pushMaven(ctx context.Context, req PushRequest) (*Result, error) {
+	config := req.Config
+	if config.PomFile == "" {
+		return nil, fmt.Errorf("POM file must be set to push Maven artifacts")
+	}
+	project, err := loadMavenProject(config.PomFile)
+	if err != nil {
+		return nil, err
+	}
+	c := project.mavenCoordinates
+	c.ArtifactID = req.Name
+	c.Version = req.Version
+
+	repo := newMavenRepository(b, config)
+	filename := path.Base(req.targetFilename())
+	operation := fmt.Sprintf("push %s of %s to registry '%s'", filename, c, config.Registry)
+	checksums, err := repo.putFile(ctx, repo.path(c, c.Version, filename), req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+	logChecksums(ctx, req.FilePath, checksums)
+
+	if err := b.publishMavenVersion(ctx, repo, c, project.Path); err != nil {
+		return nil, err
+	}
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+
+	result := &Result{
+		PackageType: Maven,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     req.Version,
+		Filename:    filename,
+		Path:        req.FilePath,
+		URL:         repo.url(c, c.Version, filename),
+	}
+	result.applyChecksums(checksums)
+	return result, nil
+}
+
+// publishMavenVersion uploads the POM of a version and adds the version to
+// the artifact metadata, once per version. Projects with pom packaging push
+// the POM as their main artifact.
+func (b *HTTPBackend) publishMavenVersion(ctx context.Context, repo *mavenRepository, c mavenCoordinates, pomFile string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := repo.config.Registry + "/" + repo.path(c, c.Version, "")
+	if b.published[key] {
+		return nil
+	}
+	if c.Packaging != "pom" {
+		pomName := mavenArtifact{Extension: "pom"}.Filename(c)
+		if _, err := repo.putFile(ctx, repo.path(c, c.Version, pomName), pomFile); err != nil {
+			return fmt.Errorf("failed to upload the POM of %s: %w", c, err)
+		}
+	}
+
+	metadataPath := repo.path(c, "", mavenMetadataFilename)
+	metadata, err := repo.getMetadata(ctx, metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata of %s:%s: %w", c.GroupID, c.ArtifactID, err)
+	}
+	if metadata == nil {
+		metadata = &mavenMetadata{GroupID: c.GroupID, ArtifactID: c.ArtifactID}
+	}
+	metadata.addVersion(c.Version, now().UTC().Format("20060102150405"))
+	if err := repo.putMetadata(ctx, metadataPath, metadata); err != nil {
+		return fmt.Errorf("failed to update metadata of %s:%s: %w", c.GroupID, c.ArtifactID, err)
+	}
+	b.published[key] = true
+	return nil
+}
+
+// pushPython uploads a wheel or sdist with the legacy upload API that twine
+// uses, a multipart form posted to the root of the Python endpoint
+func (b *HTTPBackend) pushPython(ctx context.Context, req PushRequest) (*Result, error) {
+	config := req.Config
+	dist, err := inspectPythonDistribution(req.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := fileChecksums(req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute checksums of '%s': %w", req.FilePath, err)
+	}
+	logChecksums(ctx, req.FilePath, checksums)
+
+	filename := path.Base(req.targetFilename())
+	body, contentType, err := pythonUploadForm(dist, filename, checksums)
+	if err != nil {
+		return nil, err
+	}
+	operation := fmt.Sprintf("upload %s to registry '%s'", filename, config.Registry)
+	raw, err := b.send(ctx, config, operation, http.MethodPost, packageURL(config, "python")+"/", contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	result := &Result{
+		PackageType: Python,
+		Registry:    config.Registry,
+		Name:        dist.Metadata.Name,
+		Version:     dist.Metadata.Version,
+		Filename:    filename,
+		Path:        req.FilePath,
+		URL:         pythonIndexURL(config, dist.Metadata.Name),
+		Raw:         jsonOrNil(raw),
+	}
+	result.applyChecksums(checksums)
+	return result, nil
+}
+
+// pythonUploadForm builds the multipart form of an upload, the core
+// metadata fields followed by the distribution as content
+func pythonUploadForm(dist *pythonDistribution, filename string, checksums Checksums) ([]byte, string, error) {
+	filetype, pyversion := "sdist", "source"
+	if dist.Kind == "wheel" {
+		filetype, pyversion = "bdist_wheel", "py3"
+		if _, tags, ok := parsePythonFilename(dist.Metadata.Name, filepath.Base(dist.File)); ok && len(tags) == 3 {
+			pyversion = tags[0]
+		}
+	}
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for _, field := range [][2]string{
+		{":action", "file_upload"},
+		{"protocol_version", "1"},
+		{"metadata_version", dist.Metadata.MetadataVersion},
+		{"name", dist.Metadata.Name},
+		{"version", dist.Metadata.Version},
+		{"summary", dist.Metadata.Summary},
+		{"requires_python", dist.Metadata.RequiresPython},
+		{"filetype", filetype},
+		{"pyversion", pyversion},
+		{"sha256_digest", checksums.SHA256},
+		{"md5_digest", checksums.MD5},
+	} {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, "", err
+		}
+	}
+
+	part, err := form.CreateFormFile("content", filename)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(dist.File)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open '%s': %w", dist.File, err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, "", fmt.Errorf("failed to read '%s': %w", dist.File, err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), form.FormDataContentType(), nil
+}
+
+// pushGo uploads a .zip, .mod or .info file of a module version to its
+// module proxy path, <module>/@v/<version>.<ext>
+func (b *HTTPBackend) pushGo(ctx context.Context, req PushRequest) (*Result, error) {
+	config := req.Config
+	ext := filepath.Ext(req.FilePath)
+	if ext != ".zip" && ext != ".mod" && ext != ".info" {
+		return nil, fmt.Errorf("'%s' is not a module .zip, .mod or .info file", req.FilePath)
+	}
+	info, err := os.Stat(req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", req.FilePath, err)
+	}
+	checksums, err := fileChecksums(req.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute checksums of '%s': %w", req.FilePath, err)
+	}
+	logChecksums(ctx, req.FilePath, checksums)
+
+	version := "v" + strings.TrimPrefix(req.Version, "v")
+	target := escapePath(goProxyPath(req.Name, version+ext))
+	operation := fmt.Sprintf("push %s@%s (%s) to registry '%s'", req.Name, version, ext, config.Registry)
+	err = b.Write(ctx, EndpointRequest{
+		PackageType: Go,
+		Config:      config,
+		Path:        target,
+		Size:        info.Size(),
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(req.FilePath)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", operation, err)
+	}
+
+	loggerFrom(ctx).Printf("Successfully completed: %s\n", operation)
+	result := &Result{
+		PackageType: Go,
+		Registry:    config.Registry,
+		Name:        req.Name,
+		Version:     version,
+		Filename:    goProxyEscape(version) + ext,
+		Path:        req.FilePath,
+		URL:         endpointURL(config, Go, target),
+	}
+	result.applyChecksums(checksums)
+	return result, nil
+}
@@ -0,0 +1,253 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newUploadServer records the bodies of PUT and POST requests by escaped
+// path, and serves them back on GET
+func newUploadServer(t *testing.T) (*httptest.Server, *fakeMavenRepository) {
+	t.Helper()
+	repo := &fakeMavenRepository{files: map[string][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			r.Method = http.MethodPut
+		}
+		r.URL.Path = r.URL.EscapedPath()
+		repo.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, repo
+}
+
+// uploadConfig returns the settings of a push to the upload server
+func uploadConfig(server *httptest.Server, registry string) Config {
+	config := testConfig()
+	config.Account = "acct"
+	config.Registry = registry
+	config.PkgURL = server.URL
+	config.Name = ""
+	config.Version = ""
+	return config
+}
+
+func (f *fakeMavenRepository) paths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var paths []string
+	for path := range f.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestHTTPBackend_PushNPM(t *testing.T) {
+	captureLogs(t)
+	server, repo := newUploadServer(t)
+	tarball := filepath.Join(t.TempDir(), "app-1.0.0.tgz")
+	writeNPMTarball(t, tarball, `{"name": "@scope/app", "version": "1.0.0", "description": "An app", "main": "index.js"}`)
+
+	config := uploadConfig(server, "npm-local")
+	config.Source = tarball
+	config.Tag = "next"
+	result, err := NewNPMHandler(NewHTTPBackend(server.Client())).Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Name        string            `json:"name"`
+		DistTags    map[string]string `json:"dist-tags"`
+		Versions    map[string]map[string]interface{}
+		Attachments map[string]struct {
+			Data   string `json:"data"`
+			Length int    `json:"length"`
+		} `json:"_attachments"`
+	}
+	if err := json.Unmarshal(repo.files["/pkg/acct/npm-local/npm/@scope%2Fapp"], &doc); err != nil {
+		t.Fatalf("unexpected publish request %v: %v", repo.paths(), err)
+	}
+	version := doc.Versions["1.0.0"]
+	dist, _ := version["dist"].(map[string]interface{})
+	if doc.Name != "@scope/app" || !reflect.DeepEqual(doc.DistTags, map[string]string{"next": "1.0.0"}) ||
+		version["main"] != "index.js" || dist["shasum"] != result.SHA1 ||
+		dist["tarball"] != server.URL+"/pkg/acct/npm-local/npm/@scope/app/-/app-1.0.0.tgz" {
+		t.Errorf("unexpected package document %+v", doc)
+	}
+	data, _ := os.ReadFile(tarball)
+	attachment := doc.Attachments["app-1.0.0.tgz"]
+	if content, _ := base64.StdEncoding.DecodeString(attachment.Data); !bytes.Equal(content, data) || attachment.Length != len(data) {
+		t.Errorf("unexpected attachment of %d bytes", attachment.Length)
+	}
+	if result.Name != "@scope/app" || result.Filename != "app-1.0.0.tgz" || result.Metadata["NPM_DIST_TAG"] != "next" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestHTTPBackend_PushMaven(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "build/libs/app-all.jar"))
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC) }
+	server, repo := newUploadServer(t)
+
+	config := uploadConfig(server, "maven-local")
+	config.Source = "build/libs/app-all.jar"
+	config.Version = "1.0.0"
+	config.GroupID = "io.harness"
+	config.ArtifactID = "app"
+	handler := NewMavenHandler(NewHTTPBackend(server.Client()))
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"1.0.0/app-1.0.0.jar", "1.0.0/app-1.0.0.jar.md5", "1.0.0/app-1.0.0.jar.sha1",
+		"1.0.0/app-1.0.0.pom", "1.0.0/app-1.0.0.pom.md5", "1.0.0/app-1.0.0.pom.sha1",
+		"maven-metadata.xml", "maven-metadata.xml.md5", "maven-metadata.xml.sha1"}
+	var paths []string
+	for _, path := range repo.paths() {
+		paths = append(paths, strings.TrimPrefix(path, "/pkg/acct/maven-local/maven/io/harness/app/"))
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("unexpected files\n got: %q\nwant: %q", paths, want)
+	}
+	if string(repo.file(t, "1.0.0/app-1.0.0.jar")) != "build/libs/app-all.jar" || result.Files[0].SHA1 != string(repo.file(t, "1.0.0/app-1.0.0.jar.sha1")) {
+		t.Errorf("unexpected result %+v", result.Files[0])
+	}
+
+	// The next release is added to the existing metadata
+	config.Version = "1.1.0"
+	if _, err := handler.Push(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := parseMavenMetadata(repo.file(t, "maven-metadata.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := metadata.Versioning; !reflect.DeepEqual(v.Versions, []string{"1.0.0", "1.1.0"}) || v.Release != "1.1.0" || v.LastUpdated != "20240301123000" {
+		t.Errorf("unexpected metadata %+v", v)
+	}
+}
+
+func TestHTTPBackend_PushPython(t *testing.T) {
+	captureLogs(t)
+	wheel := filepath.Join(t.TempDir(), "my_pkg-1.0.0-py3-none-any.whl")
+	writeWheel(t, wheel, pythonMetadataFor("My.Pkg", "1.0.0")+"Summary: A package\n")
+
+	var fields map[string][]string
+	var content []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/pkg/acct/python-local/python/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fields = r.MultipartForm.Value
+		file, header, err := r.FormFile("content")
+		if err != nil || header.Filename != "my_pkg-1.0.0-py3-none-any.whl" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ = io.ReadAll(file)
+	}))
+	defer server.Close()
+
+	config := uploadConfig(server, "python-local")
+	config.Source = wheel
+	result, err := NewPythonHandler(NewHTTPBackend(server.Client())).Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for field, value := range map[string]string{
+		":action": "file_upload", "name": "My.Pkg", "version": "1.0.0", "summary": "A package",
+		"filetype": "bdist_wheel", "pyversion": "py3", "sha256_digest": result.SHA256, "md5_digest": result.MD5,
+	} {
+		if got := fields[field]; len(got) != 1 || got[0] != value {
+			t.Errorf("field %s = %q, want %q", field, got, value)
+		}
+	}
+	if data, _ := os.ReadFile(wheel); !bytes.Equal(content, data) {
+		t.Errorf("unexpected content of %d bytes", len(content))
+	}
+}
+
+func TestHTTPBackend_PushGo(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeGoModule(t, "module example.com/My/Mod\n", "mod.go"))
+	server, repo := newUploadServer(t)
+
+	config := uploadConfig(server, "go-local")
+	config.Source = "."
+	config.Version = "v1.0.0"
+	result, err := NewGoHandler(NewHTTPBackend(server.Client())).Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := "/pkg/acct/go-local/go/example.com/%21my/%21mod/@v/"
+	if want := []string{root + "v1.0.0.info", root + "v1.0.0.mod", root + "v1.0.0.zip"}; !reflect.DeepEqual(repo.paths(), want) {
+		t.Errorf("unexpected files\n got: %q\nwant: %q", repo.paths(), want)
+	}
+	if len(result.Files) != 3 || result.Files[0].URL != server.URL+root+"v1.0.0.zip" || result.Files[0].Filename != "v1.0.0.zip" {
+		t.Errorf("unexpected result %+v", result.Files[0])
+	}
+}
+
+func TestHTTPBackend_PushGoFile(t *testing.T) {
+	captureLogs(t)
+	server, repo := newUploadServer(t)
+	handler := NewGoHandler(NewHTTPBackend(server.Client()))
+	source := filepath.Join(writeTree(t, "v2.0.0.mod"), "v2.0.0.mod")
+
+	tests := []struct {
+		name, want string
+	}{
+		{"", "package name must be set"},
+		{"example.com/mod/", "invalid module path"},
+		{"example.com/mod", "requires the module path example.com/mod/v2"},
+	}
+	for _, test := range tests {
+		config := uploadConfig(server, "go-local")
+		config.Source = source
+		config.Name = test.name
+		config.Version = "2.0.0"
+		if _, err := handler.Push(context.Background(), config); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("name %q: expected error %q, got %v", test.name, test.want, err)
+		}
+	}
+	if paths := repo.paths(); len(paths) != 0 {
+		t.Fatalf("unexpected files %q", paths)
+	}
+
+	config := uploadConfig(server, "go-local")
+	config.Source = source
+	config.Name = "example.com/mod/v2"
+	config.Version = "2.0.0"
+	if _, err := handler.Push(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/pkg/acct/go-local/go/example.com/mod/v2/@v/v2.0.0.mod"}; !reflect.DeepEqual(repo.paths(), want) {
+		t.Errorf("unexpected files\n got: %q\nwant: %q", repo.paths(), want)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// withLogger returns a context that routes operation logs, and the output of
+// any command run on its behalf, to the given logger
+func withLogger(ctx context.Context, logger *logrus.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger attached to the context, or the standard logger
+func loggerFrom(ctx context.Context) *logrus.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*logrus.Logger); ok {
+		return logger
+	}
+	return logrus.StandardLogger()
+}
+
+// commandOutput returns the writers for the stdout and stderr of commands
+// run on behalf of the context
+func commandOutput(ctx context.Context) (stdout, stderr io.Writer) {
+	if logger, ok := ctx.Value(loggerKey{}).(*logrus.Logger); ok {
+		return logger.Out, logger.Out
+	}
+	return os.Stdout, os.Stderr
+}
+
+// newBufferedLogger returns a logger with the standard logger's format and
+// level that writes into w
+func newBufferedLogger(w io.Writer) *logrus.Logger {
+	std := logrus.StandardLogger()
+	return &logrus.Logger{
+		Out:       w,
+		Formatter: std.Formatter,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     std.GetLevel(),
+		ExitFunc:  std.ExitFunc,
+	}
+}
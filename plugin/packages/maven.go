@@ -6,7 +6,13 @@ package packages
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,9 +23,9 @@ type MavenHandler struct {
 }
 
 // NewMavenHandler creates a new Maven package handler
-func NewMavenHandler() *MavenHandler {
+func NewMavenHandler(backend Backend) *MavenHandler {
 	return &MavenHandler{
-		BaseHandler: NewBaseHandler(Maven),
+		BaseHandler: NewBaseHandler(Maven, backend),
 	}
 }
 
@@ -28,11 +34,11 @@ func (h *MavenHandler) Validate(config Config) error {
 	if config.Registry == "" {
 		return fmt.Errorf("registry name must be set")
 	}
-	if config.Source == "" {
+	if config.Source == "" && generatesPOM(config) {
 		return fmt.Errorf("source file path must be set")
 	}
-	if config.PomFile == "" {
-		return fmt.Errorf("pom file path must be set")
+	if config.Source == "" && config.PomFile == "" {
+		return fmt.Errorf("source or pom file path must be set")
 	}
 	if config.Token == "" {
 		return fmt.Errorf("authentication token must be set")
@@ -46,45 +52,439 @@ func (h *MavenHandler) Validate(config Config) error {
 	return nil
 }
 
-// Push uploads Maven packages to the registry
-func (h *MavenHandler) Push(ctx context.Context, config Config) error {
+// Push publishes the artifacts of a Maven project. The coordinates are read
+// from the POM, the main artifact and every attached artifact found in the
+// build directory are pushed together with the POM. When source is the root
+// of a multi-module build every module is published.
+func (h *MavenHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing Maven push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
-	logrus.Printf("Source path: %s", config.Source)
-	
-	return h.pushSingleFile(config, config.Source, config.Name)
+	// Projects built without Maven get a minimal POM from the settings
+	if generatesPOM(config) {
+		dir, err := os.MkdirTemp("", "maven-pom-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		if config.PomFile, err = generateMavenPOM(config, dir); err != nil {
+			return nil, err
+		}
+	} else {
+		// A source pointing at the root of a multi-module build publishes
+		// every module
+		root, err := resolveMavenReactor(config)
+		if err != nil {
+			return nil, err
+		}
+		if root != nil {
+			logrus.Printf("Resolved multi-module build %s with %d modules from %s", root, len(root.Modules), root.Path)
+			return h.pushReactor(ctx, config, root)
+		}
+	}
+
+	build, err := resolveMavenBuild(config)
+	if err != nil {
+		return nil, err
+	}
+	project := build.project
+	if err := checkMavenCoordinates(config, &project.mavenCoordinates); err != nil {
+		return nil, err
+	}
+	logrus.Printf("Resolved Maven project %s (packaging %s) from %s", project, project.Packaging, project.Path)
+
+	artifacts, err := build.artifacts(project.mavenCoordinates)
+	if err != nil {
+		return nil, err
+	}
+	if isMavenSnapshot(project.Version) {
+		return h.publishSnapshot(ctx, config, project, artifacts)
+	}
+	return h.pushArtifacts(ctx, config, project, artifacts)
+}
+
+// generatesPOM reports whether a POM is generated from the coordinate
+// settings, which is the case when no pom_file is set
+func generatesPOM(config Config) bool {
+	return config.PomFile == "" && (config.GroupID != "" || config.ArtifactID != "")
+}
+
+// pushArtifacts pushes every artifact of a project, each together with the POM
+func (h *MavenHandler) pushArtifacts(ctx context.Context, config Config, project *mavenProject, artifacts []mavenArtifact) (*Result, error) {
+	// Artifacts are named after the coordinates, a custom filename would clash
+	if config.Filename != "" {
+		logrus.Printf("⚠ Warning: filename '%s' is ignored for Maven packages", config.Filename)
+		config.Filename = ""
+	}
+	config.PomFile = project.Path
+
+	logrus.Printf("Publishing %d artifacts of %s:", len(artifacts), project)
+	for _, artifact := range artifacts {
+		logrus.Printf("  %s", artifact.Filename(project.mavenCoordinates))
+	}
+
+	jobs := make([]uploadJob, len(artifacts))
+	files := make([]*Result, len(artifacts))
+	for i, artifact := range artifacts {
+		// Files named differently, e.g. a jar built by Gradle or pom.xml, are
+		// published under their repository name
+		config := config
+		if filename := artifact.Filename(project.mavenCoordinates); filepath.Base(artifact.File) != filename {
+			config.Filename = filename
+		}
+		jobs[i] = uploadJob{
+			Label: filepath.Base(artifact.File),
+			Push: func(ctx context.Context) error {
+				result, err := h.pushSingleFile(ctx, config, artifact.File, project.ArtifactID, project.Version)
+				files[i] = result
+				return err
+			},
+		}
+	}
+	if err := runUploads(ctx, config, fmt.Sprintf("Maven project %s", project), jobs); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		PackageType: Maven,
+		Registry:    config.Registry,
+		Name:        project.ArtifactID,
+		Version:     project.Version,
+		Files:       files,
+		Metadata:    mavenOutputs(project.mavenCoordinates, artifacts),
+	}
+	for _, file := range files {
+		result.Size += file.Size
+	}
+	return result, nil
 }
 
 // pushSingleFile handles pushing a single file for Maven packages
-func (h *MavenHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Maven)
-	cmdArgs, err := buildPushCommand(Maven, config, "", filePath, artifactName, false)
+func (h *MavenHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName, version string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Maven,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+		Version:     version,
+	})
+}
+
+// mavenBuild locates the POM and the build output of a Maven project
+type mavenBuild struct {
+	project *mavenProject
+
+	// dir is the build directory holding the artifacts, usually target/
+	dir string
+
+	// mainFile is the main artifact when source points at a file
+	mainFile string
+}
+
+// resolveMavenBuild reads the POM and locates the build directory. Without
+// pom_file the POM is looked up next to the build directory and inside it,
+// without source the build directory is target/ next to the POM.
+func resolveMavenBuild(config Config) (*mavenBuild, error) {
+	build := &mavenBuild{}
+	if config.Source != "" {
+		info, err := os.Stat(config.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access source '%s': %w", config.Source, err)
+		}
+		build.dir = config.Source
+		if !info.IsDir() {
+			build.mainFile = config.Source
+			build.dir = filepath.Dir(config.Source)
+		}
+		logrus.Printf("Source path: %s", config.Source)
+	}
+
+	pomPath := config.PomFile
+	if pomPath == "" {
+		for _, candidate := range []string{
+			filepath.Join(filepath.Dir(build.dir), "pom.xml"),
+			filepath.Join(build.dir, "pom.xml"),
+		} {
+			if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+				pomPath = candidate
+				break
+			}
+		}
+		if pomPath == "" {
+			return nil, fmt.Errorf("pom file path must be set, no pom.xml found next to '%s'", build.dir)
+		}
+	}
+	if build.dir == "" {
+		build.dir = filepath.Join(filepath.Dir(pomPath), "target")
+	}
+
+	project, err := loadMavenProject(pomPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	build.project = project
+	return build, nil
+}
+
+// artifacts returns the artifacts to publish. Projects with pom packaging
+// publish the POM itself, other projects must have a main artifact.
+func (b *mavenBuild) artifacts(c mavenCoordinates) ([]mavenArtifact, error) {
+	var artifacts []mavenArtifact
+	if info, err := os.Stat(b.dir); err == nil && info.IsDir() {
+		found, err := discoverMavenArtifacts(b.dir, c)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = found
+	} else if c.Packaging != "pom" {
+		return nil, fmt.Errorf("build directory '%s' not found, build the project first", b.dir)
+	}
+
+	hasMain := false
+	for _, artifact := range artifacts {
+		hasMain = hasMain || artifact.Classifier == ""
+	}
+
+	// An explicit source file is published as the main artifact
+	if b.mainFile != "" {
+		explicit := -1
+		for i, artifact := range artifacts {
+			if filepath.Clean(artifact.File) == filepath.Clean(b.mainFile) {
+				explicit = i
+			}
+		}
+		switch {
+		case explicit >= 0 && artifacts[explicit].Classifier != "":
+			logrus.Printf("Source '%s' is the %s artifact, publishing it with the other artifacts", b.mainFile, artifacts[explicit].Classifier)
+		case explicit < 0 && hasMain:
+			return nil, fmt.Errorf("source '%s' is not an artifact of %s, the main artifact was found in '%s'", b.mainFile, c, b.dir)
+		case explicit < 0:
+			name := filepath.Base(b.mainFile)
+			main := mavenArtifact{File: b.mainFile, Extension: strings.TrimPrefix(filepath.Ext(name), ".")}
+			if main.Extension == "" {
+				main.Extension = c.mainExtension()
+			}
+			logrus.Printf("'%s' does not follow the Maven naming scheme, it is published as %s", name, main.Filename(c))
+			artifacts = append([]mavenArtifact{main}, artifacts...)
+			hasMain = true
+		}
+	}
+
+	if c.Packaging == "pom" {
+		return append([]mavenArtifact{{File: b.project.Path, Extension: "pom"}}, artifacts...), nil
+	}
+	if !hasMain {
+		return nil, fmt.Errorf("main artifact %s-%s.%s of %s not found in '%s'", c.ArtifactID, c.Version, c.mainExtension(), c, b.dir)
+	}
+	return artifacts, nil
+}
+
+// checkMavenCoordinates validates the coordinates read from the POM against
+// the name and version settings. A version left unresolved in the POM, e.g.
+// a CI friendly ${revision}, is taken from the version setting.
+func checkMavenCoordinates(config Config, c *mavenCoordinates) error {
+	if config.Version != "" {
+		if strings.Contains(c.Version, "${") {
+			logrus.Printf("Using version '%s' for the POM version '%s'", config.Version, c.Version)
+			c.Version = config.Version
+		} else if config.Version != c.Version {
+			return fmt.Errorf("version '%s' does not match the version '%s' in the POM", config.Version, c.Version)
+		}
+	}
+	if config.Name != "" && config.Name != c.ArtifactID && config.Name != c.GroupID+":"+c.ArtifactID {
+		return fmt.Errorf("name '%s' does not match the artifactId '%s' in the POM", config.Name, c.ArtifactID)
+	}
+	if config.GroupID != "" && config.GroupID != c.GroupID {
+		return fmt.Errorf("group ID '%s' does not match the groupId '%s' in the POM", config.GroupID, c.GroupID)
+	}
+	if config.ArtifactID != "" && config.ArtifactID != c.ArtifactID {
+		return fmt.Errorf("artifact ID '%s' does not match the artifactId '%s' in the POM", config.ArtifactID, c.ArtifactID)
+	}
+	if config.Packaging != "" && config.Packaging != c.Packaging {
+		return fmt.Errorf("packaging '%s' does not match the packaging '%s' in the POM", config.Packaging, c.Packaging)
+	}
+	return c.validate()
+}
+
+// mavenOutputs returns the step outputs describing a Maven project
+func mavenOutputs(c mavenCoordinates, artifacts []mavenArtifact) map[string]string {
+	outputs := map[string]string{
+		"MAVEN_GROUP_ID":    c.GroupID,
+		"MAVEN_ARTIFACT_ID": c.ArtifactID,
+		"MAVEN_VERSION":     c.Version,
+		"MAVEN_PACKAGING":   c.Packaging,
+		"MAVEN_COORDINATES": c.String(),
+	}
+	var classifiers []string
+	seen := map[string]bool{}
+	for _, artifact := range artifacts {
+		if artifact.Classifier != "" && !seen[artifact.Classifier] {
+			seen[artifact.Classifier] = true
+			classifiers = append(classifiers, artifact.Classifier)
+		}
+	}
+	if len(classifiers) > 0 {
+		outputs["MAVEN_CLASSIFIERS"] = strings.Join(classifiers, ",")
 	}
+	return outputs
+}
 
-	return executeCommand(cmdArgs, fmt.Sprintf("push Maven artifact '%s' to registry '%s'", artifactName, config.Registry))
+// reference validates the settings shared by pull, get and delete and
+// parses the artifact reference from the name and version settings
+func (h *MavenHandler) reference(config Config) (*mavenReference, error) {
+	if config.Registry == "" {
+		return nil, fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return nil, fmt.Errorf("artifact name must be set")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return nil, fmt.Errorf("account ID must be set")
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+	return parseMavenReference(config.Name, config.Version)
 }
 
-// Pull downloads Maven packages from the registry
-func (h *MavenHandler) Pull(ctx context.Context, config Config) error {
-	// TODO: Implement Maven pull logic
-	return fmt.Errorf("Maven pull is not yet implemented")
+// Pull downloads a file of a Maven artifact into the destination. The name
+// is a groupId:artifactId:version[:classifier][@extension] reference, the
+// version may also be LATEST, RELEASE or a snapshot version.
+func (h *MavenHandler) Pull(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Maven pull command")
+
+	ref, err := h.reference(config)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Version == "" {
+		return nil, fmt.Errorf("artifact version must be set")
+	}
+	if config.Destination == "" {
+		return nil, fmt.Errorf("destination path must be set")
+	}
+
+	repo := newMavenRepository(h.backend, config)
+	if err := repo.resolveVersion(ctx, ref); err != nil {
+		return nil, err
+	}
+	if err := ref.mavenCoordinates.validate(); err != nil {
+		return nil, err
+	}
+
+	filename := ref.Filename(ref.mavenCoordinates)
+	if isMavenSnapshot(ref.Version) {
+		if filename, err = repo.snapshotFilename(ctx, ref); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot metadata of %s: %w", ref, err)
+		}
+	}
+	if config.Filename != "" && config.Filename != filename {
+		logrus.Printf("⚠ Warning: filename '%s' is ignored, the file of %s is '%s'", config.Filename, ref, filename)
+	}
+	logrus.Printf("Pulling %s (%s)", ref, filename)
+
+	result, err := h.backend.Pull(ctx, PullRequest{
+		PackageType: Maven,
+		Config:      config,
+		Name:        ref.artifactName(),
+		Version:     ref.Version,
+		Filename:    filename,
+		Path:        path.Join(ref.groupPath(), ref.ArtifactID, ref.Version, filename),
+		Destination: config.Destination,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyPull(ctx, h.backend, config, result); err != nil {
+		return nil, err
+	}
+
+	result.Metadata = mavenOutputs(ref.mavenCoordinates, []mavenArtifact{ref.mavenArtifact})
+	return result, nil
 }
 
-// Get retrieves Maven package information
-func (h *MavenHandler) Get(ctx context.Context, config Config) error {
-	// TODO: Implement Maven get logic
-	return fmt.Errorf("Maven get is not yet implemented")
+// Get prints the versions of a Maven artifact and the files of each version
+// as JSON, or of a single version when one is set
+func (h *MavenHandler) Get(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Maven get command")
+
+	ref, err := h.reference(config)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := newMavenRepository(h.backend, config)
+	if err := repo.resolveVersion(ctx, ref); err != nil {
+		return nil, err
+	}
+	summary, err := repo.summary(ctx, ref.mavenCoordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode artifact summary: %w", err)
+	}
+	printJSON(raw)
+
+	// Describe the requested version, or the latest one
+	version := summary.Latest
+	if ref.Version != "" {
+		version = ref.Version
+	}
+	result := &Result{
+		PackageType: Maven,
+		Registry:    config.Registry,
+		Name:        ref.artifactName(),
+		Version:     version,
+		URL:         repo.url(ref.mavenCoordinates, "", mavenMetadataFilename),
+		Metadata: map[string]string{
+			"MAVEN_GROUP_ID":       ref.GroupID,
+			"MAVEN_ARTIFACT_ID":    ref.ArtifactID,
+			"MAVEN_VERSION_COUNT":  strconv.Itoa(len(summary.Versions)),
+			"MAVEN_LATEST_VERSION": summary.Latest,
+		},
+		Raw: raw,
+	}
+	if summary.Release != "" {
+		result.Metadata["MAVEN_RELEASE_VERSION"] = summary.Release
+	}
+
+	logrus.Printf("Artifact %s has %d versions", ref.artifactName(), len(summary.Versions))
+	return result, nil
 }
 
-// Delete removes Maven packages from the registry
-func (h *MavenHandler) Delete(ctx context.Context, config Config) error {
-	// TODO: Implement Maven delete logic
-	return fmt.Errorf("Maven delete is not yet implemented")
+// Delete removes a version of a Maven artifact, the version is required
+func (h *MavenHandler) Delete(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Maven delete command")
+
+	ref, err := h.reference(config)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Classifier != "" || ref.Extension != "jar" {
+		return nil, fmt.Errorf("a classifier or extension cannot be deleted on its own, delete the version of %s instead", ref.artifactName())
+	}
+	if ref.Version == mavenLatest || ref.Version == mavenRelease {
+		return nil, fmt.Errorf("version %s cannot be deleted, set the version explicitly", ref.Version)
+	}
+	if ref.Version == "" {
+		return nil, fmt.Errorf("artifact version must be set")
+	}
+
+	return h.backend.Delete(ctx, ArtifactRequest{
+		PackageType: Maven,
+		Config:      config,
+		Name:        ref.artifactName(),
+		Version:     ref.Version,
+	})
 }
@@ -0,0 +1,128 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mavenChecksumExtensions lists companion files that are never published
+// as artifacts, the registry computes checksums itself
+var mavenChecksumExtensions = []string{".md5", ".sha1", ".sha256", ".sha512"}
+
+// mavenPackagingExtensions maps packaging types whose main artifact does
+// not use the packaging as file extension
+var mavenPackagingExtensions = map[string]string{
+	"bundle":         "jar",
+	"ejb":            "jar",
+	"maven-plugin":   "jar",
+	"eclipse-plugin": "jar",
+	"java-source":    "jar",
+	"javadoc":        "jar",
+}
+
+// mavenArtifact is a file published with a Maven project
+type mavenArtifact struct {
+	// File is the local path of the artifact
+	File string
+
+	// Classifier distinguishes attached artifacts, e.g. sources or javadoc,
+	// it is empty for the main artifact
+	Classifier string
+
+	// Extension of the artifact, e.g. jar or tar.gz
+	Extension string
+}
+
+// Filename returns the name of the artifact in the repository
+func (a mavenArtifact) Filename(c mavenCoordinates) string {
+	if a.Classifier == "" {
+		return fmt.Sprintf("%s-%s.%s", c.ArtifactID, c.Version, a.Extension)
+	}
+	return fmt.Sprintf("%s-%s-%s.%s", c.ArtifactID, c.Version, a.Classifier, a.Extension)
+}
+
+// mainExtension returns the file extension of the main artifact
+func (c mavenCoordinates) mainExtension() string {
+	if ext, ok := mavenPackagingExtensions[c.Packaging]; ok {
+		return ext
+	}
+	return c.Packaging
+}
+
+// parseMavenFilename splits a file named <artifactId>-<version>[-<classifier>].<ext>
+// into its classifier and extension
+func parseMavenFilename(name string, c mavenCoordinates) (classifier, ext string, ok bool) {
+	rest, found := strings.CutPrefix(name, c.ArtifactID+"-"+c.Version)
+	if !found {
+		return "", "", false
+	}
+	if dashed, found := strings.CutPrefix(rest, "-"); found {
+		classifier, ext, found = strings.Cut(dashed, ".")
+		if !found || classifier == "" {
+			return "", "", false
+		}
+	} else if ext, found = strings.CutPrefix(rest, "."); !found {
+		return "", "", false
+	}
+
+	// An extension starting with a digit belongs to a longer version, e.g.
+	// app-1.0.1.jar when looking for version 1.0
+	if ext == "" || (ext[0] >= '0' && ext[0] <= '9') {
+		return "", "", false
+	}
+	return classifier, ext, true
+}
+
+// discoverMavenArtifacts returns the main and attached artifacts of the
+// project found in the build directory, the main artifact first and the
+// others sorted by file name. POM files and checksum files are skipped.
+func discoverMavenArtifacts(dir string, c mavenCoordinates) ([]mavenArtifact, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build directory '%s': %w", dir, err)
+	}
+
+	var artifacts []mavenArtifact
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		classifier, ext, ok := parseMavenFilename(entry.Name(), c)
+		if !ok || ext == "pom" {
+			continue
+		}
+		if matchesExtension(ext, mavenChecksumExtensions) {
+			continue
+		}
+		artifacts = append(artifacts, mavenArtifact{
+			File:       filepath.Join(dir, entry.Name()),
+			Classifier: classifier,
+			Extension:  ext,
+		})
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		if (artifacts[i].Classifier == "") != (artifacts[j].Classifier == "") {
+			return artifacts[i].Classifier == ""
+		}
+		return artifacts[i].File < artifacts[j].File
+	})
+	return artifacts, nil
+}
+
+// matchesExtension reports whether ext ends with one of the extensions
+func matchesExtension(ext string, extensions []string) bool {
+	for _, candidate := range extensions {
+		if strings.HasSuffix("."+ext, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mavenScopes lists the dependency scopes allowed in a generated POM
+var mavenScopes = []string{"compile", "provided", "runtime", "test", "system", "import"}
+
+// mavenDependency is a dependency declared in a generated POM
+type mavenDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Scope      string `xml:"scope,omitempty"`
+}
+
+// generatedPOM is the document written for projects without a POM
+type generatedPOM struct {
+	XMLName        xml.Name          `xml:"project"`
+	Namespace      string            `xml:"xmlns,attr"`
+	XSI            string            `xml:"xmlns:xsi,attr"`
+	SchemaLocation string            `xml:"xsi:schemaLocation,attr"`
+	ModelVersion   string            `xml:"modelVersion"`
+	GroupID        string            `xml:"groupId"`
+	ArtifactID     string            `xml:"artifactId"`
+	Version        string            `xml:"version"`
+	Packaging      string            `xml:"packaging"`
+	Description    string            `xml:"description,omitempty"`
+	Dependencies   []mavenDependency `xml:"dependencies>dependency,omitempty"`
+}
+
+// parseMavenDependencies parses dependencies in groupId:artifactId:version[:scope] form
+func parseMavenDependencies(values []string) ([]mavenDependency, error) {
+	var dependencies []mavenDependency
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		parts := strings.Split(value, ":")
+		if len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid dependency '%s': must have the form groupId:artifactId:version[:scope]", value)
+		}
+		dependency := mavenDependency{GroupID: parts[0], ArtifactID: parts[1], Version: parts[2]}
+		if len(parts) == 4 {
+			dependency.Scope = parts[3]
+			if !slices.Contains(mavenScopes, dependency.Scope) {
+				return nil, fmt.Errorf("invalid dependency '%s': scope must be one of %s", value, strings.Join(mavenScopes, ", "))
+			}
+		}
+		c := mavenCoordinates{GroupID: dependency.GroupID, ArtifactID: dependency.ArtifactID, Version: dependency.Version}
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("invalid dependency '%s': %w", value, err)
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
+// renderMavenPOM renders a minimal POM declaring the coordinates and dependencies
+func renderMavenPOM(c mavenCoordinates, description string, dependencies []mavenDependency) ([]byte, error) {
+	data, err := xml.MarshalIndent(generatedPOM{
+		Namespace:      "http://maven.apache.org/POM/4.0.0",
+		XSI:            "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://maven.apache.org/POM/4.0.0 https://maven.apache.org/xsd/maven-4.0.0.xsd",
+		ModelVersion:   "4.0.0",
+		GroupID:        c.GroupID,
+		ArtifactID:     c.ArtifactID,
+		Version:        c.Version,
+		Packaging:      c.Packaging,
+		Description:    description,
+		Dependencies:   dependencies,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// generateMavenPOM writes a minimal POM built from the group_id,
+// artifact_id, version, packaging and dependencies settings into dir and
+// returns its path. The packaging defaults to the extension of the source
+// file, or jar.
+func generateMavenPOM(config Config, dir string) (string, error) {
+	c := mavenCoordinates{
+		GroupID:    config.GroupID,
+		ArtifactID: config.ArtifactID,
+		Version:    config.Version,
+		Packaging:  config.Packaging,
+	}
+	if c.GroupID == "" {
+		return "", fmt.Errorf("group ID must be set to generate a POM")
+	}
+	if c.ArtifactID == "" {
+		return "", fmt.Errorf("artifact ID must be set to generate a POM")
+	}
+	if c.Version == "" {
+		return "", fmt.Errorf("version must be set to generate a POM")
+	}
+	if c.Packaging == "" {
+		c.Packaging = "jar"
+		if info, err := os.Stat(config.Source); err == nil && !info.IsDir() {
+			if ext := strings.TrimPrefix(filepath.Ext(config.Source), "."); ext != "" {
+				c.Packaging = ext
+			}
+		}
+	}
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	dependencies, err := parseMavenDependencies(config.Dependencies)
+	if err != nil {
+		return "", err
+	}
+	data, err := renderMavenPOM(c, config.Description, dependencies)
+	if err != nil {
+		return "", fmt.Errorf("failed to render POM: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.pom", c.ArtifactID, c.Version))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write POM: %w", err)
+	}
+	logrus.Printf("Generated POM for %s with %d dependencies", c, len(dependencies))
+	logrus.Debugf("Generated POM:\n%s", data)
+	return path, nil
+}
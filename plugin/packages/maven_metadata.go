@@ -0,0 +1,100 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"encoding/xml"
+	"slices"
+)
+
+// mavenMetadataFilename is the name of the repository metadata files
+const mavenMetadataFilename = "maven-metadata.xml"
+
+// mavenMetadata is a maven-metadata.xml document. The artifact level
+// document lists the versions of an artifact, the version level document of
+// a snapshot lists the files of the latest build.
+type mavenMetadata struct {
+	XMLName      xml.Name        `xml:"metadata"`
+	ModelVersion string          `xml:"modelVersion,attr,omitempty"`
+	GroupID      string          `xml:"groupId"`
+	ArtifactID   string          `xml:"artifactId"`
+	Version      string          `xml:"version,omitempty"`
+	Versioning   mavenVersioning `xml:"versioning"`
+}
+
+// mavenVersioning holds the versioning section of maven-metadata.xml
+type mavenVersioning struct {
+	Latest           string                 `xml:"latest,omitempty"`
+	Release          string                 `xml:"release,omitempty"`
+	Snapshot         *mavenSnapshot         `xml:"snapshot,omitempty"`
+	Versions         []string               `xml:"versions>version,omitempty"`
+	LastUpdated      string                 `xml:"lastUpdated,omitempty"`
+	SnapshotVersions []mavenSnapshotVersion `xml:"snapshotVersions>snapshotVersion,omitempty"`
+}
+
+// mavenSnapshot identifies the latest build of a snapshot version
+type mavenSnapshot struct {
+	Timestamp   string `xml:"timestamp"`
+	BuildNumber int    `xml:"buildNumber"`
+}
+
+// mavenSnapshotVersion maps a file of a snapshot to its timestamped version
+type mavenSnapshotVersion struct {
+	Classifier string `xml:"classifier,omitempty"`
+	Extension  string `xml:"extension"`
+	Value      string `xml:"value"`
+	Updated    string `xml:"updated"`
+}
+
+// parseMavenMetadata decodes maven-metadata.xml content
+func parseMavenMetadata(data []byte) (*mavenMetadata, error) {
+	var metadata mavenMetadata
+	if err := xml.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// render encodes the metadata as an XML document
+func (m *mavenMetadata) render() ([]byte, error) {
+	data, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// addVersion records a published version in artifact level metadata. Like
+// Maven, the latest version is the one published last and the release
+// version is the last published non-snapshot version.
+func (m *mavenMetadata) addVersion(version, updated string) {
+	if !slices.Contains(m.Versioning.Versions, version) {
+		m.Versioning.Versions = append(m.Versioning.Versions, version)
+	}
+	m.Versioning.Latest = version
+	if !isMavenSnapshot(version) {
+		m.Versioning.Release = version
+	}
+	m.Versioning.LastUpdated = updated
+}
+
+// addSnapshotBuild records a snapshot build in version level metadata. The
+// files of the build replace the entries with the same classifier and
+// extension, entries of files not rebuilt are kept.
+func (m *mavenMetadata) addSnapshotBuild(snapshot mavenSnapshot, files []mavenSnapshotVersion, updated string) {
+	m.Versioning.Snapshot = &snapshot
+	m.Versioning.LastUpdated = updated
+
+	kept := m.Versioning.SnapshotVersions[:0]
+	for _, existing := range m.Versioning.SnapshotVersions {
+		replaced := slices.ContainsFunc(files, func(file mavenSnapshotVersion) bool {
+			return file.Classifier == existing.Classifier && file.Extension == existing.Extension
+		})
+		if !replaced {
+			kept = append(kept, existing)
+		}
+	}
+	m.Versioning.SnapshotVersions = append(kept, files...)
+}
@@ -0,0 +1,304 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxParentDepth limits the number of parent POMs read from disk
+	maxParentDepth = 16
+
+	// maxInterpolationDepth limits nested ${property} references
+	maxInterpolationDepth = 16
+)
+
+var (
+	// mavenIDPattern matches valid groupId and artifactId values
+	mavenIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+	// mavenVersionPattern matches versions that can be used in a repository path
+	mavenVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+	// mavenPropertyPattern matches a ${property} reference
+	mavenPropertyPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+)
+
+// mavenPOM holds the elements of a pom.xml used by the plugin
+type mavenPOM struct {
+	GroupID     string          `xml:"groupId"`
+	ArtifactID  string          `xml:"artifactId"`
+	Version     string          `xml:"version"`
+	Packaging   string          `xml:"packaging"`
+	Name        string          `xml:"name"`
+	Description string          `xml:"description"`
+	Parent      *mavenParent    `xml:"parent"`
+	Properties  mavenProperties `xml:"properties"`
+	Modules     []string        `xml:"modules>module"`
+}
+
+// mavenParent references the parent POM of a project
+type mavenParent struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+
+	// RelativePath is nil when the element is absent, Maven then looks
+	// for the parent in ../pom.xml. An empty element disables the lookup.
+	RelativePath *string `xml:"relativePath"`
+}
+
+// mavenProperties holds the <properties> of a POM
+type mavenProperties map[string]string
+
+// UnmarshalXML reads every child element of <properties> as a property
+func (p *mavenProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if *p == nil {
+		*p = mavenProperties{}
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := d.DecodeElement(&value, &t); err != nil {
+				return err
+			}
+			(*p)[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// mavenCoordinates identify a Maven artifact
+type mavenCoordinates struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+	Packaging  string
+}
+
+// String returns the coordinates in groupId:artifactId:version form
+func (c mavenCoordinates) String() string {
+	return fmt.Sprintf("%s:%s:%s", c.GroupID, c.ArtifactID, c.Version)
+}
+
+// mavenProject is a POM with its inherited coordinates and properties resolved
+type mavenProject struct {
+	mavenCoordinates
+
+	// Path is the pom.xml the project was read from
+	Path string
+
+	// Description of the project, from <description> or <name>
+	Description string
+
+	// Modules lists the module directories or POM files of an aggregator,
+	// relative to the directory of its POM
+	Modules []string
+}
+
+// parseMavenPOM decodes pom.xml content
+func parseMavenPOM(data []byte) (*mavenPOM, error) {
+	var pom mavenPOM
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, err
+	}
+	return &pom, nil
+}
+
+// loadMavenProject reads a pom.xml and resolves its coordinates. The
+// groupId and version are inherited from the parent, properties of parent
+// POMs found on disk are inherited as well.
+func loadMavenProject(pomPath string) (*mavenProject, error) {
+	data, err := os.ReadFile(pomPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read POM '%s': %w", pomPath, err)
+	}
+	pom, err := parseMavenPOM(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POM '%s': %w", pomPath, err)
+	}
+
+	properties, err := inheritedProperties(pomPath, pom, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	coordinates := mavenCoordinates{
+		GroupID:    strings.TrimSpace(pom.GroupID),
+		ArtifactID: strings.TrimSpace(pom.ArtifactID),
+		Version:    strings.TrimSpace(pom.Version),
+		Packaging:  strings.TrimSpace(pom.Packaging),
+	}
+	if pom.Parent != nil {
+		if coordinates.GroupID == "" {
+			coordinates.GroupID = strings.TrimSpace(pom.Parent.GroupID)
+		}
+		if coordinates.Version == "" {
+			coordinates.Version = strings.TrimSpace(pom.Parent.Version)
+		}
+		for _, prefix := range []string{"project.parent.", "parent."} {
+			properties[prefix+"groupId"] = strings.TrimSpace(pom.Parent.GroupID)
+			properties[prefix+"artifactId"] = strings.TrimSpace(pom.Parent.ArtifactID)
+			properties[prefix+"version"] = strings.TrimSpace(pom.Parent.Version)
+		}
+	}
+	if coordinates.Packaging == "" {
+		coordinates.Packaging = "jar"
+	}
+
+	// Built-in project properties refer to the raw values, they are
+	// interpolated together with everything else
+	for _, prefix := range []string{"project.", "pom."} {
+		properties[prefix+"groupId"] = coordinates.GroupID
+		properties[prefix+"artifactId"] = coordinates.ArtifactID
+		properties[prefix+"version"] = coordinates.Version
+	}
+
+	project := &mavenProject{Path: pomPath}
+	for _, field := range []struct {
+		name  string
+		value string
+		dest  *string
+	}{
+		{"groupId", coordinates.GroupID, &project.GroupID},
+		{"artifactId", coordinates.ArtifactID, &project.ArtifactID},
+		{"version", coordinates.Version, &project.Version},
+		{"packaging", coordinates.Packaging, &project.Packaging},
+	} {
+		value, err := interpolateMaven(field.value, properties)
+		// The version may be a CI friendly ${revision} given on the command
+		// line, it is left unresolved so that the version setting can be used
+		if err != nil && field.name != "version" {
+			return nil, fmt.Errorf("failed to resolve %s of POM '%s': %w", field.name, pomPath, err)
+		}
+		*field.dest = value
+	}
+
+	project.Description = strings.TrimSpace(pom.Description)
+	if project.Description == "" {
+		project.Description = strings.TrimSpace(pom.Name)
+	}
+	for _, module := range pom.Modules {
+		if module = strings.TrimSpace(module); module != "" {
+			project.Modules = append(project.Modules, module)
+		}
+	}
+	return project, nil
+}
+
+// inheritedProperties returns the properties of the POM merged over the
+// properties of its parents found on disk
+func inheritedProperties(pomPath string, pom *mavenPOM, depth int) (map[string]string, error) {
+	properties := map[string]string{}
+	if parentPath := localParentPath(pomPath, pom); parentPath != "" && depth < maxParentDepth {
+		data, err := os.ReadFile(parentPath)
+		if err == nil {
+			parent, err := parseMavenPOM(data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parent POM '%s': %w", parentPath, err)
+			}
+			// Only use the file when it is the declared parent
+			if parent.ArtifactID == pom.Parent.ArtifactID {
+				inherited, err := inheritedProperties(parentPath, parent, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				properties = inherited
+			}
+		}
+	}
+	for key, value := range pom.Properties {
+		properties[key] = value
+	}
+	return properties, nil
+}
+
+// localParentPath returns the path of the parent POM on disk, or an empty
+// string when the POM has no parent or its lookup is disabled
+func localParentPath(pomPath string, pom *mavenPOM) string {
+	if pom.Parent == nil {
+		return ""
+	}
+	relative := "../pom.xml"
+	if pom.Parent.RelativePath != nil {
+		relative = strings.TrimSpace(*pom.Parent.RelativePath)
+	}
+	if relative == "" {
+		return ""
+	}
+	parentPath := filepath.Join(filepath.Dir(pomPath), filepath.FromSlash(relative))
+	if info, err := os.Stat(parentPath); err == nil && info.IsDir() {
+		parentPath = filepath.Join(parentPath, "pom.xml")
+	}
+	return parentPath
+}
+
+// interpolateMaven replaces ${property} references, resolving references
+// inside property values as well. Environment variables are available as
+// ${env.NAME}.
+func interpolateMaven(value string, properties map[string]string) (string, error) {
+	for depth := 0; strings.Contains(value, "${"); depth++ {
+		if depth == maxInterpolationDepth {
+			return "", fmt.Errorf("recursive property reference in '%s'", value)
+		}
+		var missing []string
+		value = mavenPropertyPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			name := ref[2 : len(ref)-1]
+			if resolved, ok := properties[name]; ok {
+				return resolved
+			}
+			if env, ok := strings.CutPrefix(name, "env."); ok {
+				if resolved, ok := os.LookupEnv(env); ok {
+					return resolved
+				}
+			}
+			missing = append(missing, ref)
+			return ref
+		})
+		if len(missing) > 0 {
+			return value, fmt.Errorf("unresolved property %s", strings.Join(missing, ", "))
+		}
+	}
+	return value, nil
+}
+
+// validate checks that the coordinates are complete and usable in a
+// repository path
+func (c mavenCoordinates) validate() error {
+	if c.GroupID == "" {
+		return fmt.Errorf("groupId must be set")
+	}
+	if c.ArtifactID == "" {
+		return fmt.Errorf("artifactId must be set")
+	}
+	if c.Version == "" {
+		return fmt.Errorf("version must be set")
+	}
+	if !mavenIDPattern.MatchString(c.GroupID) {
+		return fmt.Errorf("invalid groupId '%s': may only contain letters, digits, '_', '-' and '.'", c.GroupID)
+	}
+	if !mavenIDPattern.MatchString(c.ArtifactID) {
+		return fmt.Errorf("invalid artifactId '%s': may only contain letters, digits, '_', '-' and '.'", c.ArtifactID)
+	}
+	if strings.Contains(c.Version, "${") {
+		return fmt.Errorf("version '%s' contains an unresolved property, set the version explicitly", c.Version)
+	}
+	if !mavenVersionPattern.MatchString(c.Version) {
+		return fmt.Errorf("invalid version '%s': may only contain letters, digits, '_', '-', '+' and '.'", c.Version)
+	}
+	return nil
+}
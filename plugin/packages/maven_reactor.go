@@ -0,0 +1,187 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// resolveMavenReactor returns the aggregator project when source is the
+// root of a multi-module build, a directory whose POM declares <modules>.
+// It returns nil for single module builds.
+func resolveMavenReactor(config Config) (*mavenProject, error) {
+	if config.Source == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(config.Source)
+	if err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	pomPath := config.PomFile
+	if pomPath == "" {
+		pomPath = filepath.Join(config.Source, "pom.xml")
+	}
+	if filepath.Clean(filepath.Dir(pomPath)) != filepath.Clean(config.Source) {
+		return nil, nil
+	}
+	if info, err := os.Stat(pomPath); err != nil || !info.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	root, err := loadMavenProject(pomPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(root.Modules) == 0 {
+		return nil, nil
+	}
+	return root, nil
+}
+
+// collectMavenModules returns the builds of the aggregator and of its
+// modules in reactor order: every aggregator before its modules, modules in
+// declaration order.
+func collectMavenModules(root *mavenProject) ([]*mavenBuild, error) {
+	var builds []*mavenBuild
+	visited := map[string]bool{}
+
+	var visit func(project *mavenProject) error
+	visit = func(project *mavenProject) error {
+		path, err := filepath.Abs(project.Path)
+		if err != nil {
+			return err
+		}
+		if visited[path] {
+			return fmt.Errorf("module '%s' is included more than once", project.Path)
+		}
+		visited[path] = true
+
+		dir := filepath.Dir(project.Path)
+		builds = append(builds, &mavenBuild{project: project, dir: filepath.Join(dir, "target")})
+		if len(project.Modules) > 0 && project.Packaging != "pom" {
+			return fmt.Errorf("aggregator POM '%s' must have pom packaging, found %s", project.Path, project.Packaging)
+		}
+
+		for _, module := range project.Modules {
+			pomPath := filepath.Join(dir, filepath.FromSlash(module))
+			if info, err := os.Stat(pomPath); err == nil && info.IsDir() {
+				pomPath = filepath.Join(pomPath, "pom.xml")
+			}
+			child, err := loadMavenProject(pomPath)
+			if err != nil {
+				return fmt.Errorf("failed to load module '%s' of %s: %w", module, project.Path, err)
+			}
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	return builds, nil
+}
+
+// mavenModuleResult is the outcome of publishing one module of a reactor
+type mavenModuleResult struct {
+	project *mavenProject
+	result  *Result
+	err     error
+}
+
+// pushReactor publishes every module of a multi-module build in reactor
+// order. The artifacts of each module are looked up in its target/
+// directory. Publishing stops at the first module that fails, like
+// mvn deploy, and a summary of all modules is printed.
+func (h *MavenHandler) pushReactor(ctx context.Context, config Config, root *mavenProject) (*Result, error) {
+	builds, err := collectMavenModules(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module coordinates differ, only the version setting applies to all
+	if config.Name != "" {
+		logrus.Printf("⚠ Warning: name '%s' is ignored for multi-module builds", config.Name)
+		config.Name = ""
+	}
+
+	// Resolve every module before publishing anything
+	modules := make([]mavenModuleResult, len(builds))
+	artifacts := make([][]mavenArtifact, len(builds))
+	logrus.Printf("Reactor build order:")
+	for i, build := range builds {
+		project := build.project
+		if err := checkMavenCoordinates(config, &project.mavenCoordinates); err != nil {
+			return nil, fmt.Errorf("module %s: %w", project.Path, err)
+		}
+		found, err := build.artifacts(project.mavenCoordinates)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", project, err)
+		}
+		modules[i].project = project
+		artifacts[i] = found
+		logrus.Printf("  %s [%s]", project, project.Packaging)
+	}
+
+	var failed error
+	for i := range modules {
+		module := &modules[i]
+		if failed != nil {
+			continue
+		}
+		logrus.Printf("Publishing module %d/%d: %s", i+1, len(modules), module.project)
+		if isMavenSnapshot(module.project.Version) {
+			module.result, module.err = h.publishSnapshot(ctx, config, module.project, artifacts[i])
+		} else {
+			module.result, module.err = h.pushArtifacts(ctx, config, module.project, artifacts[i])
+		}
+		if module.err != nil {
+			failed = fmt.Errorf("failed to publish module %s: %w", module.project, module.err)
+		}
+	}
+	logReactorSummary(modules)
+	if failed != nil {
+		return nil, failed
+	}
+
+	result := &Result{
+		PackageType: Maven,
+		Registry:    config.Registry,
+		Name:        root.ArtifactID,
+		Version:     root.Version,
+		Metadata:    mavenOutputs(root.mavenCoordinates, nil),
+	}
+	var coordinates []string
+	for _, module := range modules {
+		coordinates = append(coordinates, module.project.String())
+		result.Files = append(result.Files, module.result.Files...)
+		result.Size += module.result.Size
+	}
+	result.Metadata["MAVEN_MODULES"] = strings.Join(coordinates, ",")
+	return result, nil
+}
+
+// logReactorSummary prints the outcome of every module of a reactor build
+func logReactorSummary(modules []mavenModuleResult) {
+	logrus.Printf("Reactor summary:")
+	for _, module := range modules {
+		switch {
+		case module.err != nil:
+			logrus.Printf("  ✗ %s: %v", module.project, module.err)
+		case module.result == nil:
+			logrus.Printf("  - %s: skipped", module.project)
+		default:
+			logrus.Printf("  ✓ %s: %d files, %d bytes", module.project, len(module.result.Files), module.result.Size)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// reactorPOMs is a multi-module build with a nested aggregator
+var reactorPOMs = map[string]string{
+	"pom.xml": `<project>
+  <groupId>io.harness</groupId>
+  <artifactId>platform</artifactId>
+  <version>${revision}</version>
+  <packaging>pom</packaging>
+  <properties>
+    <revision>3.0.0</revision>
+  </properties>
+  <modules>
+    <module>core</module>
+    <module>services</module>
+  </modules>
+</project>`,
+	"core/pom.xml": `<project>
+  <parent>
+    <groupId>io.harness</groupId>
+    <artifactId>platform</artifactId>
+    <version>${revision}</version>
+  </parent>
+  <artifactId>core</artifactId>
+</project>`,
+	"services/pom.xml": `<project>
+  <parent>
+    <groupId>io.harness</groupId>
+    <artifactId>platform</artifactId>
+    <version>${revision}</version>
+  </parent>
+  <artifactId>services</artifactId>
+  <packaging>pom</packaging>
+  <modules>
+    <module>api/pom.xml</module>
+  </modules>
+</project>`,
+	"services/api/pom.xml": `<project>
+  <parent>
+    <groupId>io.harness</groupId>
+    <artifactId>services</artifactId>
+    <version>${revision}</version>
+  </parent>
+  <artifactId>api</artifactId>
+</project>`,
+}
+
+// writeMavenReactor writes the reactor POMs and the given files, changes
+// into the reactor root and returns a config publishing it
+func writeMavenReactor(t *testing.T, files ...string) Config {
+	t.Helper()
+	for path := range reactorPOMs {
+		files = append(files, path)
+	}
+	root := writeTree(t, files...)
+	for path, content := range reactorPOMs {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(path)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+
+	config := testConfig()
+	config.Source = "."
+	config.Name = ""
+	config.Version = ""
+	return config
+}
+
+func TestMavenHandler_PushReactor(t *testing.T) {
+	captureLogs(t)
+	config := writeMavenReactor(t,
+		"core/target/core-3.0.0.jar",
+		"core/target/core-3.0.0-sources.jar",
+		"services/api/target/api-3.0.0.jar",
+	)
+
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("maven")
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pushed []string
+	for _, args := range runner.args() {
+		// args: hc artifact --org o --project p push maven <registry> <file> --pom-file <pom>
+		pushed = append(pushed, args[9]+" "+args[11])
+	}
+	want := []string{
+		"pom.xml pom.xml",
+		filepath.Join("core", "target", "core-3.0.0.jar") + " " + filepath.Join("core", "pom.xml"),
+		filepath.Join("core", "target", "core-3.0.0-sources.jar") + " " + filepath.Join("core", "pom.xml"),
+		filepath.Join("services", "pom.xml") + " " + filepath.Join("services", "pom.xml"),
+		filepath.Join("services", "api", "target", "api-3.0.0.jar") + " " + filepath.Join("services", "api", "pom.xml"),
+	}
+	if !reflect.DeepEqual(pushed, want) {
+		t.Errorf("unexpected pushes\n got: %q\nwant: %q", pushed, want)
+	}
+
+	modules := "io.harness:platform:3.0.0,io.harness:core:3.0.0,io.harness:services:3.0.0,io.harness:api:3.0.0"
+	if result.Metadata["MAVEN_MODULES"] != modules || result.Metadata["MAVEN_COORDINATES"] != "io.harness:platform:3.0.0" {
+		t.Errorf("unexpected outputs %v", result.Metadata)
+	}
+	if len(result.Files) != 5 {
+		t.Errorf("expected 5 file results, got %d", len(result.Files))
+	}
+}
+
+func TestMavenHandler_PushReactorStopsAtFailedModule(t *testing.T) {
+	logs := captureLogs(t)
+	config := writeMavenReactor(t,
+		"core/target/core-3.0.0.jar",
+		"services/api/target/api-3.0.0.jar",
+	)
+
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		if strings.HasSuffix(cmd.Args[9], "core-3.0.0.jar") {
+			return errors.New("upload rejected")
+		}
+		return nil
+	}
+	handler, _ := factory.GetHandler("maven")
+
+	_, err := handler.Push(context.Background(), config)
+	if err == nil || !strings.Contains(err.Error(), "failed to publish module io.harness:core:3.0.0") {
+		t.Fatalf("expected module failure, got %v", err)
+	}
+	for _, args := range runner.args() {
+		if strings.HasPrefix(args[9], "services") {
+			t.Errorf("module after the failed one was published: %s", args[9])
+		}
+	}
+	for _, line := range []string{"✓ io.harness:platform:3.0.0", "✗ io.harness:core:3.0.0", "- io.harness:api:3.0.0: skipped"} {
+		if !strings.Contains(logs.String(), line) {
+			t.Errorf("summary does not contain %q:\n%s", line, logs.String())
+		}
+	}
+}
+
+func TestMavenHandler_PushReactorMissingArtifact(t *testing.T) {
+	captureLogs(t)
+	config := writeMavenReactor(t, "core/target/core-3.0.0.jar")
+
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("maven")
+
+	_, err := handler.Push(context.Background(), config)
+	if err == nil || !strings.Contains(err.Error(), "module io.harness:api:3.0.0") {
+		t.Fatalf("expected missing artifact error, got %v", err)
+	}
+	// Nothing is published when a module cannot be resolved
+	if len(runner.args()) != 0 {
+		t.Errorf("unexpected pushes %v", runner.args())
+	}
+}
+
+func TestCollectMavenModules_Cycle(t *testing.T) {
+	root := writeTree(t)
+	pom := `<project><groupId>g</groupId><artifactId>a</artifactId><version>1</version><packaging>pom</packaging><modules><module>.</module></modules></project>`
+	if err := os.WriteFile(filepath.Join(root, "pom.xml"), []byte(pom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := loadMavenProject(filepath.Join(root, "pom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = collectMavenModules(project)
+	if err == nil || !strings.Contains(err.Error(), "included more than once") {
+		t.Errorf("expected duplicate module error, got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// mavenLatest and mavenRelease are the version keywords resolved from
+	// the artifact metadata
+	mavenLatest  = "LATEST"
+	mavenRelease = "RELEASE"
+)
+
+// mavenRepository reads and writes files of the Maven repository layout of
+// a registry, <pkg_url>/pkg/<account>/<registry>/maven/<group path>/...,
+// through the package endpoints of a backend
+type mavenRepository struct {
+	backend Backend
+	config  Config
+}
+
+func newMavenRepository(backend Backend, config Config) *mavenRepository {
+	return &mavenRepository{
+		backend: backend,
+		config:  config,
+	}
+}
+
+// path returns the escaped repository path of a file in the directory of an
+// artifact, or of one of its versions when version is set
+func (r *mavenRepository) path(c mavenCoordinates, version, filename string) string {
+	elems := []string{escapePath(c.groupPath()), escapePath(c.ArtifactID)}
+	if version != "" {
+		elems = append(elems, escapePath(version))
+	}
+	return strings.Join(append(elems, escapePath(filename)), "/")
+}
+
+// url returns the URL of a file of the repository
+func (r *mavenRepository) url(c mavenCoordinates, version, filename string) string {
+	return endpointURL(r.config, Maven, r.path(c, version, filename))
+}
+
+// get downloads a file, it returns nil when the file does not exist
+func (r *mavenRepository) get(ctx context.Context, target string) ([]byte, error) {
+	data, err := r.backend.Read(ctx, EndpointRequest{
+		PackageType: Maven,
+		Config:      r.config,
+		Path:        target,
+	})
+	var status *StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return data, err
+}
+
+// put uploads the content returned by open, followed by its .sha1 and .md5
+// checksum files
+func (r *mavenRepository) put(ctx context.Context, target string, size int64, open func() (io.ReadCloser, error)) (Checksums, error) {
+	// The content is opened again on retries, only the digests of the last
+	// attempt count
+	var digests *checksumWriter
+	err := r.backend.Write(ctx, EndpointRequest{
+		PackageType: Maven,
+		Config:      r.config,
+		Path:        target,
+		Size:        size,
+		Open: func() (io.ReadCloser, error) {
+			body, err := open()
+			if err != nil {
+				return nil, err
+			}
+			digests = newChecksumWriter()
+			return struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(body, digests), body}, nil
+		},
+	})
+	if err != nil {
+		return Checksums{}, err
+	}
+
+	checksums := digests.Checksums()
+	for ext, sum := range map[string]string{".sha1": checksums.SHA1, ".md5": checksums.MD5} {
+		if err := r.putBytes(ctx, target+ext, []byte(sum)); err != nil {
+			return Checksums{}, err
+		}
+	}
+	return checksums, nil
+}
+
+// putBytes uploads in-memory content without checksum files
+func (r *mavenRepository) putBytes(ctx context.Context, target string, data []byte) error {
+	return r.backend.Write(ctx, EndpointRequest{
+		PackageType: Maven,
+		Config:      r.config,
+		Path:        target,
+		Size:        int64(len(data)),
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	})
+}
+
+// putFile uploads a local file with its checksum files
+func (r *mavenRepository) putFile(ctx context.Context, target, file string) (Checksums, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return Checksums{}, fmt.Errorf("failed to stat '%s': %w", file, err)
+	}
+	return r.put(ctx, target, info.Size(), func() (io.ReadCloser, error) {
+		return os.Open(file)
+	})
+}
+
+// getMetadata downloads and decodes a maven-metadata.xml file, it returns
+// nil when the file does not exist
+func (r *mavenRepository) getMetadata(ctx context.Context, target string) (*mavenMetadata, error) {
+	data, err := r.get(ctx, target)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	metadata, err := parseMavenMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s at %s: %w", mavenMetadataFilename, target, err)
+	}
+	return metadata, nil
+}
+
+// putMetadata encodes and uploads a maven-metadata.xml file with its checksum files
+func (r *mavenRepository) putMetadata(ctx context.Context, target string, metadata *mavenMetadata) error {
+	data, err := metadata.render()
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", mavenMetadataFilename, err)
+	}
+	_, err = r.put(ctx, target, int64(len(data)), func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+	return err
+}
+
+// mavenReference identifies a file of a Maven artifact, written as
+// groupId:artifactId[:version[:classifier]][@extension]
+type mavenReference struct {
+	mavenCoordinates
+	mavenArtifact
+}
+
+// parseMavenReference parses a reference. The version may also be given
+// separately, both must match when set. The extension defaults to jar.
+func parseMavenReference(name, version string) (*mavenReference, error) {
+	name = strings.TrimSpace(name)
+	ref := &mavenReference{mavenArtifact: mavenArtifact{Extension: "jar"}}
+	if rest, ext, found := strings.Cut(name, "@"); found {
+		if ext == "" {
+			return nil, fmt.Errorf("invalid Maven reference '%s': extension must not be empty", name)
+		}
+		name, ref.Extension = rest, ext
+	}
+
+	parts := strings.Split(name, ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, fmt.Errorf("invalid Maven reference '%s': must have the form groupId:artifactId[:version[:classifier]][@extension]", name)
+	}
+	ref.GroupID, ref.ArtifactID = parts[0], parts[1]
+	if len(parts) > 2 {
+		ref.Version = parts[2]
+	}
+	if len(parts) > 3 {
+		if parts[3] == "" {
+			return nil, fmt.Errorf("invalid Maven reference '%s': classifier must not be empty", name)
+		}
+		ref.Classifier = parts[3]
+	}
+
+	switch {
+	case ref.Version == "":
+		ref.Version = version
+	case version != "" && version != ref.Version:
+		return nil, fmt.Errorf("version '%s' does not match the version '%s' of '%s'", version, ref.Version, name)
+	}
+
+	// The version is validated once resolved, it may still be empty here
+	c := ref.mavenCoordinates
+	if c.Version == "" {
+		c.Version = mavenLatest
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid Maven reference '%s': %w", name, err)
+	}
+	if !mavenIDPattern.MatchString(ref.Extension) || (ref.Classifier != "" && !mavenIDPattern.MatchString(ref.Classifier)) {
+		return nil, fmt.Errorf("invalid Maven reference '%s': invalid classifier or extension", name)
+	}
+	return ref, nil
+}
+
+// artifactName returns the name of the artifact in the registry
+func (c mavenCoordinates) artifactName() string {
+	return c.GroupID + ":" + c.ArtifactID
+}
+
+// groupPath returns the repository path of the groupId
+func (c mavenCoordinates) groupPath() string {
+	return strings.ReplaceAll(c.GroupID, ".", "/")
+}
+
+// resolveVersion replaces the LATEST and RELEASE keywords by the versions
+// recorded in the artifact metadata
+func (r *mavenRepository) resolveVersion(ctx context.Context, ref *mavenReference) error {
+	if ref.Version != mavenLatest && ref.Version != mavenRelease {
+		return nil
+	}
+	metadata, err := r.getMetadata(ctx, r.path(ref.mavenCoordinates, "", mavenMetadataFilename))
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		return fmt.Errorf("artifact %s not found in registry '%s'", ref.artifactName(), r.config.Registry)
+	}
+
+	resolved := metadata.Versioning.Latest
+	if ref.Version == mavenRelease {
+		resolved = metadata.Versioning.Release
+	}
+	if resolved == "" {
+		return fmt.Errorf("artifact %s has no %s version", ref.artifactName(), ref.Version)
+	}
+	logrus.Printf("Resolved %s version of %s: %s", ref.Version, ref.artifactName(), resolved)
+	ref.Version = resolved
+	return nil
+}
+
+// snapshotFilename returns the name of the file of the latest build of a
+// snapshot, read from the version metadata. Snapshots deployed without
+// unique versions keep the plain file name.
+func (r *mavenRepository) snapshotFilename(ctx context.Context, ref *mavenReference) (string, error) {
+	metadata, err := r.getMetadata(ctx, r.path(ref.mavenCoordinates, ref.Version, mavenMetadataFilename))
+	if err != nil || metadata == nil {
+		return ref.Filename(ref.mavenCoordinates), err
+	}
+
+	unique := ref.mavenCoordinates
+	for _, entry := range metadata.Versioning.SnapshotVersions {
+		if entry.Classifier == ref.Classifier && entry.Extension == ref.Extension {
+			unique.Version = entry.Value
+			return ref.Filename(unique), nil
+		}
+	}
+	// Metadata written without snapshotVersions only records the latest build
+	if s := metadata.Versioning.Snapshot; s != nil && s.Timestamp != "" {
+		unique.Version = fmt.Sprintf("%s-%s-%d", strings.TrimSuffix(ref.Version, mavenSnapshotSuffix), s.Timestamp, s.BuildNumber)
+		return ref.Filename(unique), nil
+	}
+	return ref.Filename(ref.mavenCoordinates), nil
+}
+
+// mavenArtifactSummary is the JSON document printed by get
+type mavenArtifactSummary struct {
+	GroupID     string                `json:"groupId"`
+	ArtifactID  string                `json:"artifactId"`
+	Latest      string                `json:"latest,omitempty"`
+	Release     string                `json:"release,omitempty"`
+	LastUpdated string                `json:"lastUpdated,omitempty"`
+	Versions    []mavenVersionSummary `json:"versions"`
+}
+
+// mavenVersionSummary describes a version and its files
+type mavenVersionSummary struct {
+	Version string         `json:"version"`
+	Files   []RegistryFile `json:"files"`
+}
+
+// summary lists the versions of an artifact with the files of each one, or
+// only the given version when set
+func (r *mavenRepository) summary(ctx context.Context, c mavenCoordinates) (*mavenArtifactSummary, error) {
+	metadata, err := r.getMetadata(ctx, r.path(c, "", mavenMetadataFilename))
+	if err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("artifact %s not found in registry '%s'", c.artifactName(), r.config.Registry)
+	}
+
+	summary := &mavenArtifactSummary{
+		GroupID:     c.GroupID,
+		ArtifactID:  c.ArtifactID,
+		Latest:      metadata.Versioning.Latest,
+		Release:     metadata.Versioning.Release,
+		LastUpdated: metadata.Versioning.LastUpdated,
+	}
+	versions := metadata.Versioning.Versions
+	if c.Version != "" {
+		if !slices.Contains(versions, c.Version) {
+			return nil, fmt.Errorf("version '%s' of artifact %s not found in registry '%s'", c.Version, c.artifactName(), r.config.Registry)
+		}
+		versions = []string{c.Version}
+	}
+	for _, version := range versions {
+		files, err := r.backend.VersionFiles(ctx, ArtifactRequest{
+			PackageType: Maven,
+			Config:      r.config,
+			Name:        c.artifactName(),
+			Version:     version,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of %s:%s: %w", c.artifactName(), version, err)
+		}
+		summary.Versions = append(summary.Versions, mavenVersionSummary{Version: version, Files: files})
+	}
+	return summary, nil
+}
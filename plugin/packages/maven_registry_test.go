@@ -0,0 +1,227 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const artifactMetadataXML = `<metadata>
+  <groupId>io.harness</groupId>
+  <artifactId>app</artifactId>
+  <versioning>
+    <latest>1.1.0-SNAPSHOT</latest>
+    <release>1.0.0</release>
+    <versions>
+      <version>1.0.0</version>
+      <version>1.1.0-SNAPSHOT</version>
+    </versions>
+    <lastUpdated>20240301123000</lastUpdated>
+  </versioning>
+</metadata>`
+
+const snapshotMetadataXML = `<metadata>
+  <groupId>io.harness</groupId>
+  <artifactId>app</artifactId>
+  <version>1.1.0-SNAPSHOT</version>
+  <versioning>
+    <snapshot><timestamp>20240301.123000</timestamp><buildNumber>2</buildNumber></snapshot>
+    <snapshotVersions>
+      <snapshotVersion><extension>jar</extension><value>1.1.0-20240301.123000-2</value></snapshotVersion>
+      <snapshotVersion><classifier>sources</classifier><extension>jar</extension><value>1.1.0-20240301.120000-1</value></snapshotVersion>
+    </snapshotVersions>
+  </versioning>
+</metadata>`
+
+// newMavenRegistryServer serves the metadata of io.harness:app and the
+// files API of its versions
+func newMavenRegistryServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/pkg/test-account/test-registry/maven/io/harness/app/maven-metadata.xml":
+			w.Write([]byte(artifactMetadataXML))
+		case r.URL.Path == "/pkg/test-account/test-registry/maven/io/harness/app/1.1.0-SNAPSHOT/maven-metadata.xml":
+			w.Write([]byte(snapshotMetadataXML))
+		case strings.HasSuffix(r.URL.Path, "/artifact/io.harness:app/+/version/1.0.0/files"):
+			w.Write([]byte(`{"data": {"files": [{"name": "app-1.0.0.jar", "checksums": ["SHA-256: abc"]}, {"name": "app-1.0.0.pom"}]}}`))
+		case strings.HasSuffix(r.URL.Path, "/artifact/io.harness:app/+/version/1.1.0-SNAPSHOT/files"):
+			w.Write([]byte(`{"data": {"files": [{"name": "app-1.1.0-20240301.123000-2.jar"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseMavenReference(t *testing.T) {
+	tests := []struct {
+		name, version string
+		want          mavenReference
+		err           string
+	}{
+		{name: "io.harness:app:1.0.0", want: mavenReference{
+			mavenCoordinates{GroupID: "io.harness", ArtifactID: "app", Version: "1.0.0"},
+			mavenArtifact{Extension: "jar"},
+		}},
+		{name: "io.harness:app:1.0.0:sources@zip", want: mavenReference{
+			mavenCoordinates{GroupID: "io.harness", ArtifactID: "app", Version: "1.0.0"},
+			mavenArtifact{Classifier: "sources", Extension: "zip"},
+		}},
+		{name: "io.harness:app@pom", version: "2.0", want: mavenReference{
+			mavenCoordinates{GroupID: "io.harness", ArtifactID: "app", Version: "2.0"},
+			mavenArtifact{Extension: "pom"},
+		}},
+		{name: "io.harness:app", want: mavenReference{
+			mavenCoordinates{GroupID: "io.harness", ArtifactID: "app"},
+			mavenArtifact{Extension: "jar"},
+		}},
+		{name: "io.harness:app:1.0.0", version: "1.0.1", err: "does not match"},
+		{name: "app", err: "must have the form"},
+		{name: "io.harness:app:1.0.0:sources:x", err: "must have the form"},
+		{name: "io.harness:app:1.0.0@", err: "extension must not be empty"},
+		{name: "io.harness:app:1.0.0:", err: "classifier must not be empty"},
+		{name: "io/harness:app:1.0.0", err: "invalid Maven reference"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := parseMavenReference(test.name, test.version)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*ref, test.want) {
+				t.Errorf("got %+v, want %+v", *ref, test.want)
+			}
+		})
+	}
+}
+
+func TestCLIBackend_MavenCommands(t *testing.T) {
+	captureLogs(t)
+	hc := getHarnessBin()
+	destination := t.TempDir()
+	server := newMavenRegistryServer(t)
+
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("maven")
+
+	config := testConfig()
+	config.PkgURL = server.URL
+	config.Destination = destination
+	config.Version = ""
+
+	pulls := map[string]string{
+		"io.harness:app:1.0.0":                  "io/harness/app/1.0.0/app-1.0.0.jar",
+		"io.harness:app:RELEASE@pom":            "io/harness/app/1.0.0/app-1.0.0.pom",
+		"io.harness:app:1.1.0-SNAPSHOT":         "io/harness/app/1.1.0-SNAPSHOT/app-1.1.0-20240301.123000-2.jar",
+		"io.harness:app:LATEST:sources":         "io/harness/app/1.1.0-SNAPSHOT/app-1.1.0-20240301.120000-1-sources.jar",
+		"io.harness:app:1.1.0-SNAPSHOT:javadoc": "io/harness/app/1.1.0-SNAPSHOT/app-1.1.0-20240301.123000-2-javadoc.jar",
+	}
+	for name, path := range pulls {
+		runner.calls = nil
+		config.Name = name
+		result, err := handler.Pull(context.Background(), config)
+		if err != nil {
+			t.Fatalf("pull %s failed: %v", name, err)
+		}
+		args := runner.args()
+		if len(args) != 1 || args[0][3] != "MAVEN" || args[0][5] != path || args[0][6] != destination {
+			t.Errorf("pull %s: unexpected commands %q, want path %s", name, args, path)
+		}
+		if result.Name != "io.harness:app" || result.Metadata["MAVEN_ARTIFACT_ID"] != "app" {
+			t.Errorf("pull %s: unexpected result %+v", name, result)
+		}
+	}
+
+	runner.calls = nil
+	config.Name = "io.harness:app:1.0.0"
+	if _, err := handler.Delete(context.Background(), config); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	want := [][]string{
+		{hc, "artifact", "delete", "io.harness:app",
+			"--registry", "test-registry", "--token", "test-token", "--account", "test-account",
+			"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+			"--version", "1.0.0", "--format", "json"},
+	}
+	if got := runner.args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected commands\n got: %q\nwant: %q", got, want)
+	}
+
+	for _, name := range []string{"io.harness:app:1.0.0:sources", "io.harness:app:LATEST"} {
+		config.Name = name
+		if _, err := handler.Delete(context.Background(), config); err == nil {
+			t.Errorf("expected delete of %s to fail", name)
+		}
+	}
+	config.Name = "io.harness:app"
+	config.Version = ""
+	if _, err := handler.Delete(context.Background(), config); err == nil || !strings.Contains(err.Error(), "artifact version must be set") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+}
+
+func TestMavenHandler_Get(t *testing.T) {
+	captureLogs(t)
+	server := newMavenRegistryServer(t)
+
+	config := testConfig()
+	config.PkgURL = server.URL
+	config.ApiURL = server.URL
+	config.Name = "io.harness:app"
+	config.Version = ""
+	handler := NewMavenHandler(NewHTTPBackend(server.Client()))
+
+	result, err := handler.Get(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary mavenArtifactSummary
+	if err := json.Unmarshal(result.Raw, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Versions) != 2 || summary.Release != "1.0.0" || len(summary.Versions[0].Files) != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if summary.Versions[1].Files[0].Name != "app-1.1.0-20240301.123000-2.jar" {
+		t.Errorf("unexpected snapshot files %+v", summary.Versions[1].Files)
+	}
+	if result.Version != "1.1.0-SNAPSHOT" || result.Metadata["MAVEN_VERSION_COUNT"] != "2" || result.Metadata["MAVEN_RELEASE_VERSION"] != "1.0.0" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "1.0.0"
+	result, err = handler.Get(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "1.0.0" || result.Metadata["MAVEN_VERSION_COUNT"] != "1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "9.9.9"
+	if _, err := handler.Get(context.Background(), config); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected version not found error, got %v", err)
+	}
+
+	config.Name = "io.harness:missing"
+	config.Version = ""
+	if _, err := handler.Get(context.Background(), config); err == nil || !strings.Contains(err.Error(), "artifact io.harness:missing not found") {
+		t.Errorf("expected artifact not found error, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mavenSnapshotSuffix marks a Maven version as snapshot
+const mavenSnapshotSuffix = "-SNAPSHOT"
+
+// now returns the current time, replaced in tests
+var now = time.Now
+
+// isMavenSnapshot reports whether the version is a snapshot version
+func isMavenSnapshot(version string) bool {
+	return strings.HasSuffix(version, mavenSnapshotSuffix)
+}
+
+// publishSnapshot publishes a snapshot build the way Maven deploys it: every
+// file gets the unique <version>-<timestamp>-<build number> version, is
+// uploaded with its .sha1 and .md5 files, and the version and artifact
+// level maven-metadata.xml files are updated to point at the new build.
+// Snapshots are published through the Maven repository API of the registry,
+// with any backend.
+func (h *MavenHandler) publishSnapshot(ctx context.Context, config Config, project *mavenProject, artifacts []mavenArtifact) (*Result, error) {
+	repo := newMavenRepository(h.backend, config)
+	c := project.mavenCoordinates
+
+	versionMetadataPath := repo.path(c, c.Version, mavenMetadataFilename)
+	versionMetadata, err := repo.getMetadata(ctx, versionMetadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot metadata of %s: %w", c, err)
+	}
+	if versionMetadata == nil {
+		versionMetadata = &mavenMetadata{GroupID: c.GroupID, ArtifactID: c.ArtifactID, Version: c.Version}
+	}
+
+	// The next build number follows the latest build in the registry
+	published := now().UTC()
+	snapshot := mavenSnapshot{Timestamp: published.Format("20060102.150405"), BuildNumber: 1}
+	if versionMetadata.Versioning.Snapshot != nil {
+		snapshot.BuildNumber = versionMetadata.Versioning.Snapshot.BuildNumber + 1
+	}
+	updated := published.Format("20060102150405")
+
+	unique := c
+	unique.Version = fmt.Sprintf("%s-%s-%d", strings.TrimSuffix(c.Version, mavenSnapshotSuffix), snapshot.Timestamp, snapshot.BuildNumber)
+	logrus.Printf("Publishing snapshot %s as build %d (%s)", c, snapshot.BuildNumber, unique.Version)
+
+	// The POM is published like any other file of the build
+	files := artifacts
+	if c.Packaging != "pom" {
+		files = append([]mavenArtifact{{File: project.Path, Extension: "pom"}}, artifacts...)
+	}
+
+	jobs := make([]uploadJob, len(files))
+	results := make([]*Result, len(files))
+	entries := make([]mavenSnapshotVersion, len(files))
+	for i, file := range files {
+		filename := file.Filename(unique)
+		target := repo.path(c, c.Version, filename)
+		entries[i] = mavenSnapshotVersion{Classifier: file.Classifier, Extension: file.Extension, Value: unique.Version, Updated: updated}
+		jobs[i] = uploadJob{
+			Label: filename,
+			Push: func(ctx context.Context) error {
+				checksums, err := repo.putFile(ctx, target, file.File)
+				if err != nil {
+					return fmt.Errorf("failed to upload '%s': %w", file.File, err)
+				}
+				results[i] = &Result{
+					PackageType: Maven,
+					Registry:    config.Registry,
+					Name:        c.ArtifactID,
+					Version:     c.Version,
+					Filename:    filename,
+					Path:        file.File,
+					URL:         repo.url(c, c.Version, filename),
+				}
+				results[i].applyChecksums(checksums)
+				return nil
+			},
+		}
+	}
+	if err := runUploads(ctx, config, fmt.Sprintf("Maven snapshot %s", c), jobs); err != nil {
+		return nil, err
+	}
+
+	// Metadata is only updated once every file of the build is available
+	versionMetadata.addSnapshotBuild(snapshot, entries, updated)
+	if err := repo.putMetadata(ctx, versionMetadataPath, versionMetadata); err != nil {
+		return nil, fmt.Errorf("failed to update snapshot metadata of %s: %w", c, err)
+	}
+
+	artifactMetadataPath := repo.path(c, "", mavenMetadataFilename)
+	artifactMetadata, err := repo.getMetadata(ctx, artifactMetadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s:%s: %w", c.GroupID, c.ArtifactID, err)
+	}
+	if artifactMetadata == nil {
+		artifactMetadata = &mavenMetadata{GroupID: c.GroupID, ArtifactID: c.ArtifactID}
+	}
+	artifactMetadata.addVersion(c.Version, updated)
+	if err := repo.putMetadata(ctx, artifactMetadataPath, artifactMetadata); err != nil {
+		return nil, fmt.Errorf("failed to update metadata of %s:%s: %w", c.GroupID, c.ArtifactID, err)
+	}
+	logrus.Printf("✓ Updated %s of %s", mavenMetadataFilename, c)
+
+	result := &Result{
+		PackageType: Maven,
+		Registry:    config.Registry,
+		Name:        c.ArtifactID,
+		Version:     c.Version,
+		URL:         repo.url(c, c.Version, ""),
+		Files:       results,
+		Metadata:    mavenOutputs(c, artifacts),
+	}
+	for _, file := range results {
+		result.Size += file.Size
+	}
+	result.Metadata["MAVEN_SNAPSHOT_VERSION"] = unique.Version
+	result.Metadata["MAVEN_BUILD_NUMBER"] = strconv.Itoa(snapshot.BuildNumber)
+	return result, nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMavenRepository serves a Maven repository layout from memory
+type fakeMavenRepository struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (f *fakeMavenRepository) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.files[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeMavenRepository) file(t *testing.T, path string) []byte {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.files["/pkg/acct/maven-local/maven/io/harness/app/"+path]
+	if !ok {
+		t.Fatalf("%s was not published", path)
+	}
+	return data
+}
+
+func TestIsMavenSnapshot(t *testing.T) {
+	for version, want := range map[string]bool{
+		"1.0.0-SNAPSHOT": true,
+		"1.0-SNAPSHOT":   true,
+		"1.0.0":          false,
+		"1.0.0-snapshot": false,
+		"SNAPSHOT-1.0":   false,
+	} {
+		if got := isMavenSnapshot(version); got != want {
+			t.Errorf("isMavenSnapshot(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestMavenMetadata_AddSnapshotBuild(t *testing.T) {
+	metadata := &mavenMetadata{Versioning: mavenVersioning{SnapshotVersions: []mavenSnapshotVersion{
+		{Extension: "jar", Value: "1.0-20240101.000000-1"},
+		{Classifier: "sources", Extension: "jar", Value: "1.0-20240101.000000-1"},
+	}}}
+	metadata.addSnapshotBuild(mavenSnapshot{Timestamp: "20240102.000000", BuildNumber: 2}, []mavenSnapshotVersion{
+		{Extension: "jar", Value: "1.0-20240102.000000-2"},
+		{Extension: "pom", Value: "1.0-20240102.000000-2"},
+	}, "20240102000000")
+
+	want := []mavenSnapshotVersion{
+		{Classifier: "sources", Extension: "jar", Value: "1.0-20240101.000000-1"},
+		{Extension: "jar", Value: "1.0-20240102.000000-2"},
+		{Extension: "pom", Value: "1.0-20240102.000000-2"},
+	}
+	if !reflect.DeepEqual(metadata.Versioning.SnapshotVersions, want) {
+		t.Errorf("unexpected snapshot versions %+v", metadata.Versioning.SnapshotVersions)
+	}
+	if metadata.Versioning.Snapshot.BuildNumber != 2 || metadata.Versioning.LastUpdated != "20240102000000" {
+		t.Errorf("unexpected versioning %+v", metadata.Versioning)
+	}
+}
+
+func TestMavenMetadata_AddVersion(t *testing.T) {
+	metadata := &mavenMetadata{}
+	metadata.addVersion("1.0.0", "1")
+	metadata.addVersion("1.1.0-SNAPSHOT", "2")
+	metadata.addVersion("1.1.0-SNAPSHOT", "3")
+
+	v := metadata.Versioning
+	if v.Latest != "1.1.0-SNAPSHOT" || v.Release != "1.0.0" || v.LastUpdated != "3" {
+		t.Errorf("unexpected versioning %+v", v)
+	}
+	if want := []string{"1.0.0", "1.1.0-SNAPSHOT"}; !reflect.DeepEqual(v.Versions, want) {
+		t.Errorf("unexpected versions %v, want %v", v.Versions, want)
+	}
+}
+
+func TestMavenHandler_PushSnapshot(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "build/libs/app-all.jar"))
+
+	repo := &fakeMavenRepository{files: map[string][]byte{}}
+	server := httptest.NewServer(repo)
+	defer server.Close()
+
+	published := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return published }
+
+	// Snapshots are published through the repository API, not the hc CLI
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("maven")
+
+	config := testConfig()
+	config.Account = "acct"
+	config.Registry = "maven-local"
+	config.PkgURL = server.URL
+	config.Source = "build/libs/app-all.jar"
+	config.Name = ""
+	config.Version = "1.0.0-SNAPSHOT"
+	config.GroupID = "io.harness"
+	config.ArtifactID = "app"
+
+	for build := 1; build <= 2; build++ {
+		result, err := handler.Push(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unique := "1.0.0-20240301.123000-1"
+		if build == 2 {
+			unique = "1.0.0-20240301.124500-2"
+		}
+		if result.Metadata["MAVEN_SNAPSHOT_VERSION"] != unique || result.Metadata["MAVEN_VERSION"] != "1.0.0-SNAPSHOT" {
+			t.Errorf("build %d: unexpected outputs %v", build, result.Metadata)
+		}
+		if len(result.Files) != 2 || result.Files[1].Filename != "app-"+unique+".jar" {
+			t.Errorf("build %d: unexpected files %+v", build, result.Files)
+		}
+		published = published.Add(15 * time.Minute)
+	}
+	if len(runner.args()) != 0 {
+		t.Errorf("unexpected hc commands %v", runner.args())
+	}
+
+	jar := repo.file(t, "1.0.0-SNAPSHOT/app-1.0.0-20240301.124500-2.jar")
+	sum := sha1.Sum(jar)
+	if got := string(repo.file(t, "1.0.0-SNAPSHOT/app-1.0.0-20240301.124500-2.jar.sha1")); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected jar SHA-1 %s", got)
+	}
+	repo.file(t, "1.0.0-SNAPSHOT/app-1.0.0-20240301.124500-2.jar.md5")
+	repo.file(t, "1.0.0-SNAPSHOT/app-1.0.0-20240301.123000-1.pom")
+
+	metadata, err := parseMavenMetadata(repo.file(t, "1.0.0-SNAPSHOT/maven-metadata.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := metadata.Versioning.Snapshot; s == nil || s.Timestamp != "20240301.124500" || s.BuildNumber != 2 {
+		t.Errorf("unexpected snapshot %+v", s)
+	}
+	if metadata.Version != "1.0.0-SNAPSHOT" || len(metadata.Versioning.SnapshotVersions) != 2 {
+		t.Errorf("unexpected version metadata %+v", metadata)
+	}
+	for _, entry := range metadata.Versioning.SnapshotVersions {
+		if entry.Value != "1.0.0-20240301.124500-2" || entry.Updated != "20240301124500" {
+			t.Errorf("unexpected snapshot version %+v", entry)
+		}
+	}
+	checksum := sha1.Sum(repo.file(t, "1.0.0-SNAPSHOT/maven-metadata.xml"))
+	if got := string(repo.file(t, "1.0.0-SNAPSHOT/maven-metadata.xml.sha1")); got != hex.EncodeToString(checksum[:]) {
+		t.Errorf("unexpected metadata SHA-1 %s", got)
+	}
+
+	artifact, err := parseMavenMetadata(repo.file(t, "maven-metadata.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Versioning.Latest != "1.0.0-SNAPSHOT" || artifact.Versioning.Release != "" ||
+		!reflect.DeepEqual(artifact.Versioning.Versions, []string{"1.0.0-SNAPSHOT"}) {
+		t.Errorf("unexpected artifact metadata %+v", artifact.Versioning)
+	}
+	repo.file(t, "maven-metadata.xml.md5")
+}
@@ -0,0 +1,355 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const parentPOM = `<project>
+  <groupId>io.harness</groupId>
+  <artifactId>parent</artifactId>
+  <version>2.1.0</version>
+  <packaging>pom</packaging>
+  <properties>
+    <revision>2.1.0</revision>
+    <lib.suffix>core</lib.suffix>
+  </properties>
+</project>`
+
+const childPOM = `<project>
+  <parent>
+    <groupId>io.harness</groupId>
+    <artifactId>parent</artifactId>
+    <version>2.1.0</version>
+  </parent>
+  <artifactId>lib-${lib.suffix}</artifactId>
+  <version>${revision}</version>
+  <description>Core library</description>
+</project>`
+
+// writeMavenProject writes a parent POM, a module POM and the given files
+// into the module, and returns the module directory
+func writeMavenProject(t *testing.T, files ...string) string {
+	t.Helper()
+	root := writeTree(t, append(files, "pom.xml", "lib/pom.xml")...)
+	for path, content := range map[string]string{"pom.xml": parentPOM, "lib/pom.xml": childPOM} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(path)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(root, "lib")
+}
+
+func TestLoadMavenProject(t *testing.T) {
+	module := writeMavenProject(t)
+
+	project, err := loadMavenProject(filepath.Join(module, "pom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mavenCoordinates{GroupID: "io.harness", ArtifactID: "lib-core", Version: "2.1.0", Packaging: "jar"}
+	if project.mavenCoordinates != want {
+		t.Errorf("unexpected coordinates %+v, want %+v", project.mavenCoordinates, want)
+	}
+	if project.Description != "Core library" {
+		t.Errorf("unexpected description %q", project.Description)
+	}
+}
+
+func TestLoadMavenProject_UnresolvedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pom.xml")
+	pom := `<project><groupId>io.harness</groupId><artifactId>app</artifactId><version>${revision}</version></project>`
+	if err := os.WriteFile(path, []byte(pom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := loadMavenProject(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMavenCoordinates(Config{}, &project.mavenCoordinates); err == nil ||
+		!strings.Contains(err.Error(), "unresolved property") {
+		t.Errorf("expected unresolved version error, got %v", err)
+	}
+	if err := checkMavenCoordinates(Config{Version: "3.0.0"}, &project.mavenCoordinates); err != nil {
+		t.Fatal(err)
+	}
+	if project.Version != "3.0.0" {
+		t.Errorf("version setting was not used, got %s", project.Version)
+	}
+}
+
+func TestCheckMavenCoordinates(t *testing.T) {
+	coordinates := mavenCoordinates{GroupID: "io.harness", ArtifactID: "app", Version: "1.0.0", Packaging: "jar"}
+	tests := []struct {
+		config Config
+		err    string
+	}{
+		{config: Config{}},
+		{config: Config{Name: "app", Version: "1.0.0"}},
+		{config: Config{Name: "io.harness:app"}},
+		{config: Config{Name: "other"}, err: "does not match the artifactId"},
+		{config: Config{Version: "1.0.1"}, err: "does not match the version"},
+	}
+	for _, test := range tests {
+		c := coordinates
+		err := checkMavenCoordinates(test.config, &c)
+		if (err == nil) != (test.err == "") || (err != nil && !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("checkMavenCoordinates(%+v) = %v, want %q", test.config, err, test.err)
+		}
+	}
+
+	invalid := coordinates
+	invalid.GroupID = "io/harness"
+	if err := invalid.validate(); err == nil {
+		t.Error("expected invalid groupId error")
+	}
+}
+
+func TestDiscoverMavenArtifacts(t *testing.T) {
+	module := writeMavenProject(t,
+		"lib/target/lib-core-2.1.0.jar",
+		"lib/target/lib-core-2.1.0-sources.jar",
+		"lib/target/lib-core-2.1.0-javadoc.jar",
+		"lib/target/lib-core-2.1.0-dist.tar.gz",
+		"lib/target/lib-core-2.1.0.jar.sha1",
+		"lib/target/lib-core-2.1.0.pom",
+		"lib/target/lib-core-2.1.0.1.jar",
+		"lib/target/original-lib-core-2.1.0.jar",
+		"lib/target/classes/App.class",
+	)
+
+	c := mavenCoordinates{GroupID: "io.harness", ArtifactID: "lib-core", Version: "2.1.0", Packaging: "jar"}
+	artifacts, err := discoverMavenArtifacts(filepath.Join(module, "target"), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, artifact := range artifacts {
+		got = append(got, artifact.Classifier+"|"+artifact.Extension+"|"+artifact.Filename(c))
+	}
+	want := []string{
+		"|jar|lib-core-2.1.0.jar",
+		"dist|tar.gz|lib-core-2.1.0-dist.tar.gz",
+		"javadoc|jar|lib-core-2.1.0-javadoc.jar",
+		"sources|jar|lib-core-2.1.0-sources.jar",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected artifacts\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestMavenHandler_Push(t *testing.T) {
+	captureLogs(t)
+	module := writeMavenProject(t,
+		"lib/target/lib-core-2.1.0.jar",
+		"lib/target/lib-core-2.1.0-sources.jar",
+	)
+	t.Chdir(module)
+
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("maven")
+
+	config := testConfig()
+	config.Source = "target"
+	config.Name = ""
+	config.Version = ""
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pushed []string
+	for _, args := range runner.args() {
+		// args: hc artifact --org o --project p push maven <registry> <file> --pom-file <pom>
+		pushed = append(pushed, args[9]+" "+args[10]+" "+args[11])
+	}
+	want := []string{
+		filepath.Join("target", "lib-core-2.1.0.jar") + " --pom-file pom.xml",
+		filepath.Join("target", "lib-core-2.1.0-sources.jar") + " --pom-file pom.xml",
+	}
+	if !reflect.DeepEqual(pushed, want) {
+		t.Errorf("unexpected pushes\n got: %q\nwant: %q", pushed, want)
+	}
+
+	outputs := map[string]string{
+		"MAVEN_GROUP_ID":    "io.harness",
+		"MAVEN_ARTIFACT_ID": "lib-core",
+		"MAVEN_VERSION":     "2.1.0",
+		"MAVEN_PACKAGING":   "jar",
+		"MAVEN_COORDINATES": "io.harness:lib-core:2.1.0",
+		"MAVEN_CLASSIFIERS": "sources",
+	}
+	if !reflect.DeepEqual(result.Metadata, outputs) {
+		t.Errorf("unexpected outputs %v", result.Metadata)
+	}
+	if len(result.Files) != 2 {
+		t.Errorf("expected 2 file results, got %d", len(result.Files))
+	}
+}
+
+func TestMavenHandler_PushErrors(t *testing.T) {
+	captureLogs(t)
+	module := writeMavenProject(t, "lib/target/lib-core-2.1.0-sources.jar", "lib/other/app.jar")
+	t.Chdir(module)
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{source: "target", err: "main artifact lib-core-2.1.0.jar of io.harness:lib-core:2.1.0 not found"},
+		{source: "missing", err: "failed to access source"},
+	}
+	for _, test := range tests {
+		factory, runner := newTestFactory(t)
+		handler, _ := factory.GetHandler("maven")
+
+		config := testConfig()
+		config.Source = test.source
+		config.Name = ""
+		config.Version = ""
+		config.PomFile = "pom.xml"
+
+		_, err := handler.Push(context.Background(), config)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("source %s: expected error %q, got %v", test.source, test.err, err)
+		}
+		if len(runner.args()) != 0 {
+			t.Errorf("source %s: expected no commands, got %v", test.source, runner.args())
+		}
+	}
+}
+
+func TestParseMavenDependencies(t *testing.T) {
+	dependencies, err := parseMavenDependencies([]string{"org.slf4j:slf4j-api:2.0.9", " junit:junit:4.13.2:test ", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mavenDependency{
+		{GroupID: "org.slf4j", ArtifactID: "slf4j-api", Version: "2.0.9"},
+		{GroupID: "junit", ArtifactID: "junit", Version: "4.13.2", Scope: "test"},
+	}
+	if !reflect.DeepEqual(dependencies, want) {
+		t.Errorf("unexpected dependencies %+v", dependencies)
+	}
+
+	for _, value := range []string{"junit:junit", "junit:junit:4.13.2:testing", "junit:junit:4.13.2:test:extra", "junit::1.0"} {
+		if _, err := parseMavenDependencies([]string{value}); err == nil {
+			t.Errorf("parseMavenDependencies(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestMavenHandler_PushGeneratedPOM(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "build/libs/app-all.jar"))
+
+	var pom []byte
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		// The generated POM only exists while the push runs
+		var err error
+		pom, err = os.ReadFile(cmd.Args[11])
+		return err
+	}
+	handler, _ := factory.GetHandler("maven")
+
+	config := testConfig()
+	config.Source = "build/libs/app-all.jar"
+	config.Name = ""
+	config.Version = "1.4.0"
+	config.Description = "Service <client>"
+	config.GroupID = "io.harness"
+	config.ArtifactID = "app"
+	config.Dependencies = []string{"org.slf4j:slf4j-api:2.0.9:runtime"}
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := runner.args()
+	if len(args) != 1 {
+		t.Fatalf("expected 1 command, got %v", args)
+	}
+	// args: hc artifact --org o --project p push maven <registry> <file> --pom-file <pom> ... --filename <name>
+	if got := args[0][len(args[0])-1]; got != "app-1.4.0.jar" {
+		t.Errorf("jar published as %s, want app-1.4.0.jar", got)
+	}
+	if filepath.Base(args[0][11]) != "app-1.4.0.pom" {
+		t.Errorf("unexpected POM file %s", args[0][11])
+	}
+	if _, err := os.Stat(args[0][11]); !os.IsNotExist(err) {
+		t.Errorf("generated POM was not removed: %v", err)
+	}
+
+	for _, want := range []string{
+		"<modelVersion>4.0.0</modelVersion>",
+		"<groupId>io.harness</groupId>",
+		"<artifactId>app</artifactId>",
+		"<version>1.4.0</version>",
+		"<packaging>jar</packaging>",
+		"<description>Service &lt;client&gt;</description>",
+		"<artifactId>slf4j-api</artifactId>",
+		"<scope>runtime</scope>",
+	} {
+		if !strings.Contains(string(pom), want) {
+			t.Errorf("generated POM does not contain %s:\n%s", want, pom)
+		}
+	}
+
+	// The generated POM must be readable like any other POM
+	path := filepath.Join(t.TempDir(), "pom.xml")
+	if err := os.WriteFile(path, pom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err := loadMavenProject(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.String() != "io.harness:app:1.4.0" || result.Metadata["MAVEN_COORDINATES"] != "io.harness:app:1.4.0" {
+		t.Errorf("unexpected coordinates %s, outputs %v", project, result.Metadata)
+	}
+}
+
+func TestMavenHandler_PushGeneratedPOMErrors(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "app.jar"))
+
+	tests := []struct {
+		config func(*Config)
+		err    string
+	}{
+		{config: func(c *Config) { c.GroupID = "" }, err: "group ID must be set"},
+		{config: func(c *Config) { c.Version = "" }, err: "version must be set"},
+		{config: func(c *Config) { c.Source = "" }, err: "source file path must be set"},
+		{config: func(c *Config) { c.Dependencies = []string{"broken"} }, err: "invalid dependency 'broken'"},
+	}
+	for _, test := range tests {
+		factory, runner := newTestFactory(t)
+		handler, _ := factory.GetHandler("maven")
+
+		config := testConfig()
+		config.Source = "app.jar"
+		config.Name = ""
+		config.GroupID = "io.harness"
+		config.ArtifactID = "app"
+		test.config(&config)
+
+		_, err := handler.Push(context.Background(), config)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected error %q, got %v", test.err, err)
+		}
+		if len(runner.args()) != 0 {
+			t.Errorf("expected no commands, got %v", runner.args())
+		}
+	}
+}
@@ -6,7 +6,12 @@ package packages
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,9 +22,9 @@ type NPMHandler struct {
 }
 
 // NewNPMHandler creates a new NPM package handler
-func NewNPMHandler() *NPMHandler {
+func NewNPMHandler(backend Backend) *NPMHandler {
 	return &NPMHandler{
-		BaseHandler: NewBaseHandler(NPM),
+		BaseHandler: NewBaseHandler(NPM, backend),
 	}
 }
 
@@ -44,44 +49,260 @@ func (h *NPMHandler) Validate(config Config) error {
 }
 
 // Push uploads NPM packages to the registry
-func (h *NPMHandler) Push(ctx context.Context, config Config) error {
+func (h *NPMHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing NPM push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	// Without a tag the registry points latest at the published version
+	if config.Tag != "" {
+		if err := validateDistTag(config.Tag); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := os.Stat(config.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access source '%s': %w", config.Source, err)
+	}
+
+	// A package directory is packed into a tarball like 'npm pack' does
+	tarball := config.Source
+	if info.IsDir() {
+		outDir, err := os.MkdirTemp("", "npm-pack-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(outDir)
+
+		if tarball, err = packNPMDirectory(config.Source, outDir); err != nil {
+			return nil, err
+		}
+	}
+
+	// The package coordinates always come from the tarball's package.json
+	manifest, err := readTarballManifest(tarball)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNPMCoordinates(config, manifest); err != nil {
+		return nil, err
+	}
+	logrus.Printf("Resolved NPM package: %s@%s", manifest.Name, manifest.Version)
+	if config.Tag != "" {
+		logrus.Printf("Publishing with dist-tag '%s'", config.Tag)
+	}
+
+	result, err := h.pushSingleFile(ctx, config, tarball, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Metadata = npmOutputs(manifest)
+	result.Metadata["NPM_DIST_TAG"] = defaultDistTag
+	if config.Tag != "" {
+		result.Metadata["NPM_DIST_TAG"] = config.Tag
+	}
+	return result, nil
 }
 
 // pushSingleFile handles pushing a single file for NPM packages
-func (h *NPMHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for NPM)
-	cmdArgs, err := buildPushCommand(NPM, config, "", filePath, artifactName, false)
+func (h *NPMHandler) pushSingleFile(ctx context.Context, config Config, filePath string, manifest *npmManifest) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: NPM,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        manifest.Name,
+		Version:     manifest.Version,
+	})
+}
+
+// npmOutputs returns the step outputs describing an NPM package
+func npmOutputs(manifest *npmManifest) map[string]string {
+	outputs := map[string]string{
+		"NPM_PACKAGE_NAME":    manifest.Name,
+		"NPM_PACKAGE_VERSION": manifest.Version,
+		"NPM_PACKAGE":         manifest.Name + "@" + manifest.Version,
+	}
+	if scope := manifest.Scope(); scope != "" {
+		outputs["NPM_PACKAGE_SCOPE"] = scope
+	}
+	return outputs
+}
+
+// Pull downloads the tarball of an NPM package version into the destination
+func (h *NPMHandler) Pull(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing NPM pull command")
+
+	if config.Registry == "" {
+		return nil, fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return nil, fmt.Errorf("package name must be set")
+	}
+	if config.Version == "" {
+		return nil, fmt.Errorf("package version must be set")
+	}
+	if config.Destination == "" {
+		return nil, fmt.Errorf("destination path must be set")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return nil, fmt.Errorf("account ID must be set")
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+	if err := validateNPMName(config.Name); err != nil {
+		return nil, err
+	}
+
+	// The tarball is named after the unscoped name: <name>/-/<base>-<version>.tgz
+	version := strings.TrimPrefix(config.Version, "v")
+	packagePath := npmTarballPath(config.Name, version)
+	filename := path.Base(packagePath)
+	if config.Filename != "" && config.Filename != filename {
+		logrus.Printf("⚠ Warning: filename '%s' is ignored, NPM tarballs are always named '%s'", config.Filename, filename)
+	}
+
+	result, err := h.backend.Pull(ctx, PullRequest{
+		PackageType: NPM,
+		Config:      config,
+		Name:        config.Name,
+		Version:     version,
+		Filename:    filename,
+		Path:        packagePath,
+		Destination: config.Destination,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return executeCommand(cmdArgs, fmt.Sprintf("push NPM artifact '%s' to registry '%s'", artifactName, config.Registry))
-}
+	if err := verifyPull(ctx, h.backend, config, result); err != nil {
+		return nil, err
+	}
 
-// Pull downloads NPM packages from the registry
-func (h *NPMHandler) Pull(ctx context.Context, config Config) error {
-	// TODO: Implement NPM pull logic
-	return fmt.Errorf("NPM pull is not yet implemented")
+	// Make sure the registry served the requested package
+	manifest, err := readTarballManifest(result.Path)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Name != config.Name || manifest.Version != version {
+		return nil, fmt.Errorf("downloaded tarball contains %s@%s, expected %s@%s", manifest.Name, manifest.Version, config.Name, version)
+	}
+
+	result.Metadata = npmOutputs(manifest)
+	return result, nil
 }
 
-// Get retrieves NPM package information
-func (h *NPMHandler) Get(ctx context.Context, config Config) error {
-	// TODO: Implement NPM get logic
-	return fmt.Errorf("NPM get is not yet implemented")
+// Get prints the versions, dist-tags and tarball metadata of an NPM package
+// as JSON, or of a single version when one is set
+func (h *NPMHandler) Get(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing NPM get command")
+
+	if config.Registry == "" {
+		return nil, fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return nil, fmt.Errorf("package name must be set")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return nil, fmt.Errorf("account ID must be set")
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+	if err := validateNPMName(config.Name); err != nil {
+		return nil, err
+	}
+
+	packument, err := getNPMPackument(ctx, h.backend, config, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := packument.summary(strings.TrimPrefix(config.Version, "v"))
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode package summary: %w", err)
+	}
+	printJSON(raw)
+
+	// Describe the requested version, or the one installed by default
+	version := summary.DistTags[defaultDistTag]
+	if config.Version != "" {
+		version = summary.Versions[0].Version
+	}
+	result := &Result{
+		PackageType: NPM,
+		Registry:    config.Registry,
+		Name:        config.Name,
+		Version:     version,
+		URL:         npmPackumentURL(config, config.Name),
+		Metadata: map[string]string{
+			"NPM_PACKAGE_NAME":  config.Name,
+			"NPM_VERSION_COUNT": strconv.Itoa(len(summary.Versions)),
+		},
+		Raw: raw,
+	}
+	if latest := summary.DistTags[defaultDistTag]; latest != "" {
+		result.Metadata["NPM_LATEST_VERSION"] = latest
+	}
+	for _, entry := range summary.Versions {
+		if entry.Version == version {
+			result.Filename = path.Base(entry.Tarball)
+			result.URL = entry.Tarball
+			result.SHA1 = entry.Shasum
+		}
+	}
+
+	logrus.Printf("Package %s has %d versions", config.Name, len(summary.Versions))
+	return result, nil
 }
 
-// Delete removes NPM packages from the registry
-func (h *NPMHandler) Delete(ctx context.Context, config Config) error {
-	// TODO: Implement NPM delete logic
-	return fmt.Errorf("NPM delete is not yet implemented")
+// Delete removes a single version of an NPM package, or the whole package
+// when no version is set
+func (h *NPMHandler) Delete(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing NPM delete command")
+
+	if config.Registry == "" {
+		return nil, fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return nil, fmt.Errorf("package name must be set")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return nil, fmt.Errorf("account ID must be set")
+	}
+	if err := validateNPMName(config.Name); err != nil {
+		return nil, err
+	}
+
+	version := strings.TrimPrefix(config.Version, "v")
+	if version == "" {
+		logrus.Printf("No version specified, deleting every version of %s", config.Name)
+	}
+
+	return h.backend.Delete(ctx, ArtifactRequest{
+		PackageType: NPM,
+		Config:      config,
+		Name:        config.Name,
+		Version:     version,
+	})
 }
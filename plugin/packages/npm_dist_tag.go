@@ -0,0 +1,205 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultDistTag is the tag npm installs when no version is requested
+const defaultDistTag = "latest"
+
+// DistTagHandler is implemented by handlers of package types with npm style
+// dist-tags, tags pointing at a version of a package
+type DistTagHandler interface {
+	// AddDistTag points a new tag at a version, it fails when the tag
+	// already points at another version
+	AddDistTag(ctx context.Context, config Config) (*Result, error)
+
+	// MoveDistTag points an existing tag at another version
+	MoveDistTag(ctx context.Context, config Config) (*Result, error)
+
+	// RemoveDistTag deletes a tag, the tagged version is kept
+	RemoveDistTag(ctx context.Context, config Config) (*Result, error)
+}
+
+// validateDistTag checks a dist-tag name. Like npm, tags that look like a
+// version are rejected since they could not be told apart on install.
+func validateDistTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("tag must be set")
+	}
+	if strings.TrimSpace(tag) != tag || strings.ContainsAny(tag, " /%") {
+		return fmt.Errorf("invalid tag '%s': must not contain spaces, '/' or '%%'", tag)
+	}
+	if semverPattern.MatchString(strings.TrimPrefix(tag, "v")) {
+		return fmt.Errorf("invalid tag '%s': must not be a version", tag)
+	}
+	return nil
+}
+
+// validateDistTagConfig checks the settings of a dist-tag command
+func (h *NPMHandler) validateDistTagConfig(config Config, needVersion bool) error {
+	if config.Registry == "" {
+		return fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return fmt.Errorf("package name must be set")
+	}
+	if needVersion && config.Version == "" {
+		return fmt.Errorf("package version must be set")
+	}
+	if config.Token == "" {
+		return fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return fmt.Errorf("account ID must be set")
+	}
+	if config.PkgURL == "" {
+		return fmt.Errorf("package URL must be set")
+	}
+	if err := validateNPMName(config.Name); err != nil {
+		return err
+	}
+	return validateDistTag(config.Tag)
+}
+
+// AddDistTag points a new dist-tag at a version of an NPM package
+func (h *NPMHandler) AddDistTag(ctx context.Context, config Config) (*Result, error) {
+	return h.setDistTag(ctx, config, false)
+}
+
+// MoveDistTag points an existing dist-tag at another version of an NPM package
+func (h *NPMHandler) MoveDistTag(ctx context.Context, config Config) (*Result, error) {
+	return h.setDistTag(ctx, config, true)
+}
+
+// setDistTag adds or moves a dist-tag, existing tells whether the tag must
+// already exist
+func (h *NPMHandler) setDistTag(ctx context.Context, config Config, existing bool) (*Result, error) {
+	if err := h.validateDistTagConfig(config, true); err != nil {
+		return nil, err
+	}
+	version := strings.TrimPrefix(config.Version, "v")
+
+	tags, err := npmDistTags(ctx, h.backend, config, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	previous, found := tags[config.Tag]
+	switch {
+	case existing && !found:
+		return nil, fmt.Errorf("tag '%s' does not exist on package '%s', use the dist-tag-add command to create it", config.Tag, config.Name)
+	case !existing && found && previous != version:
+		return nil, fmt.Errorf("tag '%s' already points at %s@%s, use the dist-tag-move command to move it", config.Tag, config.Name, previous)
+	}
+
+	result := &Result{
+		PackageType: NPM,
+		Registry:    config.Registry,
+		Name:        config.Name,
+		Version:     version,
+		Metadata:    map[string]string{"NPM_DIST_TAG": config.Tag},
+	}
+	if previous == version {
+		loggerFrom(ctx).Printf("Tag '%s' already points at %s@%s", config.Tag, config.Name, version)
+		return result, nil
+	}
+
+	err = h.backend.SetDistTag(ctx, DistTagRequest{
+		Config:  config,
+		Name:    config.Name,
+		Tag:     config.Tag,
+		Version: version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		result.Metadata["NPM_DIST_TAG_PREVIOUS_VERSION"] = previous
+		loggerFrom(ctx).Printf("Moved tag '%s' of %s from %s to %s", config.Tag, config.Name, previous, version)
+	} else {
+		loggerFrom(ctx).Printf("Added tag '%s' to %s@%s", config.Tag, config.Name, version)
+	}
+	return result, nil
+}
+
+// RemoveDistTag deletes a dist-tag of an NPM package. The latest tag cannot
+// be removed, npm always needs a default version to install.
+func (h *NPMHandler) RemoveDistTag(ctx context.Context, config Config) (*Result, error) {
+	if err := h.validateDistTagConfig(config, false); err != nil {
+		return nil, err
+	}
+	if config.Tag == defaultDistTag {
+		return nil, fmt.Errorf("tag '%s' cannot be removed, use the dist-tag-move command to point it at another version", defaultDistTag)
+	}
+
+	tags, err := npmDistTags(ctx, h.backend, config, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	previous, found := tags[config.Tag]
+	if !found {
+		return nil, fmt.Errorf("tag '%s' does not exist on package '%s'", config.Tag, config.Name)
+	}
+
+	err = h.backend.RemoveDistTag(ctx, DistTagRequest{
+		Config: config,
+		Name:   config.Name,
+		Tag:    config.Tag,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loggerFrom(ctx).Printf("Removed tag '%s' from %s@%s", config.Tag, config.Name, previous)
+	return &Result{
+		PackageType: NPM,
+		Registry:    config.Registry,
+		Name:        config.Name,
+		Version:     previous,
+		Metadata:    map[string]string{"NPM_DIST_TAG": config.Tag},
+	}, nil
+}
+
+// npmPackagePath returns the path of an npm registry endpoint of a package
+// below the npm root. The slash of a scoped name is escaped like the npm
+// client does.
+func npmPackagePath(name string, elems ...string) string {
+	return "-/package/" + url.PathEscape(name) + "/" + escapePath(strings.Join(elems, "/"))
+}
+
+// npmDistTagURL returns the URL of a dist-tag of a package
+func (c *Client) npmDistTagURL(name, tag string) string {
+	return c.packageURL("npm") + "/" + npmPackagePath(name, "dist-tags", tag)
+}
+
+// npmDistTags returns the dist-tags of a package, keyed by tag name
+func npmDistTags(ctx context.Context, backend Backend, config Config, name string) (map[string]string, error) {
+	data, err := backend.Read(ctx, EndpointRequest{
+		PackageType: NPM,
+		Config:      config,
+		Path:        npmPackagePath(name, "dist-tags"),
+	})
+	var status *StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("package '%s' not found in registry '%s'", name, config.Registry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dist-tags of %s from registry '%s': %w", name, config.Registry, err)
+	}
+	tags := map[string]string{}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("invalid dist-tags of %s: %w", name, err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newDistTagServer serves the dist-tags of a single package like an npm
+// registry and records every modifying request
+func newDistTagServer(t *testing.T, tags map[string]string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+	prefix := "/pkg/acct/npm-local/npm/-/package/@scope%2Fapp/dist-tags"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.EscapedPath()
+		if !strings.HasPrefix(path, prefix) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		tag := strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(tags)
+		case http.MethodPut:
+			var version string
+			if err := json.NewDecoder(r.Body).Decode(&version); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			tags[tag] = version
+			requests = append(requests, "PUT "+tag+" "+version)
+		case http.MethodDelete:
+			delete(tags, tag)
+			requests = append(requests, "DELETE "+tag)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func distTagConfig(server *httptest.Server, tag, version string) Config {
+	return Config{
+		Token:    "pat.test",
+		Account:  "acct",
+		Registry: "npm-local",
+		PkgURL:   server.URL,
+		Name:     "@scope/app",
+		Version:  version,
+		Tag:      tag,
+	}
+}
+
+func TestNPMHandler_DistTags(t *testing.T) {
+	captureLogs(t)
+	tags := map[string]string{"latest": "1.0.0"}
+	server, requests := newDistTagServer(t, tags)
+	handler := NewNPMHandler(NewHTTPBackend(server.Client()))
+	ctx := context.Background()
+
+	result, err := handler.AddDistTag(ctx, distTagConfig(server, "next", "v2.0.0-rc.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "2.0.0-rc.1" || result.Metadata["NPM_DIST_TAG"] != "next" {
+		t.Errorf("unexpected add result %+v", result)
+	}
+
+	// Adding a tag that points elsewhere must be an explicit move
+	if _, err := handler.AddDistTag(ctx, distTagConfig(server, "latest", "2.0.0")); err == nil ||
+		!strings.Contains(err.Error(), "use the dist-tag-move command") {
+		t.Errorf("expected add of an existing tag to fail, got %v", err)
+	}
+	if _, err := handler.MoveDistTag(ctx, distTagConfig(server, "beta", "2.0.0")); err == nil ||
+		!strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected move of a missing tag to fail, got %v", err)
+	}
+
+	result, err = handler.MoveDistTag(ctx, distTagConfig(server, "latest", "2.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Metadata["NPM_DIST_TAG_PREVIOUS_VERSION"] != "1.0.0" {
+		t.Errorf("unexpected move result %+v", result)
+	}
+
+	if _, err := handler.RemoveDistTag(ctx, distTagConfig(server, "latest", "")); err == nil {
+		t.Error("expected removal of latest to fail")
+	}
+	result, err = handler.RemoveDistTag(ctx, distTagConfig(server, "next", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "2.0.0-rc.1" {
+		t.Errorf("unexpected remove result %+v", result)
+	}
+
+	want := []string{"PUT next 2.0.0-rc.1", "PUT latest 2.0.0", "DELETE next"}
+	if strings.Join(*requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected requests\n got: %q\nwant: %q", *requests, want)
+	}
+	if len(tags) != 1 || tags["latest"] != "2.0.0" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestValidateDistTag(t *testing.T) {
+	for _, tag := range []string{"latest", "next", "beta-2", "release.candidate"} {
+		if err := validateDistTag(tag); err != nil {
+			t.Errorf("validateDistTag(%q) = %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "1.2.3", "v1.0.0-beta", "feature/x", "has space"} {
+		if err := validateDistTag(tag); err == nil {
+			t.Errorf("validateDistTag(%q) succeeded, want error", tag)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// maxManifestSize limits the size of a package.json read from a tarball
+const maxManifestSize = 10 << 20
+
+var (
+	// npmNamePattern matches a URL safe package name or scope that does not
+	// start with '.' or '_', as required by npm for new packages
+	npmNamePattern = regexp.MustCompile(`^[a-z0-9-][a-z0-9._-]*$`)
+
+	// semverPattern matches a Semantic Versioning 2.0.0 version
+	semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+)
+
+// npmManifest holds the fields of package.json used by the plugin
+type npmManifest struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// Scope returns the scope of a scoped package name without the '@', or an
+// empty string for unscoped packages
+func (m *npmManifest) Scope() string {
+	if scope, _, ok := strings.Cut(m.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+		return strings.TrimPrefix(scope, "@")
+	}
+	return ""
+}
+
+// readTarballManifest reads and validates package/package.json from an npm
+// tarball
+func readTarballManifest(tarball string) (*npmManifest, error) {
+	data, err := readTarballPackageJSON(tarball)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := parseNPMManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package.json in npm tarball '%s': %w", tarball, err)
+	}
+	return manifest, nil
+}
+
+// readTarballPackageJSON returns the content of package/package.json in an
+// npm tarball. The top level directory is usually 'package' but npm accepts
+// any name.
+func readTarballPackageJSON(tarball string) ([]byte, error) {
+	file, err := os.Open(tarball)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open npm tarball '%s': %w", tarball, err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("npm tarball '%s' is not gzip compressed: %w", tarball, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("npm tarball '%s' does not contain package/package.json", tarball)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read npm tarball '%s': %w", tarball, err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		dir, base := path.Split(name)
+		if header.Typeflag != tar.TypeReg || base != "package.json" || strings.Count(dir, "/") != 1 {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxManifestSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from npm tarball '%s': %w", name, tarball, err)
+		}
+		if len(data) > maxManifestSize {
+			return nil, fmt.Errorf("%s in npm tarball '%s' is too large", name, tarball)
+		}
+		return data, nil
+	}
+}
+
+// parseNPMManifest decodes and validates package.json content
+func parseNPMManifest(data []byte) (*npmManifest, error) {
+	var manifest npmManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("package name must be set")
+	}
+	if manifest.Version == "" {
+		return nil, fmt.Errorf("package version must be set")
+	}
+	if err := validateNPMName(manifest.Name); err != nil {
+		return nil, err
+	}
+	if !semverPattern.MatchString(manifest.Version) {
+		return nil, fmt.Errorf("invalid package version '%s': must be a valid semantic version", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// validateNPMName applies the npm naming rules for new packages
+func validateNPMName(name string) error {
+	if len(name) > 214 {
+		return fmt.Errorf("invalid package name '%s': must not be longer than 214 characters", name)
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("invalid package name '%s': must not contain leading or trailing spaces", name)
+	}
+	if name == "node_modules" || name == "favicon.ico" {
+		return fmt.Errorf("invalid package name '%s': name is reserved", name)
+	}
+	if strings.ToLower(name) != name {
+		return fmt.Errorf("invalid package name '%s': must not contain uppercase letters", name)
+	}
+
+	parts := []string{name}
+	if strings.HasPrefix(name, "@") {
+		scope, pkg, ok := strings.Cut(strings.TrimPrefix(name, "@"), "/")
+		if !ok || scope == "" || pkg == "" {
+			return fmt.Errorf("invalid package name '%s': scoped names must have the form @scope/name", name)
+		}
+		parts = []string{scope, pkg}
+	}
+	for _, part := range parts {
+		if !npmNamePattern.MatchString(part) {
+			return fmt.Errorf("invalid package name '%s': must not start with '.' or '_' and may only contain lowercase letters, digits, '-', '.' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// checkNPMCoordinates fails when the configured name or version disagree
+// with the manifest. A leading 'v' of the configured version is ignored.
+func checkNPMCoordinates(config Config, manifest *npmManifest) error {
+	if config.Name != "" && config.Name != manifest.Name {
+		return fmt.Errorf("package name '%s' does not match the name '%s' in package.json", config.Name, manifest.Name)
+	}
+	if config.Version != "" && strings.TrimPrefix(config.Version, "v") != manifest.Version {
+		return fmt.Errorf("package version '%s' does not match the version '%s' in package.json", config.Version, manifest.Version)
+	}
+	return nil
+}
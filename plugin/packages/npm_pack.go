@@ -0,0 +1,349 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// npmPackTime is the modification time npm uses for every tarball entry,
+// so that packing the same files always produces the same tarball
+var npmPackTime = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+var (
+	// npmAlwaysIgnored lists files and directories npm never packs
+	npmAlwaysIgnored = []string{
+		".git", "CVS", ".svn", ".hg", ".lock-wscript", ".wafpickle-*", ".*.swp",
+		".DS_Store", "._*", "npm-debug.log", ".npmrc", "config.gypi", "*.orig",
+		".npmignore", ".gitignore", "node_modules",
+	}
+
+	// npmRootIgnored lists files npm never packs from the package root
+	npmRootIgnored = []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml"}
+
+	// npmAlwaysIncluded lists root files npm packs regardless of the files
+	// field and ignore files, matched case insensitively
+	npmAlwaysIncluded = []string{"package.json", "readme*", "license*", "licence*"}
+)
+
+// npmPackageJSON holds the package.json fields that control packing
+type npmPackageJSON struct {
+	npmManifest
+	Main  string   `json:"main"`
+	Files []string `json:"files"`
+}
+
+// npmIgnoreRule is a single pattern of an .npmignore or .gitignore file
+type npmIgnoreRule struct {
+	// base is the directory of the ignore file, relative to the package root
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// match reports whether the rule matches the slash separated path relative
+// to the package root
+func (r npmIgnoreRule) match(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = strings.TrimPrefix(relPath, r.base+"/")
+	}
+	if r.anchored {
+		return matchSegments(strings.Split(r.pattern, "/"), strings.Split(relPath, "/"))
+	}
+	return matchGlob(r.pattern, relPath)
+}
+
+// parseNPMIgnore parses ignore file content using the gitignore syntax
+func parseNPMIgnore(base string, r io.Reader) ([]npmIgnoreRule, error) {
+	var rules []npmIgnoreRule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := npmIgnoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// A pattern containing a slash is relative to the ignore file
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// loadNPMIgnore reads the .npmignore file of a directory, falling back to
+// its .gitignore file like npm does
+func loadNPMIgnore(root, relDir string) ([]npmIgnoreRule, error) {
+	for _, name := range []string{".npmignore", ".gitignore"} {
+		file, err := os.Open(filepath.Join(root, filepath.FromSlash(relDir), name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return parseNPMIgnore(relDir, file)
+	}
+	return nil, nil
+}
+
+// npmPackList returns the sorted, slash separated paths of the files npm
+// would pack from the package directory. Without a files field every file
+// not excluded by the ignore files is packed. With a files field only the
+// listed files and directories are packed, ignore files below the package
+// root still apply to the listed directories.
+func npmPackList(root string, pkg *npmPackageJSON) ([]string, error) {
+	ignores := map[string][]npmIgnoreRule{}
+
+	ignored := func(relPath string, isDir bool) bool {
+		// Ignore files of the parent directories apply, the last match wins
+		result := false
+		dirs := strings.Split(relPath, "/")
+		for i := 0; i < len(dirs); i++ {
+			for _, rule := range ignores[strings.Join(dirs[:i], "/")] {
+				if rule.match(relPath, isDir) {
+					result = !rule.negate
+				}
+			}
+		}
+		return result
+	}
+
+	listed := func(relPath string, isDir bool) bool {
+		matched := false
+		for _, entry := range pkg.Files {
+			negate := strings.HasPrefix(entry, "!")
+			entry = strings.Trim(strings.TrimPrefix(strings.TrimPrefix(entry, "!"), "./"), "/")
+			if entry == "" {
+				continue
+			}
+			segments := strings.Split(entry, "/")
+			name := strings.Split(relPath, "/")
+			match := matchSegments(segments, name) ||
+				// Files below a listed directory
+				matchSegments(append(segments, "**"), name) ||
+				// Directories leading to a listed path must be walked
+				(isDir && !negate && len(name) < len(segments) && matchSegments(segments[:len(name)], name))
+			if match {
+				matched = !negate
+			}
+		}
+		return matched
+	}
+
+	main := strings.TrimPrefix(path.Clean(pkg.Main), "./")
+	alwaysIncluded := func(relPath string) bool {
+		if relPath == main {
+			return true
+		}
+		if strings.Contains(relPath, "/") {
+			return false
+		}
+		for _, pattern := range npmAlwaysIncluded {
+			if ok, _ := path.Match(pattern, strings.ToLower(relPath)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if relPath == "." {
+			// The root ignore file does not apply when the files field is set
+			if pkg.Files == nil {
+				rules, err := loadNPMIgnore(root, "")
+				ignores[""] = rules
+				return err
+			}
+			return nil
+		}
+
+		isRoot := !strings.Contains(relPath, "/")
+		if matchAnyGlob(npmAlwaysIgnored, relPath) || (isRoot && matchAnyGlob(npmRootIgnored, relPath)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			// Directories holding the main file are always walked
+			if !strings.HasPrefix(main, relPath+"/") &&
+				(ignored(relPath, true) || (pkg.Files != nil && !listed(relPath, true))) {
+				return filepath.SkipDir
+			}
+			rules, err := loadNPMIgnore(root, relPath)
+			ignores[relPath] = rules
+			return err
+		}
+
+		// Symbolic links and other special files are never packed
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if alwaysIncluded(relPath) {
+			files = append(files, relPath)
+			return nil
+		}
+		if pkg.Files != nil && !listed(relPath, false) {
+			return nil
+		}
+		if ignored(relPath, false) {
+			return nil
+		}
+		files = append(files, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list package files in '%s': %w", root, err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// npmTarballName returns the file name npm pack uses for a package, scoped
+// packages are named <scope>-<name>-<version>.tgz
+func npmTarballName(manifest *npmManifest) string {
+	name := strings.ReplaceAll(strings.TrimPrefix(manifest.Name, "@"), "/", "-")
+	return fmt.Sprintf("%s-%s.tgz", name, manifest.Version)
+}
+
+// packNPMDirectory builds an npm compatible tarball from the package
+// directory into outDir and returns its path. Every file is stored below
+// package/ with a fixed modification time.
+func packNPMDirectory(dir, outDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read package.json in '%s': %w", dir, err)
+	}
+	if _, err := parseNPMManifest(data); err != nil {
+		return "", fmt.Errorf("invalid package.json in '%s': %w", dir, err)
+	}
+	var pkg npmPackageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", fmt.Errorf("invalid package.json in '%s': %w", dir, err)
+	}
+
+	files, err := npmPackList(dir, &pkg)
+	if err != nil {
+		return "", err
+	}
+
+	tarball := filepath.Join(outDir, npmTarballName(&pkg.npmManifest))
+	out, err := os.Create(tarball)
+	if err != nil {
+		return "", fmt.Errorf("failed to create npm tarball: %w", err)
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, relPath := range files {
+		logrus.Debugf("Packing %s", relPath)
+		if err := addTarFile(tw, filepath.Join(dir, filepath.FromSlash(relPath)), "package/"+relPath, npmPackTime); err != nil {
+			return "", fmt.Errorf("failed to pack '%s': %w", relPath, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to write npm tarball: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write npm tarball: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write npm tarball: %w", err)
+	}
+
+	logrus.Printf("Packed %d files from '%s' into %s", len(files), dir, filepath.Base(tarball))
+	return tarball, nil
+}
+
+// addTarFile writes a regular file into the tarball. Executable files keep
+// their executable bit, ownership is normalized and every entry gets the
+// given modification time.
+func addTarFile(tw *tar.Writer, file, name string, modTime time.Time) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	mode := int64(0644)
+	if info.Mode()&0111 != 0 {
+		mode = 0755
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     mode,
+		Size:     info.Size(),
+		ModTime:  modTime,
+		Format:   tar.FormatUSTAR,
+	}
+	if len(name) > 100 {
+		// Long paths need the PAX format
+		header.Format = tar.FormatPAX
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writePackage creates a package directory with the given files, every
+// file holding its own path unless content is provided
+func writePackage(t *testing.T, content map[string]string, files ...string) string {
+	t.Helper()
+	for name := range content {
+		files = append(files, name)
+	}
+	root := writeTree(t, files...)
+	for name, data := range content {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// tarEntries lists the entries of a gzipped tarball
+func tarEntries(t *testing.T, tarball string) []*tar.Header {
+	t.Helper()
+	file, err := os.Open(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var headers []*tar.Header
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return headers
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, header)
+	}
+}
+
+func TestNPMPackList(t *testing.T) {
+	common := []string{
+		"README.md", "LICENSE", "index.js", "lib/util.js", "lib/util.test.js", "lib/fixtures/data.json",
+		"docs/guide.md", "node_modules/dep/index.js", ".git/config", "package-lock.json", ".DS_Store",
+		"src/main.ts", ".env",
+	}
+	tests := []struct {
+		name     string
+		manifest string
+		extra    map[string]string
+		want     []string
+	}{
+		{
+			name:     "everything",
+			manifest: `{"name": "app", "version": "1.0.0"}`,
+			want: []string{".env", "LICENSE", "README.md", "docs/guide.md", "index.js", "lib/fixtures/data.json",
+				"lib/util.js", "lib/util.test.js", "package.json", "src/main.ts"},
+		},
+		{
+			name:     "npmignore",
+			manifest: `{"name": "app", "version": "1.0.0"}`,
+			extra: map[string]string{
+				".npmignore":     "# sources\nsrc/\n*.test.js\ndocs\n.env\n",
+				".gitignore":     "index.js\n",
+				"lib/.npmignore": "fixtures/\n",
+			},
+			want: []string{"LICENSE", "README.md", "index.js", "lib/util.js", "package.json"},
+		},
+		{
+			name:     "gitignore fallback and negation",
+			manifest: `{"name": "app", "version": "1.0.0"}`,
+			extra:    map[string]string{".gitignore": "*.md\n!README.md\n/src\n.env\nlib/fixtures\n"},
+			want:     []string{"LICENSE", "README.md", "index.js", "lib/util.js", "lib/util.test.js", "package.json"},
+		},
+		{
+			name:     "files field",
+			manifest: `{"name": "app", "version": "1.0.0", "main": "index.js", "files": ["lib", "!lib/*.test.js"]}`,
+			extra: map[string]string{
+				".npmignore":     "lib/\n",
+				"lib/.npmignore": "fixtures\n",
+			},
+			want: []string{"LICENSE", "README.md", "index.js", "lib/util.js", "package.json"},
+		},
+		{
+			name:     "files field with globs",
+			manifest: `{"name": "app", "version": "1.0.0", "files": ["lib/**/*.json", "docs/guide.md"]}`,
+			want:     []string{"LICENSE", "README.md", "docs/guide.md", "lib/fixtures/data.json", "package.json"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := map[string]string{"package.json": test.manifest}
+			for name, data := range test.extra {
+				content[name] = data
+			}
+			root := writePackage(t, content, common...)
+
+			var pkg npmPackageJSON
+			if err := json.Unmarshal([]byte(test.manifest), &pkg); err != nil {
+				t.Fatal(err)
+			}
+			got, err := npmPackList(root, &pkg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("unexpected files\n got: %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPackNPMDirectory(t *testing.T) {
+	captureLogs(t)
+	root := writePackage(t, map[string]string{
+		"package.json": `{"name": "@scope/app", "version": "1.2.3"}`,
+	}, "index.js", "bin/cli.js")
+	if err := os.Chmod(filepath.Join(root, "bin", "cli.js"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := packNPMDirectory(root, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(first) != "scope-app-1.2.3.tgz" {
+		t.Errorf("unexpected tarball name %s", filepath.Base(first))
+	}
+
+	var names []string
+	for _, header := range tarEntries(t, first) {
+		names = append(names, header.Name)
+		if !header.ModTime.Equal(npmPackTime) {
+			t.Errorf("%s: unexpected modification time %s", header.Name, header.ModTime)
+		}
+		wantMode := int64(0644)
+		if header.Name == "package/bin/cli.js" {
+			wantMode = 0755
+		}
+		if header.Mode != wantMode {
+			t.Errorf("%s: unexpected mode %o", header.Name, header.Mode)
+		}
+	}
+	want := []string{"package/bin/cli.js", "package/index.js", "package/package.json"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected entries\n got: %q\nwant: %q", names, want)
+	}
+
+	// Packing the same files again produces the same tarball
+	second, err := packNPMDirectory(root, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := fileChecksums(first)
+	b, _ := fileChecksums(second)
+	if a.SHA256 != b.SHA256 {
+		t.Error("packing is not reproducible")
+	}
+}
+
+func TestNPMHandler_PushDirectory(t *testing.T) {
+	captureLogs(t)
+	root := writePackage(t, map[string]string{
+		"package.json": `{"name": "app", "version": "3.0.0"}`,
+	}, "index.js")
+
+	var pushed []*tar.Header
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		// args: hc artifact --org o --project p push npm <registry> <file>
+		pushed = tarEntries(t, cmd.Args[9])
+		return nil
+	}
+
+	config := testConfig()
+	config.Source = root
+	config.Name = ""
+	config.Version = ""
+
+	handler, _ := factory.GetHandler("npm")
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != "app" || result.Version != "3.0.0" || result.Filename != "app-3.0.0.tgz" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(pushed) != 2 {
+		t.Errorf("expected 2 packed files, got %d", len(pushed))
+	}
+}
+
+func TestPackNPMDirectory_InvalidManifest(t *testing.T) {
+	root := writePackage(t, map[string]string{"package.json": `{"name": "app"}`})
+	if _, err := packNPMDirectory(root, t.TempDir()); err == nil {
+		t.Error("expected invalid package.json error")
+	}
+}
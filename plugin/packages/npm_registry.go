@@ -0,0 +1,187 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// npmPackument is the package document an npm registry serves for a
+// package, listing every published version
+type npmPackument struct {
+	Name     string                       `json:"name"`
+	DistTags map[string]string            `json:"dist-tags"`
+	Versions map[string]npmPackumentEntry `json:"versions"`
+	Time     map[string]string            `json:"time"`
+}
+
+// npmPackumentEntry holds the fields of a published version used by the plugin
+type npmPackumentEntry struct {
+	Version string  `json:"version"`
+	Dist    npmDist `json:"dist"`
+}
+
+// npmDist describes the tarball of a published version
+type npmDist struct {
+	Tarball      string `json:"tarball,omitempty"`
+	Shasum       string `json:"shasum,omitempty"`
+	Integrity    string `json:"integrity,omitempty"`
+	FileCount    int    `json:"fileCount,omitempty"`
+	UnpackedSize int64  `json:"unpackedSize,omitempty"`
+}
+
+// npmVersionSummary describes a published version in the get output
+type npmVersionSummary struct {
+	Version   string `json:"version"`
+	Published string `json:"published,omitempty"`
+	npmDist
+}
+
+// npmPackageSummary is the JSON document printed by the NPM get command
+type npmPackageSummary struct {
+	Name     string              `json:"name"`
+	DistTags map[string]string   `json:"dist-tags"`
+	Versions []npmVersionSummary `json:"versions"`
+}
+
+// npmTarballPath returns the registry path of the tarball of a version,
+// e.g. @scope/app/-/app-1.0.0.tgz. The tarball name never holds the scope.
+func npmTarballPath(name, version string) string {
+	base := name[strings.LastIndex(name, "/")+1:]
+	return fmt.Sprintf("%s/-/%s-%s.tgz", name, base, version)
+}
+
+// npmPackumentURL returns the URL of the package document of a package
+func npmPackumentURL(config Config, name string) string {
+	return endpointURL(config, NPM, url.PathEscape(name))
+}
+
+// getNPMPackument fetches the package document of a package
+func getNPMPackument(ctx context.Context, backend Backend, config Config, name string) (*npmPackument, error) {
+	data, err := backend.Read(ctx, EndpointRequest{
+		PackageType: NPM,
+		Config:      config,
+		Path:        url.PathEscape(name),
+	})
+	var status *StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("package '%s' not found in registry '%s'", name, config.Registry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get package '%s' from registry '%s': %w", name, config.Registry, err)
+	}
+	var packument npmPackument
+	if err := json.Unmarshal(data, &packument); err != nil {
+		return nil, fmt.Errorf("invalid package document of %s: %w", name, err)
+	}
+	return &packument, nil
+}
+
+// summary returns the versions of the package, oldest first, or only the
+// given version when one is set
+func (p *npmPackument) summary(version string) (*npmPackageSummary, error) {
+	summary := &npmPackageSummary{
+		Name:     p.Name,
+		DistTags: p.DistTags,
+	}
+	if summary.DistTags == nil {
+		summary.DistTags = map[string]string{}
+	}
+	for key, entry := range p.Versions {
+		if version != "" && key != version {
+			continue
+		}
+		summary.Versions = append(summary.Versions, npmVersionSummary{
+			Version:   key,
+			Published: p.Time[key],
+			npmDist:   entry.Dist,
+		})
+	}
+	if version != "" && len(summary.Versions) == 0 {
+		return nil, fmt.Errorf("version '%s' of package '%s' not found", version, p.Name)
+	}
+	sort.Slice(summary.Versions, func(i, j int) bool {
+		return compareSemver(summary.Versions[i].Version, summary.Versions[j].Version) < 0
+	})
+	return summary, nil
+}
+
+// compareSemver compares two semantic versions following the precedence
+// rules of Semantic Versioning 2.0.0, build metadata is ignored
+func compareSemver(a, b string) int {
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	coreA, preA, _ := strings.Cut(a, "-")
+	coreB, preB, _ := strings.Cut(b, "-")
+
+	partsA := strings.Split(coreA, ".")
+	partsB := strings.Split(coreB, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if c := compareIdentifier(partsA[i], partsB[i]); c != 0 {
+			return c
+		}
+	}
+	if len(partsA) != len(partsB) {
+		return compareInt(len(partsA), len(partsB))
+	}
+
+	// A version without pre-release has the higher precedence
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	idsA := strings.Split(preA, ".")
+	idsB := strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		if c := compareIdentifier(idsA[i], idsB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(idsA), len(idsB))
+}
+
+// compareIdentifier compares numeric identifiers numerically and others in
+// ASCII order, numeric identifiers have the lower precedence
+func compareIdentifier(a, b string) int {
+	numA, errA := strconv.ParseUint(a, 10, 64)
+	numB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		switch {
+		case numA < numB:
+			return -1
+		case numA > numB:
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNPMTarballPath(t *testing.T) {
+	tests := map[string]string{
+		"app":        "app/-/app-1.0.0.tgz",
+		"@scope/app": "@scope/app/-/app-1.0.0.tgz",
+	}
+	for name, want := range tests {
+		if got := npmTarballPath(name, "1.0.0"); got != want {
+			t.Errorf("npmTarballPath(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	// Ordered by precedence, from the Semantic Versioning specification
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "1.10.0", "2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := compareInt(i, j)
+			if got := compareSemver(ordered[i], ordered[j]); got != want {
+				t.Errorf("compareSemver(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+	if compareSemver("1.0.0+build.1", "1.0.0+build.2") != 0 {
+		t.Error("build metadata must be ignored")
+	}
+}
+
+func TestCLIBackend_NPMCommands(t *testing.T) {
+	captureLogs(t)
+	hc := getHarnessBin()
+	destination := t.TempDir()
+
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		// Fake the download of the requested tarball
+		if cmd.Args[2] == "pull" {
+			writeNPMTarball(t, filepath.Join(destination, "app-1.0.0.tgz"), `{"name": "@scope/app", "version": "1.0.0"}`)
+		}
+		return nil
+	}
+	handler, _ := factory.GetHandler("npm")
+
+	config := testConfig()
+	config.Name = "@scope/app"
+	config.Version = "v1.0.0"
+	config.Destination = destination
+
+	result, err := handler.Pull(context.Background(), config)
+	if err != nil {
+		t.Fatalf("pull failed: %v", err)
+	}
+	if result.Path != filepath.Join(destination, "app-1.0.0.tgz") || result.Metadata["NPM_PACKAGE"] != "@scope/app@1.0.0" {
+		t.Errorf("unexpected pull result %+v", result)
+	}
+	if _, err := handler.Delete(context.Background(), config); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	want := [][]string{
+		{hc, "artifact", "pull", "NPM", "test-registry", "@scope/app/-/app-1.0.0.tgz", destination,
+			"--token", "test-token", "--account", "test-account", "--pkg-url", "https://pkg.harness.io",
+			"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+			"--format", "json"},
+		{hc, "artifact", "delete", "@scope/app",
+			"--registry", "test-registry", "--token", "test-token", "--account", "test-account",
+			"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+			"--version", "1.0.0", "--format", "json"},
+	}
+	if got := runner.args(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected commands\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestNPMHandler_PullMismatchingTarball(t *testing.T) {
+	captureLogs(t)
+	destination := t.TempDir()
+
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		writeNPMTarball(t, filepath.Join(destination, "app-1.0.0.tgz"), `{"name": "other", "version": "1.0.0"}`)
+		return nil
+	}
+	handler, _ := factory.GetHandler("npm")
+
+	config := testConfig()
+	config.Name = "app"
+	config.Version = "1.0.0"
+	config.Destination = destination
+
+	_, err := handler.Pull(context.Background(), config)
+	if err == nil || !strings.Contains(err.Error(), "contains other@1.0.0") {
+		t.Errorf("expected mismatching tarball error, got %v", err)
+	}
+}
+
+func TestNPMHandler_Get(t *testing.T) {
+	captureLogs(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/pkg/acct/npm-local/npm/@scope%2Fapp" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+			"name": "@scope/app",
+			"dist-tags": {"latest": "1.10.0", "next": "2.0.0-rc.1"},
+			"versions": {
+				"2.0.0-rc.1": {"version": "2.0.0-rc.1", "dist": {"tarball": "http://registry/app-2.0.0-rc.1.tgz", "shasum": "c"}},
+				"1.10.0": {"version": "1.10.0", "dist": {"tarball": "http://registry/app-1.10.0.tgz", "shasum": "b", "fileCount": 3}},
+				"1.9.0": {"version": "1.9.0", "dist": {"tarball": "http://registry/app-1.9.0.tgz", "shasum": "a"}}
+			},
+			"time": {"1.9.0": "2024-01-01T00:00:00.000Z"}
+		}`))
+	}))
+	defer server.Close()
+
+	config := Config{
+		Token:    "pat.test",
+		Account:  "acct",
+		Registry: "npm-local",
+		PkgURL:   server.URL,
+		Name:     "@scope/app",
+	}
+	handler := NewNPMHandler(NewHTTPBackend(server.Client()))
+
+	result, err := handler.Get(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary npmPackageSummary
+	if err := json.Unmarshal(result.Raw, &summary); err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, entry := range summary.Versions {
+		versions = append(versions, entry.Version)
+	}
+	if want := []string{"1.9.0", "1.10.0", "2.0.0-rc.1"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("unexpected versions %v, want %v", versions, want)
+	}
+	if summary.Versions[0].Published == "" || summary.Versions[1].FileCount != 3 {
+		t.Errorf("unexpected version metadata %+v", summary.Versions)
+	}
+	if result.Version != "1.10.0" || result.SHA1 != "b" || result.Metadata["NPM_VERSION_COUNT"] != "3" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "2.0.0-rc.1"
+	result, err = handler.Get(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "2.0.0-rc.1" || result.Filename != "app-2.0.0-rc.1.tgz" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "3.0.0"
+	if _, err := handler.Get(context.Background(), config); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+}
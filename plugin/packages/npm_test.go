@@ -0,0 +1,168 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeNPMTarball writes a gzipped tarball holding package/package.json
+// with the given content and any additional package/ files
+func writeNPMTarball(t *testing.T, path, manifest string, files ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	entries := map[string]string{"package/package.json": manifest}
+	for _, name := range files {
+		entries[name] = name
+	}
+	for name, content := range entries {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateNPMName(t *testing.T) {
+	valid := []string{"express", "@babel/core", "lodash.merge", "my-package_2", "-dash"}
+	for _, name := range valid {
+		if err := validateNPMName(name); err != nil {
+			t.Errorf("validateNPMName(%q) returned %v", name, err)
+		}
+	}
+
+	invalid := []string{"", ".hidden", "_private", "Uppercase", " spaced", "node_modules", "@scope", "@/name",
+		"@scope/", "with space", "special!", strings.Repeat("a", 215), "@scope/.dotted"}
+	for _, name := range invalid {
+		if err := validateNPMName(name); err == nil {
+			t.Errorf("validateNPMName(%q) should fail", name)
+		}
+	}
+}
+
+func TestParseNPMManifest(t *testing.T) {
+	tests := []struct {
+		manifest string
+		err      string
+	}{
+		{manifest: `{"name": "app", "version": "1.2.3"}`},
+		{manifest: `{"name": "@scope/app", "version": "1.0.0-beta.1+build.5"}`},
+		{manifest: `{"version": "1.0.0"}`, err: "package name must be set"},
+		{manifest: `{"name": "app"}`, err: "package version must be set"},
+		{manifest: `{"name": "app", "version": "1.0"}`, err: "must be a valid semantic version"},
+		{manifest: `{"name": "app", "version": "01.0.0"}`, err: "must be a valid semantic version"},
+		{manifest: `{"name": "App", "version": "1.0.0"}`, err: "uppercase"},
+		{manifest: `not json`, err: "invalid character"},
+	}
+	for _, test := range tests {
+		_, err := parseNPMManifest([]byte(test.manifest))
+		if test.err == "" && err != nil {
+			t.Errorf("parseNPMManifest(%s) returned %v", test.manifest, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("parseNPMManifest(%s) = %v, want error containing %q", test.manifest, err, test.err)
+		}
+	}
+}
+
+func TestNPMHandler_Push(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(t.TempDir())
+	writeNPMTarball(t, "scope-app-2.0.0.tgz", `{"name": "@scope/app", "version": "2.0.0"}`, "package/index.js")
+
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("npm")
+
+	config := testConfig()
+	config.Source = "scope-app-2.0.0.tgz"
+	config.Name = ""
+	config.Version = "v2.0.0"
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runner.args()) != 1 {
+		t.Fatalf("expected 1 command, got %v", runner.args())
+	}
+	if result.Name != "@scope/app" || result.Version != "2.0.0" {
+		t.Errorf("unexpected coordinates %s@%s", result.Name, result.Version)
+	}
+	want := map[string]string{
+		"NPM_PACKAGE_NAME":    "@scope/app",
+		"NPM_PACKAGE_VERSION": "2.0.0",
+		"NPM_PACKAGE":         "@scope/app@2.0.0",
+		"NPM_PACKAGE_SCOPE":   "scope",
+		"NPM_DIST_TAG":        "latest",
+	}
+	if !reflect.DeepEqual(result.Metadata, want) {
+		t.Errorf("unexpected outputs %v", result.Metadata)
+	}
+}
+
+func TestNPMHandler_PushRejectsInvalidTarball(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(t.TempDir())
+	writeNPMTarball(t, "app.tgz", `{"name": "app", "version": "1.0.0"}`)
+	writeNPMTarball(t, "missing.tgz", `{"name": "app"}`)
+	if err := os.WriteFile("plain.tgz", []byte("not a tarball"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source  string
+		name    string
+		version string
+		err     string
+	}{
+		{source: "app.tgz", name: "other", err: "does not match the name 'app'"},
+		{source: "app.tgz", version: "1.0.1", err: "does not match the version '1.0.0'"},
+		{source: "missing.tgz", err: "package version must be set"},
+		{source: "plain.tgz", err: "not gzip compressed"},
+	}
+	for _, test := range tests {
+		factory, runner := newTestFactory(t)
+		handler, _ := factory.GetHandler("npm")
+
+		config := testConfig()
+		config.Source = test.source
+		config.Name = test.name
+		config.Version = test.version
+
+		_, err := handler.Push(context.Background(), config)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %v", test.source, test.err, err)
+		}
+		if len(runner.args()) != 0 {
+			t.Errorf("%s: nothing must be pushed, got %v", test.source, runner.args())
+		}
+	}
+}
@@ -17,9 +17,9 @@ type NuGetHandler struct {
 }
 
 // NewNuGetHandler creates a new NuGet package handler
-func NewNuGetHandler() *NuGetHandler {
+func NewNuGetHandler(backend Backend) *NuGetHandler {
 	return &NuGetHandler{
-		BaseHandler: NewBaseHandler(NuGet),
+		BaseHandler: NewBaseHandler(NuGet, backend),
 	}
 }
 
@@ -44,44 +44,43 @@ func (h *NuGetHandler) Validate(config Config) error {
 }
 
 // Push uploads NuGet packages to the registry
-func (h *NuGetHandler) Push(ctx context.Context, config Config) error {
+func (h *NuGetHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing NuGet push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
 // pushSingleFile handles pushing a single file for NuGet packages
-func (h *NuGetHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for NuGet)
-	cmdArgs, err := buildPushCommand(NuGet, config, "", filePath, artifactName, false)
-	if err != nil {
-		return err
-	}
-
-	return executeCommand(cmdArgs, fmt.Sprintf("push NuGet artifact '%s' to registry '%s'", artifactName, config.Registry))
+func (h *NuGetHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: NuGet,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+	})
 }
 
 // Pull downloads NuGet packages from the registry
-func (h *NuGetHandler) Pull(ctx context.Context, config Config) error {
+func (h *NuGetHandler) Pull(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement NuGet pull logic
-	return fmt.Errorf("NuGet pull is not yet implemented")
+	return nil, fmt.Errorf("NuGet pull is not yet implemented")
 }
 
 // Get retrieves NuGet package information
-func (h *NuGetHandler) Get(ctx context.Context, config Config) error {
+func (h *NuGetHandler) Get(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement NuGet get logic
-	return fmt.Errorf("NuGet get is not yet implemented")
+	return nil, fmt.Errorf("NuGet get is not yet implemented")
 }
 
 // Delete removes NuGet packages from the registry
-func (h *NuGetHandler) Delete(ctx context.Context, config Config) error {
+func (h *NuGetHandler) Delete(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement NuGet delete logic
-	return fmt.Errorf("NuGet delete is not yet implemented")
+	return nil, fmt.Errorf("NuGet delete is not yet implemented")
 }
@@ -0,0 +1,114 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Keys of the Harness CLI JSON output holding result fields, matched case
+// insensitively and ignoring '_' and '-'
+var (
+	outputURLKeys     = []string{"url", "downloadurl", "fileurl"}
+	outputVersionKeys = []string{"version", "latestversion"}
+	outputSHA256Keys  = []string{"sha256", "sha256checksum", "sha256sum"}
+	outputSizeKeys    = []string{"size", "filesize"}
+	outputPathKeys    = []string{"path", "filepath", "downloadpath"}
+)
+
+// applyCommandOutput fills the result from the JSON document printed by a
+// Harness CLI command run with '--format json'. Output that is not JSON is
+// ignored, the request details already in the result are kept.
+func applyCommandOutput(result *Result, output []byte) {
+	raw := extractJSON(output)
+	if raw == nil {
+		return
+	}
+	result.Raw = raw
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return
+	}
+	fields := flattenOutput(document)
+
+	if url := lookupOutput(fields, outputURLKeys); url != "" {
+		result.URL = url
+	}
+	if result.Version == "" {
+		result.Version = lookupOutput(fields, outputVersionKeys)
+	}
+	if sum := lookupOutput(fields, outputSHA256Keys); sum != "" {
+		result.SHA256 = strings.ToLower(sum)
+	}
+	if size, err := strconv.ParseInt(lookupOutput(fields, outputSizeKeys), 10, 64); err == nil && size > 0 {
+		result.Size = size
+	}
+	if p := lookupOutput(fields, outputPathKeys); p != "" {
+		result.Path = p
+	}
+}
+
+// extractJSON returns the JSON document printed by a command, which may be
+// preceded by progress messages
+func extractJSON(output []byte) json.RawMessage {
+	lines := bytes.Split(output, []byte("\n"))
+	for i, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || (line[0] != '{' && line[0] != '[') {
+			continue
+		}
+		candidate := bytes.TrimSpace(bytes.Join(lines[i:], []byte("\n")))
+		if json.Valid(candidate) {
+			return json.RawMessage(candidate)
+		}
+	}
+	return nil
+}
+
+// flattenOutput collects the scalar values of the document breadth first,
+// so values of the top level object win over values of nested objects such
+// as "data"
+func flattenOutput(document map[string]interface{}) map[string]string {
+	fields := map[string]string{}
+	queue := []map[string]interface{}{document}
+	for len(queue) > 0 {
+		object := queue[0]
+		queue = queue[1:]
+		for key, value := range object {
+			key = normalizeOutputKey(key)
+			if _, ok := fields[key]; ok {
+				continue
+			}
+			switch value := value.(type) {
+			case map[string]interface{}:
+				queue = append(queue, value)
+			case string:
+				if value != "" {
+					fields[key] = value
+				}
+			case float64:
+				fields[key] = strconv.FormatFloat(value, 'f', -1, 64)
+			}
+		}
+	}
+	return fields
+}
+
+func lookupOutput(fields map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func normalizeOutputKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyCommandOutput(t *testing.T) {
+	output := []byte("Downloading artifact...\n" + `{
+  "status": "SUCCESS",
+  "data": {
+    "downloadUrl": "https://pkg.harness.io/files/app.zip",
+    "version": "2.0.0",
+    "sha256": "ABCDEF",
+    "size": 1024
+  }
+}
+`)
+	result := &Result{Name: "app"}
+	applyCommandOutput(result, output)
+
+	if result.URL != "https://pkg.harness.io/files/app.zip" {
+		t.Errorf("unexpected URL %q", result.URL)
+	}
+	if result.Version != "2.0.0" {
+		t.Errorf("unexpected version %q", result.Version)
+	}
+	if result.SHA256 != "abcdef" {
+		t.Errorf("unexpected checksum %q", result.SHA256)
+	}
+	if result.Size != 1024 {
+		t.Errorf("unexpected size %d", result.Size)
+	}
+	if result.Raw == nil {
+		t.Error("expected the raw output to be kept")
+	}
+}
+
+func TestApplyCommandOutput_NotJSON(t *testing.T) {
+	result := &Result{Name: "app", Version: "1.0.0"}
+	applyCommandOutput(result, []byte("Artifact deleted\n"))
+	if result.Raw != nil || result.Version != "1.0.0" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCLIBackend_PullResult(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		// Simulate the CLI downloading the file and reporting it
+		dest := cmd.Args[6]
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dest, "artifact.bin"), []byte("payload"), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.Stdout, `{"url": "https://pkg.harness.io/artifact.bin"}`)
+		return nil
+	}
+
+	config := testConfig()
+	config.Filename = "artifact.bin"
+	config.Destination = "downloads"
+
+	handler, _ := factory.GetHandler("generic")
+	result, err := handler.Pull(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := fileChecksums(filepath.Join("downloads", "artifact.bin"))
+	if result.SHA256 != want.SHA256 || result.Size != int64(len("payload")) {
+		t.Errorf("unexpected checksum %q (%d bytes), want %q", result.SHA256, result.Size, want.SHA256)
+	}
+	if result.URL != "https://pkg.harness.io/artifact.bin" {
+		t.Errorf("unexpected URL %q", result.URL)
+	}
+	if result.Path != filepath.Join("downloads", "artifact.bin") {
+		t.Errorf("unexpected path %q", result.Path)
+	}
+}
@@ -6,7 +6,13 @@ package packages
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,9 +23,9 @@ type PythonHandler struct {
 }
 
 // NewPythonHandler creates a new Python package handler
-func NewPythonHandler() *PythonHandler {
+func NewPythonHandler(backend Backend) *PythonHandler {
 	return &PythonHandler{
-		BaseHandler: NewBaseHandler(Python),
+		BaseHandler: NewBaseHandler(Python, backend),
 	}
 }
 
@@ -43,45 +49,324 @@ func (h *PythonHandler) Validate(config Config) error {
 	return nil
 }
 
-// Push uploads Python packages to the registry
-func (h *PythonHandler) Push(ctx context.Context, config Config) error {
+// Push uploads the distributions of a Python project. source is a wheel,
+// an sdist or a directory like dist/ whose distributions are all pushed.
+// A directory holding a pyproject.toml is a pure-Python project whose sdist
+// and wheel are built first. The metadata of every distribution is
+// inspected: they must belong to the same project version and follow the
+// filename conventions.
+func (h *PythonHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing Python push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
+	info, err := os.Stat(config.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access source '%s': %w", config.Source, err)
+	}
+
+	files := []string{config.Source}
+	switch {
+	case info.IsDir() && isPythonProject(config.Source):
+		project, err := loadPythonProject(config.Source, config.Version)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Printf("Building sdist and wheel of %s %s from %s", project.Name, project.Version, pyprojectFilename)
+		outDir, err := os.MkdirTemp("", "python-build-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create build directory: %w", err)
+		}
+		defer os.RemoveAll(outDir)
+		if files, err = buildPythonProject(project, outDir); err != nil {
+			return nil, err
+		}
+	case info.IsDir():
+		if files, err = findPythonDistributions(config.Source); err != nil {
+			return nil, err
+		}
+	}
+	dists, err := inspectPythonDistributions(files)
+	if err != nil {
+		return nil, err
+	}
+	metadata := dists[0].Metadata
+	if err := checkPythonCoordinates(config, metadata); err != nil {
+		return nil, err
+	}
+
+	logrus.Printf("Resolved Python project %s %s with %d distributions:", metadata.Name, metadata.Version, len(dists))
+	for _, dist := range dists {
+		logrus.Printf("  %s (%s)", filepath.Base(dist.File), dist.Kind)
+		for _, warning := range dist.filenameWarnings() {
+			logrus.Printf("⚠ Warning: %s", warning)
+		}
+	}
+	return h.pushDistributions(ctx, config, dists)
+}
+
+// pushDistributions pushes every distribution of a project version
+func (h *PythonHandler) pushDistributions(ctx context.Context, config Config, dists []*pythonDistribution) (*Result, error) {
+	// Distributions keep their names, clients parse them
+	if config.Filename != "" {
+		logrus.Printf("⚠ Warning: filename '%s' is ignored for Python packages", config.Filename)
+		config.Filename = ""
+	}
+	metadata := dists[0].Metadata
+
+	jobs := make([]uploadJob, len(dists))
+	files := make([]*Result, len(dists))
+	for i, dist := range dists {
+		jobs[i] = uploadJob{
+			Label: filepath.Base(dist.File),
+			Push: func(ctx context.Context) error {
+				result, err := h.pushSingleFile(ctx, config, dist.File, metadata)
+				files[i] = result
+				return err
+			},
+		}
+	}
+	if err := runUploads(ctx, config, fmt.Sprintf("Python project %s %s", metadata.Name, metadata.Version), jobs); err != nil {
+		return nil, err
+	}
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	result := &Result{
+		PackageType: Python,
+		Registry:    config.Registry,
+		Name:        metadata.Name,
+		Version:     metadata.Version,
+		Files:       files,
+		Metadata:    pythonOutputs(dists),
+	}
+	for _, file := range files {
+		result.Size += file.Size
+	}
+	// A single distribution is described by the result itself
+	if len(files) == 1 {
+		files[0].Metadata = result.Metadata
+		return files[0], nil
+	}
+	return result, nil
 }
 
 // pushSingleFile handles pushing a single file for Python packages
-func (h *PythonHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for Python)
-	cmdArgs, err := buildPushCommand(Python, config, "", filePath, artifactName, false)
+func (h *PythonHandler) pushSingleFile(ctx context.Context, config Config, filePath string, metadata *pythonMetadata) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: Python,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        metadata.Name,
+		Version:     metadata.Version,
+	})
+}
+
+// checkPythonCoordinates validates the project read from the distributions
+// against the name and version settings
+func checkPythonCoordinates(config Config, metadata *pythonMetadata) error {
+	if config.Name != "" && normalizePythonName(config.Name) != normalizePythonName(metadata.Name) {
+		return fmt.Errorf("name '%s' does not match the project name '%s' of the distributions", config.Name, metadata.Name)
+	}
+	if config.Version != "" && comparePythonVersions(config.Version, metadata.Version) != 0 {
+		return fmt.Errorf("version '%s' does not match the version '%s' of the distributions", config.Version, metadata.Version)
+	}
+	return nil
+}
+
+// pythonOutputs returns the step outputs describing pushed distributions
+func pythonOutputs(dists []*pythonDistribution) map[string]string {
+	metadata := dists[0].Metadata
+	var names []string
+	for _, dist := range dists {
+		names = append(names, filepath.Base(dist.File))
+	}
+	return map[string]string{
+		"PYTHON_PACKAGE_NAME":    metadata.Name,
+		"PYTHON_PACKAGE_VERSION": metadata.Version,
+		"PYTHON_DISTRIBUTIONS":   strings.Join(names, ","),
+	}
+}
+
+// Pull downloads a distribution of a project version into the destination:
+// the wheel compatible with the first matching tag of python_tags, or the
+// sdist, or the file named by filename. The file is checked against the
+// hash listed on the simple index page and its metadata.
+func (h *PythonHandler) Pull(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Python pull command")
+
+	if err := validatePythonRequest(config); err != nil {
+		return nil, err
+	}
+	if config.Version == "" {
+		return nil, fmt.Errorf("package version must be set")
+	}
+	if config.Destination == "" {
+		return nil, fmt.Errorf("destination path must be set")
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+
+	files, err := pythonFiles(ctx, h.backend, config, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	version := strings.TrimPrefix(config.Version, "v")
+	summary, err := pythonSummary(config.Name, files, version)
+	if err != nil {
+		return nil, err
+	}
+	files = summary.Versions[0].Files
+
+	var file *pythonFile
+	if config.Filename != "" {
+		for i := range files {
+			if files[i].Filename == config.Filename {
+				file = &files[i]
+			}
+		}
+		if file == nil {
+			return nil, fmt.Errorf("file '%s' not found in version '%s' of project '%s'", config.Filename, version, config.Name)
+		}
+	} else if file, err = selectPythonFile(files, config.PythonTags); err != nil {
+		return nil, fmt.Errorf("failed to select a distribution of %s %s: %w", config.Name, version, err)
+	}
+	if file.Yanked {
+		logrus.Printf("⚠ Warning: %s is yanked: %s", file.Filename, file.YankedReason)
+	}
+
+	// The download path is relative to the Python endpoint of the registry
+	root := packageURL(config, "python") + "/"
+	if !strings.HasPrefix(file.URL, root) {
+		return nil, fmt.Errorf("distribution '%s' is served from '%s', outside of registry '%s'", file.Filename, file.URL, config.Registry)
+	}
+	filePath, err := url.PathUnescape(strings.TrimPrefix(file.URL, root))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid URL of distribution '%s': %w", file.Filename, err)
 	}
+	logrus.Printf("Pulling %s", file.Filename)
 
-	return executeCommand(cmdArgs, fmt.Sprintf("push Python artifact '%s' to registry '%s'", artifactName, config.Registry))
+	result, err := h.backend.Pull(ctx, PullRequest{
+		PackageType: Python,
+		Config:      config,
+		Name:        normalizePythonName(config.Name),
+		Version:     file.Version,
+		Filename:    file.Filename,
+		Path:        filePath,
+		Destination: config.Destination,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config.ExpectedSHA256 == "" && file.SHA256 != "" {
+		err = verifyFileSHA256(ctx, result, file.SHA256, "simple index")
+	} else {
+		err = verifyPull(ctx, h.backend, config, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the registry served the requested project version
+	dist, err := inspectPythonDistribution(result.Path)
+	if err != nil {
+		return nil, err
+	}
+	if normalizePythonName(dist.Metadata.Name) != normalizePythonName(config.Name) || comparePythonVersions(dist.Metadata.Version, version) != 0 {
+		return nil, fmt.Errorf("downloaded distribution contains %s %s, expected %s %s", dist.Metadata.Name, dist.Metadata.Version, config.Name, version)
+	}
+	result.Metadata = pythonOutputs([]*pythonDistribution{dist})
+	return result, nil
 }
 
-// Pull downloads Python packages from the registry
-func (h *PythonHandler) Pull(ctx context.Context, config Config) error {
-	// TODO: Implement Python pull logic
-	return fmt.Errorf("Python pull is not yet implemented")
+// Get prints the versions of a project and their distributions with their
+// hashes as JSON, or of a single version when one is set
+func (h *PythonHandler) Get(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Python get command")
+
+	if err := validatePythonRequest(config); err != nil {
+		return nil, err
+	}
+	if config.PkgURL == "" {
+		return nil, fmt.Errorf("package URL must be set")
+	}
+
+	files, err := pythonFiles(ctx, h.backend, config, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := pythonSummary(config.Name, files, strings.TrimPrefix(config.Version, "v"))
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode project summary: %w", err)
+	}
+	printJSON(raw)
+
+	// Describe the requested version, or the one installed by default
+	version := summary.latestVersion()
+	if config.Version != "" {
+		version = summary.Versions[0].Version
+	}
+	result := &Result{
+		PackageType: Python,
+		Registry:    config.Registry,
+		Name:        summary.Name,
+		Version:     version,
+		URL:         pythonIndexURL(config, config.Name),
+		Metadata: map[string]string{
+			"PYTHON_PACKAGE_NAME":  summary.Name,
+			"PYTHON_VERSION_COUNT": strconv.Itoa(len(summary.Versions)),
+		},
+		Raw: raw,
+	}
+	if latest := summary.latestVersion(); latest != "" {
+		result.Metadata["PYTHON_LATEST_VERSION"] = latest
+	}
+	return result, nil
 }
 
-// Get retrieves Python package information
-func (h *PythonHandler) Get(ctx context.Context, config Config) error {
-	// TODO: Implement Python get logic
-	return fmt.Errorf("Python get is not yet implemented")
+// Delete removes a version of a project from the registry
+func (h *PythonHandler) Delete(ctx context.Context, config Config) (*Result, error) {
+	logrus.Println("Executing Python delete command")
+
+	if err := validatePythonRequest(config); err != nil {
+		return nil, err
+	}
+	if config.Version == "" {
+		return nil, fmt.Errorf("package version must be set")
+	}
+
+	return h.backend.Delete(ctx, ArtifactRequest{
+		PackageType: Python,
+		Config:      config,
+		Name:        normalizePythonName(config.Name),
+		Version:     strings.TrimPrefix(config.Version, "v"),
+	})
 }
 
-// Delete removes Python packages from the registry
-func (h *PythonHandler) Delete(ctx context.Context, config Config) error {
-	// TODO: Implement Python delete logic
-	return fmt.Errorf("Python delete is not yet implemented")
+// validatePythonRequest checks the settings shared by pull, get and delete
+func validatePythonRequest(config Config) error {
+	if config.Registry == "" {
+		return fmt.Errorf("registry name must be set")
+	}
+	if config.Name == "" {
+		return fmt.Errorf("package name must be set")
+	}
+	if config.Token == "" {
+		return fmt.Errorf("authentication token must be set")
+	}
+	if config.Account == "" {
+		return fmt.Errorf("account ID must be set")
+	}
+	if !pythonNamePattern.MatchString(config.Name) {
+		return fmt.Errorf("invalid project name '%s'", config.Name)
+	}
+	return nil
 }
@@ -0,0 +1,598 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pyprojectFilename is the project file of a Python project
+const pyprojectFilename = "pyproject.toml"
+
+var (
+	// pythonCanonicalVersionPattern matches a normalized PEP 440 version
+	pythonCanonicalVersionPattern = regexp.MustCompile(`^([1-9][0-9]*!)?(0|[1-9][0-9]*)(\.(0|[1-9][0-9]*))*` +
+		`((a|b|rc)(0|[1-9][0-9]*))?(\.post(0|[1-9][0-9]*))?(\.dev(0|[1-9][0-9]*))?(\+[a-z0-9]+(\.[a-z0-9]+)*)?$`)
+
+	// pythonExtensionSources lists files that make a project not pure Python
+	pythonExtensionSources = []string{".c", ".cc", ".cpp", ".cxx", ".pyx", ".pxd", ".so", ".pyd", ".dylib"}
+
+	// pythonLicensePatterns are the license files included when the
+	// project does not list them, like setuptools does
+	pythonLicensePatterns = []string{"LICEN[CS]E*", "COPYING*", "NOTICE*", "AUTHORS*"}
+
+	// pythonReadmeTypes maps readme file extensions to their content type
+	pythonReadmeTypes = map[string]string{".md": "text/markdown", ".rst": "text/x-rst", ".txt": "text/plain"}
+)
+
+// pythonContact is an author or maintainer of a project
+type pythonContact struct {
+	Name  string
+	Email string
+}
+
+// pythonProject holds the [project] table of a pyproject.toml
+type pythonProject struct {
+	// Dir is the project directory
+	Dir string
+
+	Name           string
+	Version        string
+	Description    string
+	RequiresPython string
+	License        string
+	Keywords       []string
+	Classifiers    []string
+	Dependencies   []string
+	Authors        []pythonContact
+	Maintainers    []pythonContact
+	URLs           map[string]string
+
+	// OptionalDependencies maps extras to their dependencies
+	OptionalDependencies map[string][]string
+
+	// EntryPoints maps entry point groups, e.g. console_scripts, to their
+	// entry points
+	EntryPoints map[string]map[string]string
+
+	// Readme is the path of the readme file relative to Dir, ReadmeText
+	// its content
+	Readme      string
+	ReadmeText  string
+	ReadmeType  string
+	LicenseFile []string
+}
+
+// isPythonProject reports whether the directory holds a pyproject.toml
+func isPythonProject(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, pyprojectFilename))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// loadPythonProject reads the [project] table of the pyproject.toml in dir.
+// A version declared dynamic is taken from the version argument, other
+// dynamic fields cannot be computed without running the build backend.
+func loadPythonProject(dir, version string) (*pythonProject, error) {
+	data, err := os.ReadFile(filepath.Join(dir, pyprojectFilename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pyprojectFilename, err)
+	}
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in '%s': %w", pyprojectFilename, dir, err)
+	}
+	table, ok := doc["project"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s in '%s' has no [project] table", pyprojectFilename, dir)
+	}
+
+	project := &pythonProject{Dir: dir}
+	fields := tomlFields{table: table}
+	project.Name = fields.string("name")
+	project.Version = fields.string("version")
+	project.Description = fields.string("description")
+	project.RequiresPython = fields.string("requires-python")
+	project.Keywords = fields.strings("keywords")
+	project.Classifiers = fields.strings("classifiers")
+	project.Dependencies = fields.strings("dependencies")
+	project.URLs = fields.stringMap("urls")
+	project.Authors = fields.contacts("authors")
+	project.Maintainers = fields.contacts("maintainers")
+	dynamic := fields.strings("dynamic")
+
+	project.OptionalDependencies = map[string][]string{}
+	for extra := range fields.tables("optional-dependencies") {
+		project.OptionalDependencies[extra] = fields.sub("optional-dependencies").strings(extra)
+	}
+	project.EntryPoints = map[string]map[string]string{}
+	if scripts := fields.stringMap("scripts"); len(scripts) > 0 {
+		project.EntryPoints["console_scripts"] = scripts
+	}
+	if scripts := fields.stringMap("gui-scripts"); len(scripts) > 0 {
+		project.EntryPoints["gui_scripts"] = scripts
+	}
+	for group := range fields.tables("entry-points") {
+		project.EntryPoints[group] = fields.sub("entry-points").stringMap(group)
+	}
+	if err := fields.err; err != nil {
+		return nil, fmt.Errorf("invalid [project] table in %s: %w", pyprojectFilename, err)
+	}
+
+	for _, field := range dynamic {
+		if field != "version" {
+			return nil, fmt.Errorf("field '%s' is dynamic in %s, only a dynamic version is supported", field, pyprojectFilename)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("version is dynamic in %s, the version setting must be set", pyprojectFilename)
+		}
+		project.Version = strings.TrimPrefix(version, "v")
+	}
+
+	if !pythonNamePattern.MatchString(project.Name) {
+		return nil, fmt.Errorf("invalid project name '%s' in %s", project.Name, pyprojectFilename)
+	}
+	if project.Version == "" {
+		return nil, fmt.Errorf("%s does not declare a version", pyprojectFilename)
+	}
+	if !pythonCanonicalVersionPattern.MatchString(project.Version) {
+		return nil, fmt.Errorf("version '%s' must be a normalized PEP 440 version, e.g. 1.0.0, 1.0.0rc1 or 1.0.0.post1", project.Version)
+	}
+	if strings.ContainsAny(project.Description, "\r\n") {
+		return nil, fmt.Errorf("description in %s must be a single line", pyprojectFilename)
+	}
+
+	if err := project.loadReadme(table["readme"]); err != nil {
+		return nil, err
+	}
+	if err := project.loadLicense(table["license"], fields.strings("license-files")); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// loadReadme reads the readme, given as a file name or a table with a file
+// or text and a content-type
+func (p *pythonProject) loadReadme(value any) error {
+	switch readme := value.(type) {
+	case nil:
+		return nil
+	case string:
+		p.Readme = readme
+		p.ReadmeType = pythonReadmeTypes[strings.ToLower(path.Ext(readme))]
+		if p.ReadmeType == "" {
+			return fmt.Errorf("unknown content type of readme '%s', use a table with content-type", readme)
+		}
+	case map[string]any:
+		fields := tomlFields{table: readme}
+		p.Readme = fields.string("file")
+		p.ReadmeText = fields.string("text")
+		p.ReadmeType = fields.string("content-type")
+		if fields.err != nil {
+			return fmt.Errorf("invalid readme in %s: %w", pyprojectFilename, fields.err)
+		}
+		if p.ReadmeType == "" {
+			return fmt.Errorf("readme table in %s must declare a content-type", pyprojectFilename)
+		}
+	default:
+		return fmt.Errorf("readme in %s must be a string or a table", pyprojectFilename)
+	}
+
+	if p.Readme != "" {
+		data, err := os.ReadFile(filepath.Join(p.Dir, filepath.FromSlash(p.Readme)))
+		if err != nil {
+			return fmt.Errorf("failed to read readme: %w", err)
+		}
+		p.ReadmeText = string(data)
+	}
+	return nil
+}
+
+// loadLicense reads the license, given as an SPDX expression or a table
+// with a file or text, and resolves the license files
+func (p *pythonProject) loadLicense(value any, patterns []string) error {
+	switch license := value.(type) {
+	case nil:
+	case string:
+		p.License = license
+	case map[string]any:
+		fields := tomlFields{table: license}
+		p.License = fields.string("text")
+		if file := fields.string("file"); file != "" {
+			data, err := os.ReadFile(filepath.Join(p.Dir, filepath.FromSlash(file)))
+			if err != nil {
+				return fmt.Errorf("failed to read license file: %w", err)
+			}
+			p.License = strings.TrimSpace(string(data))
+			p.LicenseFile = append(p.LicenseFile, file)
+		}
+		if fields.err != nil {
+			return fmt.Errorf("invalid license in %s: %w", pyprojectFilename, fields.err)
+		}
+	default:
+		return fmt.Errorf("license in %s must be a string or a table", pyprojectFilename)
+	}
+
+	if len(patterns) == 0 && len(p.LicenseFile) == 0 {
+		patterns = pythonLicensePatterns
+	}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(p.Dir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return fmt.Errorf("invalid license file pattern '%s': %w", pattern, err)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			rel, err := filepath.Rel(p.Dir, match)
+			if err != nil {
+				return err
+			}
+			if rel = filepath.ToSlash(rel); !slices.Contains(p.LicenseFile, rel) {
+				p.LicenseFile = append(p.LicenseFile, rel)
+			}
+		}
+	}
+	sort.Strings(p.LicenseFile)
+	return nil
+}
+
+// distName returns the project name used in distribution filenames
+func (p *pythonProject) distName() string {
+	return escapePythonName(p.Name) + "-" + p.Version
+}
+
+// pythonSourceFile is a file of the import package of a project
+type pythonSourceFile struct {
+	// Path is the path relative to the project directory
+	Path string
+
+	// Name is the path inside the wheel
+	Name string
+}
+
+// sourceFiles locates the import package of the project, named after the
+// normalized project name, and returns its files. The package is a
+// directory or a single module, in src/ or in the project directory.
+func (p *pythonProject) sourceFiles() ([]pythonSourceFile, error) {
+	module := escapePythonName(p.Name)
+	for _, root := range []string{"src", "."} {
+		dir := path.Join(root, module)
+		if info, err := os.Stat(filepath.Join(p.Dir, filepath.FromSlash(dir))); err == nil && info.IsDir() {
+			return p.walkPackage(root, dir)
+		}
+		file := dir + ".py"
+		if info, err := os.Stat(filepath.Join(p.Dir, filepath.FromSlash(file))); err == nil && info.Mode().IsRegular() {
+			return []pythonSourceFile{{Path: file, Name: module + ".py"}}, nil
+		}
+	}
+	return nil, fmt.Errorf("package '%s' not found, expected src/%[1]s/, %[1]s/, src/%[1]s.py or %[1]s.py in '%s'", module, p.Dir)
+}
+
+// walkPackage returns the files of a package directory, skipping caches
+// and hidden files
+func (p *pythonProject) walkPackage(root, dir string) ([]pythonSourceFile, error) {
+	var files []pythonSourceFile
+	err := filepath.WalkDir(filepath.Join(p.Dir, filepath.FromSlash(dir)), func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if name == "__pycache__" || (strings.HasPrefix(name, ".") && name != ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".pyc") || strings.HasSuffix(name, ".pyo") || !entry.Type().IsRegular() {
+			return nil
+		}
+		if slices.Contains(pythonExtensionSources, strings.ToLower(filepath.Ext(name))) {
+			return fmt.Errorf("'%s' is an extension module file, only pure-Python projects can be built, build the wheels with Python instead", file)
+		}
+
+		rel, err := filepath.Rel(p.Dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files = append(files, pythonSourceFile{Path: rel, Name: strings.TrimPrefix(rel, root+"/")})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// coreMetadata renders the METADATA file of the wheel, also used as the
+// PKG-INFO file of the sdist
+func (p *pythonProject) coreMetadata() []byte {
+	var b bytes.Buffer
+	field := func(name, value string) {
+		if value != "" {
+			// Continuation lines of multi-line values are indented
+			fmt.Fprintf(&b, "%s: %s\n", name, strings.ReplaceAll(value, "\n", "\n        "))
+		}
+	}
+	field("Metadata-Version", "2.1")
+	field("Name", p.Name)
+	field("Version", p.Version)
+	field("Summary", p.Description)
+	field("Keywords", strings.Join(p.Keywords, ","))
+	for _, contacts := range []struct {
+		prefix string
+		list   []pythonContact
+	}{{"Author", p.Authors}, {"Maintainer", p.Maintainers}} {
+		var names, emails []string
+		for _, contact := range contacts.list {
+			switch {
+			case contact.Email != "" && contact.Name != "":
+				emails = append(emails, fmt.Sprintf("%s <%s>", contact.Name, contact.Email))
+			case contact.Email != "":
+				emails = append(emails, contact.Email)
+			default:
+				names = append(names, contact.Name)
+			}
+		}
+		field(contacts.prefix, strings.Join(names, ", "))
+		field(contacts.prefix+"-email", strings.Join(emails, ", "))
+	}
+	field("License", p.License)
+	for _, label := range sortedKeys(p.URLs) {
+		field("Project-URL", label+", "+p.URLs[label])
+	}
+	for _, classifier := range p.Classifiers {
+		field("Classifier", classifier)
+	}
+	field("Requires-Python", p.RequiresPython)
+	for _, dependency := range p.Dependencies {
+		field("Requires-Dist", dependency)
+	}
+	for _, extra := range sortedKeys(p.OptionalDependencies) {
+		field("Provides-Extra", extra)
+		for _, dependency := range p.OptionalDependencies[extra] {
+			requirement, marker, found := strings.Cut(dependency, ";")
+			if found {
+				dependency = fmt.Sprintf("%s; (%s) and extra == %q", strings.TrimSpace(requirement), strings.TrimSpace(marker), extra)
+			} else {
+				dependency = fmt.Sprintf("%s; extra == %q", strings.TrimSpace(dependency), extra)
+			}
+			field("Requires-Dist", dependency)
+		}
+	}
+	if p.ReadmeText != "" {
+		field("Description-Content-Type", p.ReadmeType)
+		b.WriteString("\n")
+		b.WriteString(p.ReadmeText)
+	}
+	return b.Bytes()
+}
+
+// entryPoints renders the entry_points.txt file, or nil without entry points
+func (p *pythonProject) entryPoints() []byte {
+	if len(p.EntryPoints) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	for _, group := range sortedKeys(p.EntryPoints) {
+		fmt.Fprintf(&b, "[%s]\n", group)
+		for _, name := range sortedKeys(p.EntryPoints[group]) {
+			fmt.Fprintf(&b, "%s = %s\n", name, p.EntryPoints[group][name])
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// pythonBuildTime returns the modification time of every file of built
+// distributions: SOURCE_DATE_EPOCH when set, so that builds are reproducible
+// and dated, or the earliest time zip files can store
+func pythonBuildTime() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil && epoch >= 315532800 {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// buildPythonProject builds the sdist and the py3-none-any wheel of a
+// pure-Python project into outDir and returns their paths, wheel first
+func buildPythonProject(project *pythonProject, outDir string) ([]string, error) {
+	sources, err := project.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	modTime := pythonBuildTime()
+
+	wheel, err := project.buildWheel(sources, outDir, modTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build wheel: %w", err)
+	}
+	sdist, err := project.buildSdist(sources, outDir, modTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sdist: %w", err)
+	}
+	logrus.Printf("Built %s and %s with %d source files", filepath.Base(wheel), filepath.Base(sdist), len(sources))
+	return []string{wheel, sdist}, nil
+}
+
+// buildWheel writes the wheel: the package files followed by the
+// .dist-info directory, whose RECORD lists the hash of every file
+func (p *pythonProject) buildWheel(sources []pythonSourceFile, outDir string, modTime time.Time) (string, error) {
+	wheel := filepath.Join(outDir, p.distName()+"-py3-none-any"+wheelExtension)
+	out, err := os.Create(wheel)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	var record bytes.Buffer
+	add := func(name string, data []byte) error {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+		header.SetMode(0644)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&record, "%s,sha256=%s,%d\n", name, base64.RawURLEncoding.EncodeToString(sum[:]), len(data))
+		return nil
+	}
+
+	for _, source := range sources {
+		data, err := os.ReadFile(filepath.Join(p.Dir, filepath.FromSlash(source.Path)))
+		if err != nil {
+			return "", err
+		}
+		if err := add(source.Name, data); err != nil {
+			return "", err
+		}
+	}
+
+	distInfo := p.distName() + ".dist-info/"
+	if err := add(distInfo+"METADATA", p.coreMetadata()); err != nil {
+		return "", err
+	}
+	wheelFile := "Wheel-Version: 1.0\nGenerator: drone-har\nRoot-Is-Purelib: true\nTag: py3-none-any\n"
+	if err := add(distInfo+"WHEEL", []byte(wheelFile)); err != nil {
+		return "", err
+	}
+	if entryPoints := p.entryPoints(); entryPoints != nil {
+		if err := add(distInfo+"entry_points.txt", entryPoints); err != nil {
+			return "", err
+		}
+	}
+	for _, license := range p.LicenseFile {
+		data, err := os.ReadFile(filepath.Join(p.Dir, filepath.FromSlash(license)))
+		if err != nil {
+			return "", err
+		}
+		if err := add(distInfo+"licenses/"+license, data); err != nil {
+			return "", err
+		}
+	}
+
+	// RECORD lists itself without a hash
+	fmt.Fprintf(&record, "%sRECORD,,\n", distInfo)
+	header := &zip.FileHeader{Name: distInfo + "RECORD", Method: zip.Deflate, Modified: modTime}
+	header.SetMode(0644)
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(record.Bytes()); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return wheel, out.Close()
+}
+
+// buildSdist writes the sdist: PKG-INFO, pyproject.toml, the readme, the
+// license files and the package files below a <name>-<version>/ directory
+func (p *pythonProject) buildSdist(sources []pythonSourceFile, outDir string, modTime time.Time) (string, error) {
+	sdist := filepath.Join(outDir, p.distName()+sdistExtension)
+	out, err := os.Create(sdist)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	files := []string{pyprojectFilename}
+	if p.Readme != "" {
+		files = append(files, path.Clean(p.Readme))
+	}
+	files = append(files, p.LicenseFile...)
+	for _, source := range sources {
+		files = append(files, source.Path)
+	}
+	sort.Strings(files)
+	files = slices.Compact(files)
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	root := p.distName() + "/"
+	metadata := p.coreMetadata()
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: root + "PKG-INFO", Mode: 0644, Size: int64(len(metadata)), ModTime: modTime, Format: tar.FormatUSTAR}
+	if err := tw.WriteHeader(header); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tw, bytes.NewReader(metadata)); err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if err := addTarFile(tw, filepath.Join(p.Dir, filepath.FromSlash(file)), root+file, modTime); err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	return sdist, out.Close()
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// contacts reads an array of tables with name and email strings
+func (f *tomlFields) contacts(key string) []pythonContact {
+	values, ok := f.table[key].([]any)
+	if !ok {
+		if f.table[key] != nil {
+			f.fail(key, "an array of tables")
+		}
+		return nil
+	}
+	var contacts []pythonContact
+	for _, value := range values {
+		table, ok := value.(map[string]any)
+		if !ok {
+			f.fail(key, "an array of tables")
+			return nil
+		}
+		fields := tomlFields{table: table}
+		contact := pythonContact{Name: fields.string("name"), Email: fields.string("email")}
+		if fields.err != nil {
+			f.fail(key, "an array of tables with name and email strings")
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts
+}
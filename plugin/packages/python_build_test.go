@@ -0,0 +1,284 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPyproject = `[project]
+name = "My.Pkg"
+dynamic = ["version"]
+description = "A package"
+readme = "README.md"
+requires-python = ">=3.8"
+license = {text = "MIT"}
+authors = [{name = "Jane", email = "jane@example.com"}, {name = "Joe"}]
+classifiers = ["Programming Language :: Python :: 3"]
+dependencies = ["requests>=2"]
+
+[project.optional-dependencies]
+test = ["pytest; python_version >= '3.8'"]
+
+[project.scripts]
+my-pkg = "my_pkg.cli:main"
+
+[project.urls]
+Homepage = "https://example.com"
+`
+
+// writePythonProject writes a src layout project with the pyproject.toml
+func writePythonProject(t *testing.T, pyproject string, files ...string) string {
+	t.Helper()
+	dir := writeTree(t, append([]string{"README.md", "LICENSE", "src/my_pkg/__init__.py", "src/my_pkg/cli.py",
+		"src/my_pkg/__pycache__/cli.cpython-311.pyc", "src/my_pkg/data/.hidden", "src/my_pkg/data/schema.json", "tests/test_cli.py"}, files...)...)
+	if err := os.WriteFile(filepath.Join(dir, pyprojectFilename), []byte(pyproject), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// readArchive returns the contents of the files of a wheel or an sdist
+func readArchive(t *testing.T, file string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	if strings.HasSuffix(file, wheelExtension) {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(r)
+			r.Close()
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tr)
+		files[header.Name] = string(content)
+	}
+}
+
+func TestBuildPythonProject(t *testing.T) {
+	captureLogs(t)
+	dir := writePythonProject(t, testPyproject)
+	project, err := loadPythonProject(dir, "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := buildPythonProject(project, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(built) != 2 || filepath.Base(built[0]) != "my_pkg-1.2.0-py3-none-any.whl" || filepath.Base(built[1]) != "my_pkg-1.2.0.tar.gz" {
+		t.Fatalf("unexpected distributions %q", built)
+	}
+
+	dists, err := inspectPythonDistributions(built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dist := range dists {
+		if warnings := dist.filenameWarnings(); len(warnings) != 0 {
+			t.Errorf("unexpected warnings %q", warnings)
+		}
+	}
+
+	wheel := readArchive(t, built[0])
+	want := []string{
+		"my_pkg-1.2.0.dist-info/METADATA",
+		"my_pkg-1.2.0.dist-info/RECORD",
+		"my_pkg-1.2.0.dist-info/WHEEL",
+		"my_pkg-1.2.0.dist-info/entry_points.txt",
+		"my_pkg-1.2.0.dist-info/licenses/LICENSE",
+		"my_pkg/__init__.py",
+		"my_pkg/cli.py",
+		"my_pkg/data/schema.json",
+	}
+	if names := sortedKeys(wheel); !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected wheel files\n got: %q\nwant: %q", names, want)
+	}
+
+	metadata := wheel["my_pkg-1.2.0.dist-info/METADATA"]
+	for _, line := range []string{
+		"Name: My.Pkg\n",
+		"Version: 1.2.0\n",
+		"Author: Joe\n",
+		"Author-email: Jane <jane@example.com>\n",
+		"License: MIT\n",
+		"Project-URL: Homepage, https://example.com\n",
+		"Requires-Python: >=3.8\n",
+		"Requires-Dist: requests>=2\n",
+		"Provides-Extra: test\n",
+		"Requires-Dist: pytest; (python_version >= '3.8') and extra == \"test\"\n",
+		"Description-Content-Type: text/markdown\n\nREADME.md",
+	} {
+		if !strings.Contains(metadata, line) {
+			t.Errorf("METADATA does not contain %q:\n%s", line, metadata)
+		}
+	}
+	if entryPoints := wheel["my_pkg-1.2.0.dist-info/entry_points.txt"]; entryPoints != "[console_scripts]\nmy-pkg = my_pkg.cli:main\n\n" {
+		t.Errorf("unexpected entry points %q", entryPoints)
+	}
+
+	// Every file but RECORD itself is listed with its hash and size
+	record := strings.Split(strings.TrimSpace(wheel["my_pkg-1.2.0.dist-info/RECORD"]), "\n")
+	if len(record) != len(wheel) || record[len(record)-1] != "my_pkg-1.2.0.dist-info/RECORD,," {
+		t.Fatalf("unexpected RECORD %q", record)
+	}
+	for _, line := range record[:len(record)-1] {
+		parts := strings.Split(line, ",")
+		sum := sha256.Sum256([]byte(wheel[parts[0]]))
+		if want := fmt.Sprintf("sha256=%s", base64.RawURLEncoding.EncodeToString(sum[:])); parts[1] != want || parts[2] != fmt.Sprint(len(wheel[parts[0]])) {
+			t.Errorf("unexpected RECORD line %q", line)
+		}
+	}
+
+	sdist := readArchive(t, built[1])
+	want = []string{
+		"my_pkg-1.2.0/LICENSE",
+		"my_pkg-1.2.0/PKG-INFO",
+		"my_pkg-1.2.0/README.md",
+		"my_pkg-1.2.0/pyproject.toml",
+		"my_pkg-1.2.0/src/my_pkg/__init__.py",
+		"my_pkg-1.2.0/src/my_pkg/cli.py",
+		"my_pkg-1.2.0/src/my_pkg/data/schema.json",
+	}
+	if names := sortedKeys(sdist); !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected sdist files\n got: %q\nwant: %q", names, want)
+	}
+	if sdist["my_pkg-1.2.0/PKG-INFO"] != metadata {
+		t.Errorf("PKG-INFO differs from METADATA")
+	}
+}
+
+func TestBuildPythonProject_Reproducible(t *testing.T) {
+	captureLogs(t)
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	dir := writePythonProject(t, strings.Replace(testPyproject, `dynamic = ["version"]`, `version = "1.0.0"`, 1))
+	project, err := loadPythonProject(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var builds [2][]string
+	for i := range builds {
+		if builds[i], err = buildPythonProject(project, t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range builds[0] {
+		first, _ := os.ReadFile(builds[0][i])
+		second, _ := os.ReadFile(builds[1][i])
+		if !bytes.Equal(first, second) {
+			t.Errorf("%s differs between builds", filepath.Base(builds[0][i]))
+		}
+	}
+}
+
+func TestLoadPythonProject_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		pyproject string
+		version   string
+		files     []string
+		err       string
+	}{
+		{name: "no project", pyproject: "[tool.black]\n", err: "has no [project] table"},
+		{name: "dynamic version", pyproject: testPyproject, err: "the version setting must be set"},
+		{name: "dynamic field", pyproject: strings.Replace(testPyproject, `["version"]`, `["version", "dependencies"]`, 1), version: "1.0", err: "field 'dependencies' is dynamic"},
+		{name: "non canonical version", pyproject: testPyproject, version: "1.0-beta", err: "must be a normalized PEP 440 version"},
+		{name: "invalid type", pyproject: strings.Replace(testPyproject, `description = "A package"`, "description = 1", 1), version: "1.0", err: "'description' must be a string"},
+		{name: "readme type", pyproject: strings.Replace(testPyproject, "README.md", "README", 1), version: "1.0", files: []string{"README"}, err: "unknown content type"},
+		{name: "syntax", pyproject: "[project\n", err: "invalid pyproject.toml"},
+	}
+	for _, test := range tests {
+		dir := writePythonProject(t, test.pyproject, test.files...)
+		if _, err := loadPythonProject(dir, test.version); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestBuildPythonProject_Errors(t *testing.T) {
+	captureLogs(t)
+	dir := writePythonProject(t, testPyproject, "src/my_pkg/_speedups.c")
+	project, err := loadPythonProject(dir, "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buildPythonProject(project, t.TempDir()); err == nil || !strings.Contains(err.Error(), "only pure-Python projects") {
+		t.Errorf("expected extension module error, got %v", err)
+	}
+
+	project.Name = "other"
+	if _, err := buildPythonProject(project, t.TempDir()); err == nil || !strings.Contains(err.Error(), "package 'other' not found") {
+		t.Errorf("expected missing package error, got %v", err)
+	}
+}
+
+func TestPythonHandler_PushProject(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writePythonProject(t, testPyproject))
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("python")
+
+	config := testConfig()
+	config.Source = "."
+	config.Name = "my-pkg"
+	config.Version = "v1.2.0"
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pushed []string
+	for _, args := range runner.args() {
+		pushed = append(pushed, filepath.Base(args[9]))
+	}
+	if want := []string{"my_pkg-1.2.0-py3-none-any.whl", "my_pkg-1.2.0.tar.gz"}; !reflect.DeepEqual(pushed, want) {
+		t.Errorf("unexpected pushes %q", pushed)
+	}
+	if result.Name != "My.Pkg" || result.Version != "1.2.0" || result.Metadata["PYTHON_DISTRIBUTIONS"] != "my_pkg-1.2.0-py3-none-any.whl,my_pkg-1.2.0.tar.gz" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxCoreMetadataSize limits the size of a METADATA or PKG-INFO file
+	maxCoreMetadataSize = 10 << 20
+
+	wheelExtension = ".whl"
+	sdistExtension = ".tar.gz"
+)
+
+var (
+	// pythonSeparatorPattern matches the separators folded by name normalization
+	pythonSeparatorPattern = regexp.MustCompile(`[-_.]+`)
+
+	// pythonNamePattern matches a valid project name, see PEP 508
+	pythonNamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+
+	// Tags of a wheel filename, each may be a '.' separated set of tags
+	wheelPythonTagPattern   = regexp.MustCompile(`^[a-z]+[0-9_]*$`)
+	wheelABITagPattern      = regexp.MustCompile(`^[a-z0-9_]+$`)
+	wheelPlatformTagPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// pythonMetadata holds the core metadata fields used by the plugin, read
+// from the METADATA file of a wheel or the PKG-INFO file of an sdist
+type pythonMetadata struct {
+	MetadataVersion string
+	Name            string
+	Version         string
+	Summary         string
+	RequiresPython  string
+}
+
+// pythonDistribution is a wheel or sdist of a Python project
+type pythonDistribution struct {
+	// File is the local path of the distribution
+	File string
+
+	// Kind is "wheel" or "sdist"
+	Kind string
+
+	Metadata *pythonMetadata
+
+	// Tags are the python, abi and platform tags of a wheel
+	Tags []string
+}
+
+// normalizePythonName returns the PEP 503 normalized form of a project name
+func normalizePythonName(name string) string {
+	return strings.ToLower(pythonSeparatorPattern.ReplaceAllString(name, "-"))
+}
+
+// escapePythonName returns the form of a project name used in distribution
+// filenames: normalized, with '_' as separator
+func escapePythonName(name string) string {
+	return strings.ToLower(pythonSeparatorPattern.ReplaceAllString(name, "_"))
+}
+
+// isPythonDistribution reports whether the file name looks like a wheel or an sdist
+func isPythonDistribution(name string) bool {
+	return strings.HasSuffix(name, wheelExtension) || strings.HasSuffix(name, sdistExtension)
+}
+
+// parseCoreMetadata reads the header fields of a core metadata file. The
+// format is RFC 822 like: "Key: value" lines, continuation lines start with
+// whitespace and the headers end at the first empty line.
+func parseCoreMetadata(r io.Reader) (*pythonMetadata, error) {
+	fields := map[string]string{}
+	var last string
+	scanner := bufio.NewScanner(io.LimitReader(r, maxCoreMetadataSize))
+	scanner.Buffer(make([]byte, 64*1024), maxCoreMetadataSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if last != "" {
+				fields[last] += "\n" + strings.TrimSpace(line)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid metadata line '%s'", line)
+		}
+		last = strings.ToLower(strings.TrimSpace(key))
+		// Only the first value of multiple use fields is kept
+		if _, seen := fields[last]; !seen {
+			fields[last] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	metadata := &pythonMetadata{
+		MetadataVersion: fields["metadata-version"],
+		Name:            fields["name"],
+		Version:         fields["version"],
+		Summary:         fields["summary"],
+		RequiresPython:  fields["requires-python"],
+	}
+	if metadata.Name == "" {
+		return nil, fmt.Errorf("metadata does not declare a Name")
+	}
+	if metadata.Version == "" {
+		return nil, fmt.Errorf("metadata does not declare a Version")
+	}
+	if !pythonNamePattern.MatchString(metadata.Name) {
+		return nil, fmt.Errorf("invalid project name '%s'", metadata.Name)
+	}
+	return metadata, nil
+}
+
+// readWheelMetadata reads <name>-<version>.dist-info/METADATA from a wheel
+func readWheelMetadata(file string) (*pythonMetadata, error) {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wheel '%s': %w", file, err)
+	}
+	defer archive.Close()
+
+	var found *zip.File
+	for _, entry := range archive.File {
+		dir, base := path.Split(entry.Name)
+		if base != "METADATA" || strings.Count(dir, "/") != 1 || !strings.HasSuffix(dir, ".dist-info/") {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("wheel '%s' contains more than one .dist-info directory", file)
+		}
+		found = entry
+	}
+	if found == nil {
+		return nil, fmt.Errorf("wheel '%s' does not contain a .dist-info/METADATA file", file)
+	}
+
+	r, err := found.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of wheel '%s': %w", found.Name, file, err)
+	}
+	defer r.Close()
+	metadata, err := parseCoreMetadata(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in wheel '%s': %w", found.Name, file, err)
+	}
+	return metadata, nil
+}
+
+// readSdistMetadata reads <name>-<version>/PKG-INFO from a gzipped sdist
+func readSdistMetadata(file string) (*pythonMetadata, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sdist '%s': %w", file, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("sdist '%s' is not gzip compressed: %w", file, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("sdist '%s' does not contain a PKG-INFO file", file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sdist '%s': %w", file, err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		dir, base := path.Split(name)
+		if header.Typeflag != tar.TypeReg || base != "PKG-INFO" || strings.Count(dir, "/") != 1 {
+			continue
+		}
+		metadata, err := parseCoreMetadata(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in sdist '%s': %w", name, file, err)
+		}
+		return metadata, nil
+	}
+}
+
+// inspectPythonDistribution reads the metadata of a wheel or sdist and
+// checks that its filename follows the naming conventions
+func inspectPythonDistribution(file string) (*pythonDistribution, error) {
+	name := filepath.Base(file)
+	dist := &pythonDistribution{File: file}
+	var err error
+	switch {
+	case strings.HasSuffix(name, wheelExtension):
+		dist.Kind = "wheel"
+		dist.Metadata, err = readWheelMetadata(file)
+	case strings.HasSuffix(name, sdistExtension):
+		dist.Kind = "sdist"
+		dist.Metadata, err = readSdistMetadata(file)
+	default:
+		return nil, fmt.Errorf("'%s' is not a wheel (.whl) or sdist (.tar.gz)", file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := dist.checkFilename(); err != nil {
+		return nil, fmt.Errorf("invalid filename '%s': %w", name, err)
+	}
+	return dist, nil
+}
+
+// checkFilename validates the filename against the metadata. Wheels are
+// named {name}-{version}(-{build})?-{python}-{abi}-{platform}.whl, sdists
+// {name}-{version}.tar.gz.
+func (d *pythonDistribution) checkFilename() error {
+	filename := filepath.Base(d.File)
+	version := d.Metadata.Version
+
+	var name string
+	if d.Kind == "sdist" {
+		var found bool
+		name, found = strings.CutSuffix(strings.TrimSuffix(filename, sdistExtension), "-"+version)
+		if !found {
+			return fmt.Errorf("does not end with the version %s of the metadata", version)
+		}
+	} else {
+		parts := strings.Split(strings.TrimSuffix(filename, wheelExtension), "-")
+		if len(parts) != 5 && len(parts) != 6 {
+			return fmt.Errorf("must have the form {name}-{version}(-{build tag})?-{python tag}-{abi tag}-{platform tag}.whl")
+		}
+		name = parts[0]
+		// Hyphens of a version are replaced by '_' in wheel filenames
+		if parts[1] != strings.ReplaceAll(version, "-", "_") {
+			return fmt.Errorf("version '%s' does not match the version %s of the metadata", parts[1], version)
+		}
+		if len(parts) == 6 && (parts[2] == "" || parts[2][0] < '0' || parts[2][0] > '9') {
+			return fmt.Errorf("build tag '%s' must start with a digit", parts[2])
+		}
+		d.Tags = parts[len(parts)-3:]
+		for i, pattern := range []*regexp.Regexp{wheelPythonTagPattern, wheelABITagPattern, wheelPlatformTagPattern} {
+			for _, tag := range strings.Split(d.Tags[i], ".") {
+				if !pattern.MatchString(tag) {
+					return fmt.Errorf("invalid %s tag '%s'", []string{"python", "abi", "platform"}[i], tag)
+				}
+			}
+		}
+	}
+
+	if normalizePythonName(name) != normalizePythonName(d.Metadata.Name) {
+		return fmt.Errorf("name '%s' does not match the name %s of the metadata", name, d.Metadata.Name)
+	}
+	return nil
+}
+
+// filenameWarnings returns conventions the filename does not follow but
+// which clients still accept
+func (d *pythonDistribution) filenameWarnings() []string {
+	var warnings []string
+	filename := filepath.Base(d.File)
+	if escaped := escapePythonName(d.Metadata.Name); !strings.HasPrefix(filename, escaped+"-") {
+		warnings = append(warnings, fmt.Sprintf("'%s' does not use the normalized name '%s'", filename, escaped))
+	}
+	if len(d.Tags) == 3 {
+		for _, platform := range strings.Split(d.Tags[2], ".") {
+			if strings.HasPrefix(platform, "linux_") {
+				warnings = append(warnings, fmt.Sprintf("'%s' has the platform tag '%s', use a manylinux or musllinux tag for wheels installed on other machines", filename, platform))
+			}
+		}
+	}
+	return warnings
+}
+
+// findPythonDistributions returns the wheels and sdists found in a
+// directory like dist/, sorted with sdists last
+func findPythonDistributions(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory '%s': %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isPythonDistribution(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no wheel (.whl) or sdist (.tar.gz) found in '%s'", dir)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		iWheel, jWheel := strings.HasSuffix(files[i], wheelExtension), strings.HasSuffix(files[j], wheelExtension)
+		if iWheel != jWheel {
+			return iWheel
+		}
+		return files[i] < files[j]
+	})
+	return files, nil
+}
+
+// inspectPythonDistributions inspects every distribution and checks that
+// they all belong to the same project version
+func inspectPythonDistributions(files []string) ([]*pythonDistribution, error) {
+	dists := make([]*pythonDistribution, 0, len(files))
+	for _, file := range files {
+		dist, err := inspectPythonDistribution(file)
+		if err != nil {
+			return nil, err
+		}
+		dists = append(dists, dist)
+	}
+
+	first := dists[0].Metadata
+	for _, dist := range dists[1:] {
+		if normalizePythonName(dist.Metadata.Name) != normalizePythonName(first.Name) || dist.Metadata.Version != first.Version {
+			return nil, fmt.Errorf("distributions belong to different projects: '%s' is %s %s, '%s' is %s %s",
+				filepath.Base(dists[0].File), first.Name, first.Version,
+				filepath.Base(dist.File), dist.Metadata.Name, dist.Metadata.Version)
+		}
+	}
+	return dists, nil
+}
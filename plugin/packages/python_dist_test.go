@@ -0,0 +1,207 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pythonMetadataFor returns core metadata declaring the name and version
+func pythonMetadataFor(name, version string) string {
+	return "Metadata-Version: 2.1\nName: " + name + "\nVersion: " + version + "\n"
+}
+
+// writeWheel writes a wheel whose dist-info directory is named after the
+// file and holds the given METADATA
+func writeWheel(t *testing.T, path, metadata string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	parts := strings.Split(filepath.Base(path), "-")
+	distInfo := parts[0] + "-" + parts[1] + ".dist-info/"
+	zw := zip.NewWriter(file)
+	for name, content := range map[string]string{
+		distInfo + "METADATA":     metadata,
+		distInfo + "WHEEL":        "Wheel-Version: 1.0\n",
+		parts[0] + "/__init__.py": "",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeSdist writes a gzipped sdist holding <root>/PKG-INFO
+func writeSdist(t *testing.T, path, root, metadata string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		root + "/PKG-INFO":       metadata,
+		root + "/pyproject.toml": "[project]\n",
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseCoreMetadata(t *testing.T) {
+	metadata, err := parseCoreMetadata(strings.NewReader("Metadata-Version: 2.1\r\n" +
+		"Name: My.Package\r\n" +
+		"Version: 1.0.0rc1\r\n" +
+		"Summary: A package\r\n" +
+		"Classifier: A\r\n" +
+		"Classifier: B\r\n" +
+		"License: first\r\n" +
+		"        second\r\n" +
+		"Requires-Python: >=3.8\r\n" +
+		"\r\n" +
+		"Name: not a header\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &pythonMetadata{MetadataVersion: "2.1", Name: "My.Package", Version: "1.0.0rc1", Summary: "A package", RequiresPython: ">=3.8"}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("got %+v, want %+v", metadata, want)
+	}
+
+	for input, want := range map[string]string{
+		"Version: 1.0\n":             "does not declare a Name",
+		"Name: pkg\n":                "does not declare a Version",
+		"Name: -pkg\nVersion: 1.0\n": "invalid project name",
+		"Name pkg\n":                 "invalid metadata line",
+	} {
+		if _, err := parseCoreMetadata(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestNormalizePythonName(t *testing.T) {
+	for name, want := range map[string][2]string{
+		"My.Package":  {"my-package", "my_package"},
+		"my__package": {"my-package", "my_package"},
+		"A-_.b":       {"a-b", "a_b"},
+	} {
+		if got := normalizePythonName(name); got != want[0] {
+			t.Errorf("normalizePythonName(%q) = %q, want %q", name, got, want[0])
+		}
+		if got := escapePythonName(name); got != want[1] {
+			t.Errorf("escapePythonName(%q) = %q, want %q", name, got, want[1])
+		}
+	}
+}
+
+func TestInspectPythonDistribution(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		filename string
+		name     string
+		version  string
+		err      string
+		warnings int
+	}{
+		{filename: "my_pkg-1.0.0-py3-none-any.whl", name: "my-pkg", version: "1.0.0"},
+		{filename: "my_pkg-1.0.0-1-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl", name: "My.Pkg", version: "1.0.0"},
+		{filename: "My.Pkg-1.0.0-py2.py3-none-any.whl", name: "my-pkg", version: "1.0.0", warnings: 1},
+		{filename: "my_pkg-1.0.0-cp311-cp311-linux_x86_64.whl", name: "my-pkg", version: "1.0.0", warnings: 1},
+		{filename: "my_pkg-1.0.0.tar.gz", name: "my-pkg", version: "1.0.0"},
+		{filename: "my-pkg-1.0.0.tar.gz", name: "my_pkg", version: "1.0.0", warnings: 1},
+		{filename: "other-1.0.0-py3-none-any.whl", name: "my-pkg", version: "1.0.0", err: "does not match the name"},
+		{filename: "my_pkg-1.0.1-py3-none-any.whl", name: "my-pkg", version: "1.0.0", err: "does not match the version"},
+		{filename: "my_pkg-1.0.0-py3-any.whl", name: "my-pkg", version: "1.0.0", err: "must have the form"},
+		{filename: "my_pkg-1.0.0-b1-py3-none-any.whl", name: "my-pkg", version: "1.0.0", err: "build tag"},
+		{filename: "my_pkg-1.0.0-py3-none-Any.whl", name: "my-pkg", version: "1.0.0", err: "invalid platform tag"},
+		{filename: "my_pkg-1.0.1.tar.gz", name: "my-pkg", version: "1.0.0", err: "does not end with the version"},
+	}
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			path := filepath.Join(dir, test.filename)
+			if strings.HasSuffix(path, ".whl") {
+				writeWheel(t, path, pythonMetadataFor(test.name, test.version))
+			} else {
+				writeSdist(t, path, strings.TrimSuffix(test.filename, ".tar.gz"), pythonMetadataFor(test.name, test.version))
+			}
+
+			dist, err := inspectPythonDistribution(path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dist.Metadata.Name != test.name || dist.Metadata.Version != test.version {
+				t.Errorf("unexpected metadata %+v", dist.Metadata)
+			}
+			if warnings := dist.filenameWarnings(); len(warnings) != test.warnings {
+				t.Errorf("expected %d warnings, got %q", test.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestInspectPythonDistribution_MissingMetadata(t *testing.T) {
+	dir := t.TempDir()
+	wheel := filepath.Join(dir, "pkg-1.0-py3-none-any.whl")
+	if err := os.WriteFile(wheel, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inspectPythonDistribution(wheel); err == nil || !strings.Contains(err.Error(), "failed to open wheel") {
+		t.Errorf("expected open error, got %v", err)
+	}
+
+	sdist := filepath.Join(dir, "pkg-1.0.tar.gz")
+	writeNPMTarball(t, sdist, "{}")
+	if _, err := inspectPythonDistribution(sdist); err == nil || !strings.Contains(err.Error(), "does not contain a PKG-INFO") {
+		t.Errorf("expected missing PKG-INFO error, got %v", err)
+	}
+
+	if _, err := inspectPythonDistribution(filepath.Join(dir, "pkg-1.0.egg")); err == nil || !strings.Contains(err.Error(), "is not a wheel") {
+		t.Errorf("expected unsupported file error, got %v", err)
+	}
+}
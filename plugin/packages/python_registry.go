@@ -0,0 +1,514 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxSimplePageSize limits the size of a simple index page
+const maxSimplePageSize = 32 << 20
+
+var (
+	// simpleAnchorPattern matches the links of a PEP 503 project page
+	simpleAnchorPattern = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a\s*>`)
+
+	// htmlAttributePattern matches an attribute of an HTML tag, the value
+	// is optional for boolean attributes like data-yanked
+	htmlAttributePattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9_:-]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+
+	// pythonVersionPattern matches a PEP 440 version in any of its
+	// accepted spellings, see the packaging library
+	pythonVersionPattern = regexp.MustCompile(`(?i)^\s*v?(?:(?:(?P<epoch>[0-9]+)!)?(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+		`(?:[-_.]?(?P<pre_l>alpha|beta|preview|pre|a|b|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+		`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+		`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?)(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?\s*$`)
+)
+
+// pythonFile is a distribution listed on the simple index page of a project
+type pythonFile struct {
+	Filename string `json:"filename"`
+	Version  string `json:"version"`
+
+	// Kind is "wheel" or "sdist"
+	Kind string `json:"kind"`
+
+	// Tags are the python, abi and platform tags of a wheel
+	Tags []string `json:"tags,omitempty"`
+
+	URL            string `json:"url"`
+	SHA256         string `json:"sha256,omitempty"`
+	RequiresPython string `json:"requires_python,omitempty"`
+	Yanked         bool   `json:"yanked,omitempty"`
+	YankedReason   string `json:"yanked_reason,omitempty"`
+}
+
+// pythonVersionSummary describes a version in the get output
+type pythonVersionSummary struct {
+	Version string       `json:"version"`
+	Files   []pythonFile `json:"files"`
+}
+
+// pythonProjectSummary is the JSON document printed by the Python get command
+type pythonProjectSummary struct {
+	Name     string                 `json:"name"`
+	Versions []pythonVersionSummary `json:"versions"`
+}
+
+// pythonIndexPath returns the path of the PEP 503 simple index page of a
+// project below the Python root
+func pythonIndexPath(name string) string {
+	return "simple/" + escapePath(normalizePythonName(name)) + "/"
+}
+
+// pythonIndexURL returns the URL of the PEP 503 simple index page of a project
+func pythonIndexURL(config Config, name string) string {
+	return endpointURL(config, Python, pythonIndexPath(name))
+}
+
+// pythonFiles lists the distributions of a project from its simple index
+// page. Links whose filename does not belong to the project are ignored.
+func pythonFiles(ctx context.Context, backend Backend, config Config, name string) ([]pythonFile, error) {
+	page, err := backend.Read(ctx, EndpointRequest{
+		PackageType: Python,
+		Config:      config,
+		Path:        pythonIndexPath(name),
+	})
+	var status *StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("project '%s' not found in registry '%s'", name, config.Registry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project '%s' from registry '%s': %w", name, config.Registry, err)
+	}
+	if len(page) > maxSimplePageSize {
+		return nil, fmt.Errorf("simple index page of project '%s' exceeds %d bytes", name, maxSimplePageSize)
+	}
+
+	links, err := parseSimplePage(pythonIndexURL(config, name), string(page))
+	if err != nil {
+		return nil, err
+	}
+	var files []pythonFile
+	for _, link := range links {
+		version, tags, ok := parsePythonFilename(name, link.Filename)
+		if !ok {
+			continue
+		}
+		link.Version = version
+		link.Tags = tags
+		link.Kind = "sdist"
+		if tags != nil {
+			link.Kind = "wheel"
+		}
+		files = append(files, link)
+	}
+	return files, nil
+}
+
+// parseSimplePage returns the links of a PEP 503 project page. The link
+// text is the filename, the URL fragment holds the hash of the file.
+func parseSimplePage(pageURL, page string) ([]pythonFile, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []pythonFile
+	for _, anchor := range simpleAnchorPattern.FindAllStringSubmatch(page, -1) {
+		attributes := map[string]string{}
+		for _, attribute := range htmlAttributePattern.FindAllStringSubmatch(anchor[1], -1) {
+			attributes[strings.ToLower(attribute[1])] = html.UnescapeString(attribute[2] + attribute[3] + attribute[4])
+		}
+		href, ok := attributes["href"]
+		if !ok {
+			continue
+		}
+		link, err := base.Parse(href)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link '%s' on simple index page: %w", href, err)
+		}
+
+		file := pythonFile{
+			Filename:       strings.TrimSpace(html.UnescapeString(anchor[2])),
+			RequiresPython: attributes["data-requires-python"],
+		}
+		if file.Filename == "" {
+			file.Filename = path.Base(link.Path)
+		}
+		if algorithm, digest, ok := strings.Cut(link.Fragment, "="); ok && algorithm == "sha256" {
+			file.SHA256 = strings.ToLower(digest)
+		}
+		if reason, ok := attributes["data-yanked"]; ok {
+			file.Yanked = true
+			file.YankedReason = reason
+		}
+		link.Fragment = ""
+		file.URL = link.String()
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// parsePythonFilename returns the version of a distribution of the project
+// and the tags of a wheel, ok is false when the file is not a distribution
+// of the project
+func parsePythonFilename(project, filename string) (version string, tags []string, ok bool) {
+	name := normalizePythonName(project)
+	if base, found := strings.CutSuffix(filename, wheelExtension); found {
+		parts := strings.Split(base, "-")
+		if (len(parts) != 5 && len(parts) != 6) || normalizePythonName(parts[0]) != name {
+			return "", nil, false
+		}
+		return parts[1], parts[len(parts)-3:], true
+	}
+
+	// The name of an sdist may hold hyphens, as may legacy versions
+	base, found := strings.CutSuffix(filename, sdistExtension)
+	if !found {
+		if base, found = strings.CutSuffix(filename, ".zip"); !found {
+			return "", nil, false
+		}
+	}
+	for i := range base {
+		if base[i] == '-' && normalizePythonName(base[:i]) == name {
+			return base[i+1:], nil, true
+		}
+	}
+	return "", nil, false
+}
+
+// matchesTags reports whether the wheel is compatible with a
+// {python}-{abi}-{platform} tag. The wheel tags may be compressed tag sets
+// like py2.py3, the tag components may hold shell patterns like cp3*.
+func (f *pythonFile) matchesTags(tag string) bool {
+	want := strings.Split(tag, "-")
+	if len(f.Tags) != 3 || len(want) != 3 {
+		return false
+	}
+	for i, set := range f.Tags {
+		found := false
+		for _, value := range strings.Split(set, ".") {
+			if matched, _ := path.Match(want[i], value); matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// selectPythonFile picks the distribution of a version to download: the
+// first wheel compatible with a tag of the preference list, in order, or
+// the sdist. "sdist" may be listed to prefer it over later tags. Pure
+// Python wheels are preferred when no tags are set. Among wheels matching
+// the same tag, the first one listed wins.
+func selectPythonFile(files []pythonFile, tags []string) (*pythonFile, error) {
+	if len(tags) == 0 {
+		tags = []string{"py3-none-any", "*-none-any"}
+	}
+
+	var sdist *pythonFile
+	for i := range files {
+		if files[i].Kind == "sdist" && sdist == nil {
+			sdist = &files[i]
+		}
+	}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "sdist" {
+			if sdist != nil {
+				return sdist, nil
+			}
+			continue
+		}
+		if strings.Count(tag, "-") != 2 {
+			return nil, fmt.Errorf("invalid tag '%s' in python_tags, expected {python}-{abi}-{platform} or sdist", tag)
+		}
+		for i := range files {
+			if files[i].matchesTags(tag) {
+				return &files[i], nil
+			}
+		}
+	}
+	if sdist != nil {
+		return sdist, nil
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Filename)
+	}
+	return nil, fmt.Errorf("no distribution matches the tags %s, available: %s", strings.Join(tags, ", "), strings.Join(names, ", "))
+}
+
+// pythonVersion is a parsed PEP 440 version
+type pythonVersion struct {
+	epoch   int
+	release []int
+
+	// pre is the pre-release phase, a = 0, b = 1, rc = 2, and its number
+	pre    [2]int
+	hasPre bool
+
+	// post and dev are -1 when not set
+	post  int
+	dev   int
+	local []string
+}
+
+// parsePythonVersion parses a version in any of its PEP 440 spellings
+func parsePythonVersion(version string) (*pythonVersion, bool) {
+	match := pythonVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return nil, false
+	}
+	group := func(name string) string {
+		return match[pythonVersionPattern.SubexpIndex(name)]
+	}
+	number := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+
+	v := &pythonVersion{epoch: number(group("epoch")), post: -1, dev: -1}
+	for _, part := range strings.Split(group("release"), ".") {
+		v.release = append(v.release, number(part))
+	}
+	if phase := strings.ToLower(group("pre_l")); phase != "" {
+		v.hasPre = true
+		switch phase {
+		case "a", "alpha":
+			v.pre[0] = 0
+		case "b", "beta":
+			v.pre[0] = 1
+		default:
+			v.pre[0] = 2
+		}
+		v.pre[1] = number(group("pre_n"))
+	}
+	if group("post_n1") != "" || group("post_l") != "" {
+		v.post = number(group("post_n1") + group("post_n2"))
+	}
+	if group("dev_l") != "" {
+		v.dev = number(group("dev_n"))
+	}
+	if local := group("local"); local != "" {
+		v.local = strings.FieldsFunc(strings.ToLower(local), func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+	return v, true
+}
+
+// String returns the normalized spelling of the version, e.g. 1.0rc1 for
+// 1.0-RC1
+func (v *pythonVersion) String() string {
+	var b strings.Builder
+	if v.epoch != 0 {
+		fmt.Fprintf(&b, "%d!", v.epoch)
+	}
+	for i, n := range v.release {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(strconv.Itoa(n))
+	}
+	if v.hasPre {
+		fmt.Fprintf(&b, "%s%d", [...]string{"a", "b", "rc"}[v.pre[0]], v.pre[1])
+	}
+	if v.post >= 0 {
+		fmt.Fprintf(&b, ".post%d", v.post)
+	}
+	if v.dev >= 0 {
+		fmt.Fprintf(&b, ".dev%d", v.dev)
+	}
+	if len(v.local) > 0 {
+		b.WriteString("+" + strings.Join(v.local, "."))
+	}
+	return b.String()
+}
+
+// pythonVersionKey returns the key distributions are grouped by, equal
+// versions like 1.0 and 1.0.0 share a key. Invalid versions are their own key.
+func pythonVersionKey(version string) string {
+	v, ok := parsePythonVersion(version)
+	if !ok {
+		return version
+	}
+	for len(v.release) > 1 && v.release[len(v.release)-1] == 0 {
+		v.release = v.release[:len(v.release)-1]
+	}
+	for i, segment := range v.local {
+		if n, err := strconv.Atoi(segment); err == nil {
+			v.local[i] = strconv.Itoa(n)
+		}
+	}
+	return v.String()
+}
+
+// isPrerelease reports whether the version is a pre-release or a
+// development release
+func (v *pythonVersion) isPrerelease() bool {
+	return v.hasPre || v.dev >= 0
+}
+
+// comparePythonVersions compares two versions following the PEP 440
+// ordering. Invalid versions sort before valid ones, in string order.
+func comparePythonVersions(a, b string) int {
+	va, okA := parsePythonVersion(a)
+	vb, okB := parsePythonVersion(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+
+	if c := compareInt(va.epoch, vb.epoch); c != 0 {
+		return c
+	}
+	for i := 0; i < len(va.release) || i < len(vb.release); i++ {
+		var x, y int
+		if i < len(va.release) {
+			x = va.release[i]
+		}
+		if i < len(vb.release) {
+			y = vb.release[i]
+		}
+		if c := compareInt(x, y); c != 0 {
+			return c
+		}
+	}
+	if c := compareInt(va.phaseKey(), vb.phaseKey()); c != 0 {
+		return c
+	}
+	if va.hasPre && vb.hasPre {
+		if c := compareInt(va.pre[1], vb.pre[1]); c != 0 {
+			return c
+		}
+	}
+	if c := compareInt(va.post, vb.post); c != 0 {
+		return c
+	}
+	// A development release sorts before the release it leads to
+	if c := compareInt(devKey(va.dev), devKey(vb.dev)); c != 0 {
+		return c
+	}
+	return compareLocalVersions(va.local, vb.local)
+}
+
+// phaseKey orders versions of the same release: development releases of
+// the release first, then pre-releases by phase, then the release itself
+func (v *pythonVersion) phaseKey() int {
+	switch {
+	case v.hasPre:
+		return v.pre[0]
+	case v.dev >= 0 && v.post < 0:
+		return -1
+	}
+	return 3
+}
+
+func devKey(dev int) int {
+	if dev < 0 {
+		return int(^uint(0) >> 1)
+	}
+	return dev
+}
+
+// compareLocalVersions compares local version labels segment by segment,
+// numeric segments sort after alphanumeric ones
+func compareLocalVersions(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		numA, errA := strconv.Atoi(a[i])
+		numB, errB := strconv.Atoi(b[i])
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareInt(numA, numB)
+		case errA == nil:
+			c = 1
+		case errB == nil:
+			c = -1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a), len(b))
+}
+
+// pythonSummary groups the distributions by version, oldest first, or
+// keeps a single version when one is set. Files of equal versions spelled
+// differently, like 1.0 and 1.0.0, are listed under the normalized spelling
+// of the first one.
+func pythonSummary(name string, files []pythonFile, version string) (*pythonProjectSummary, error) {
+	summary := &pythonProjectSummary{Name: normalizePythonName(name), Versions: []pythonVersionSummary{}}
+	byVersion := map[string]int{}
+	for _, file := range files {
+		if version != "" && comparePythonVersions(file.Version, version) != 0 {
+			continue
+		}
+		key := pythonVersionKey(file.Version)
+		i, ok := byVersion[key]
+		if !ok {
+			i = len(summary.Versions)
+			byVersion[key] = i
+			spelling := file.Version
+			if v, ok := parsePythonVersion(file.Version); ok {
+				spelling = v.String()
+			}
+			summary.Versions = append(summary.Versions, pythonVersionSummary{Version: spelling})
+		}
+		summary.Versions[i].Files = append(summary.Versions[i].Files, file)
+	}
+	if version != "" && len(summary.Versions) == 0 {
+		return nil, fmt.Errorf("version '%s' of project '%s' not found", version, name)
+	}
+
+	sort.Slice(summary.Versions, func(i, j int) bool {
+		return comparePythonVersions(summary.Versions[i].Version, summary.Versions[j].Version) < 0
+	})
+	for _, entry := range summary.Versions {
+		sort.Slice(entry.Files, func(i, j int) bool { return entry.Files[i].Filename < entry.Files[j].Filename })
+	}
+	return summary, nil
+}
+
+// latestVersion returns the latest version that is neither a pre-release
+// nor fully yanked, or the latest version when there is none
+func (s *pythonProjectSummary) latestVersion() string {
+	if len(s.Versions) == 0 {
+		return ""
+	}
+	for i := len(s.Versions) - 1; i >= 0; i-- {
+		entry := s.Versions[i]
+		if v, ok := parsePythonVersion(entry.Version); !ok || v.isPrerelease() {
+			continue
+		}
+		for _, file := range entry.Files {
+			if !file.Yanked {
+				return entry.Version
+			}
+		}
+	}
+	return s.Versions[len(s.Versions)-1].Version
+}
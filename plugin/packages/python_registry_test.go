@@ -0,0 +1,318 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSimplePage(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><body>
+<h1>Links for my-pkg</h1>
+<a href="../../files/my-pkg/1.0.0/my_pkg-1.0.0-py3-none-any.whl#sha256=ABC" data-requires-python="&gt;=3.8">my_pkg-1.0.0-py3-none-any.whl</a><br/>
+<A HREF='https://mirror.example.com/my_pkg-0.9.tar.gz' data-yanked="broken build">my_pkg-0.9.tar.gz</A>
+<a href="/files/my-pkg/0.8/my-pkg-0.8.zip" data-yanked></a>
+<a name="anchor">no link</a>
+</body></html>`
+	files, err := parseSimplePage("https://pkg.harness.io/pkg/acct/reg/python/simple/my-pkg/", page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pythonFile{
+		{
+			Filename:       "my_pkg-1.0.0-py3-none-any.whl",
+			URL:            "https://pkg.harness.io/pkg/acct/reg/python/files/my-pkg/1.0.0/my_pkg-1.0.0-py3-none-any.whl",
+			SHA256:         "abc",
+			RequiresPython: ">=3.8",
+		},
+		{Filename: "my_pkg-0.9.tar.gz", URL: "https://mirror.example.com/my_pkg-0.9.tar.gz", Yanked: true, YankedReason: "broken build"},
+		{Filename: "my-pkg-0.8.zip", URL: "https://pkg.harness.io/files/my-pkg/0.8/my-pkg-0.8.zip", Yanked: true},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("unexpected files\n got: %+v\nwant: %+v", files, want)
+	}
+}
+
+func TestParsePythonFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		version  string
+		tags     []string
+		ok       bool
+	}{
+		{filename: "my_pkg-1.0.0-py3-none-any.whl", version: "1.0.0", tags: []string{"py3", "none", "any"}, ok: true},
+		{filename: "My.Pkg-1.0.0-1-cp311-cp311-win_amd64.whl", version: "1.0.0", tags: []string{"cp311", "cp311", "win_amd64"}, ok: true},
+		{filename: "my-pkg-1.0.0.tar.gz", version: "1.0.0", ok: true},
+		{filename: "my_pkg-1.0.0rc1.zip", version: "1.0.0rc1", ok: true},
+		{filename: "my_pkg_extra-1.0.0.tar.gz"},
+		{filename: "my_pkg-1.0.0-py3-any.whl"},
+		{filename: "my_pkg-1.0.0.egg"},
+	}
+	for _, test := range tests {
+		version, tags, ok := parsePythonFilename("My-Pkg", test.filename)
+		if version != test.version || !reflect.DeepEqual(tags, test.tags) || ok != test.ok {
+			t.Errorf("parsePythonFilename(%q) = %q, %q, %v", test.filename, version, tags, ok)
+		}
+	}
+}
+
+func TestComparePythonVersions(t *testing.T) {
+	// Ordered following PEP 440
+	ordered := []string{
+		"1.0.dev456", "1.0a1", "1.0a2.dev456", "1.0a12.dev456", "1.0a12", "1.0b1.dev456", "1.0b2",
+		"1.0b2.post345.dev456", "1.0b2.post345", "1.0rc1.dev456", "1.0rc1", "1.0", "1.0+abc.5", "1.0+abc.7",
+		"1.0+5", "1.0.post456.dev34", "1.0.post456", "1.0.15", "1.1.dev1", "1!0.1",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := compareInt(i, j)
+			if got := comparePythonVersions(ordered[i], ordered[j]); got != want {
+				t.Errorf("comparePythonVersions(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+	for a, b := range map[string]string{"1.0": "1.0.0", "1.0-r4": "1.0.post4", "1.0alpha": "1.0a0", "v1.0c1": "1.0rc1"} {
+		if comparePythonVersions(a, b) != 0 {
+			t.Errorf("%q and %q must be equal", a, b)
+		}
+	}
+}
+
+func TestSelectPythonFile(t *testing.T) {
+	files := []pythonFile{
+		{Filename: "pkg-1.0.tar.gz", Kind: "sdist"},
+		{Filename: "pkg-1.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl", Kind: "wheel", Tags: []string{"cp311", "cp311", "manylinux_2_17_x86_64.manylinux2014_x86_64"}},
+		{Filename: "pkg-1.0-cp311-cp311-win_amd64.whl", Kind: "wheel", Tags: []string{"cp311", "cp311", "win_amd64"}},
+		{Filename: "pkg-1.0-py2.py3-none-any.whl", Kind: "wheel", Tags: []string{"py2.py3", "none", "any"}},
+	}
+	tests := []struct {
+		tags []string
+		want string
+		err  string
+	}{
+		{want: "pkg-1.0-py2.py3-none-any.whl"},
+		{tags: []string{"cp311-cp311-manylinux2014_x86_64", "py3-none-any"}, want: "pkg-1.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl"},
+		{tags: []string{"cp312-cp312-win_amd64", "cp311-*-win*"}, want: "pkg-1.0-cp311-cp311-win_amd64.whl"},
+		{tags: []string{"sdist", "py3-none-any"}, want: "pkg-1.0.tar.gz"},
+		{tags: []string{"cp312-cp312-macosx_11_0_arm64"}, want: "pkg-1.0.tar.gz"},
+		{tags: []string{"cp311"}, err: "invalid tag 'cp311'"},
+	}
+	for _, test := range tests {
+		file, err := selectPythonFile(files, test.tags)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: expected error containing %q, got %v", test.tags, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Filename != test.want {
+			t.Errorf("%q: selected %s, want %s", test.tags, file.Filename, test.want)
+		}
+	}
+
+	if _, err := selectPythonFile(files[1:3:3], nil); err == nil || !strings.Contains(err.Error(), "no distribution matches the tags py3-none-any, *-none-any") {
+		t.Errorf("expected no match error, got %v", err)
+	}
+}
+
+// newSimpleIndex serves the simple index page of my-pkg listing the files,
+// each linked with the SHA-256 checksum of the wheel
+func newSimpleIndex(t *testing.T, sum string, files ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pkg/acct/python-local/python/simple/my-pkg/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, "<html><body>")
+		for _, file := range files {
+			version, _, _ := parsePythonFilename("my-pkg", file)
+			fmt.Fprintf(w, "<a href=\"../../files/my-pkg/%s/%s#sha256=%s\">%s</a>\n", version, file, sum, file)
+		}
+		fmt.Fprintln(w, "</body></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPythonHandler_Pull(t *testing.T) {
+	captureLogs(t)
+	wheel := filepath.Join(t.TempDir(), "my_pkg-1.0.0-py3-none-any.whl")
+	writeWheel(t, wheel, pythonMetadataFor("My.Pkg", "1.0.0"))
+	data, err := os.ReadFile(wheel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	server := newSimpleIndex(t, hex.EncodeToString(sum[:]),
+		"my_pkg-1.0.0-py3-none-any.whl", "my_pkg-1.0.0.tar.gz", "my_pkg-2.0.0-py3-none-any.whl")
+
+	destination := t.TempDir()
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		// Fake the download, only the wheel has the listed checksum
+		content := data
+		if strings.HasSuffix(cmd.Args[5], sdistExtension) {
+			content = []byte("corrupted")
+		}
+		return os.WriteFile(filepath.Join(destination, filepath.Base(cmd.Args[5])), content, 0644)
+	}
+	handler, _ := factory.GetHandler("python")
+
+	config := testConfig()
+	config.Account = "acct"
+	config.Registry = "python-local"
+	config.PkgURL = server.URL
+	config.Name = "My_Pkg"
+	config.Version = "v1.0"
+	config.Destination = destination
+
+	result, err := handler.Pull(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := runner.args()
+	if len(args) != 1 || args[0][3] != "PYTHON" || args[0][5] != "files/my-pkg/1.0.0/my_pkg-1.0.0-py3-none-any.whl" {
+		t.Errorf("unexpected commands %q", args)
+	}
+	if result.Path != filepath.Join(destination, "my_pkg-1.0.0-py3-none-any.whl") || result.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.Metadata["PYTHON_PACKAGE_NAME"] != "My.Pkg" || result.Metadata["PYTHON_PACKAGE_VERSION"] != "1.0.0" {
+		t.Errorf("unexpected outputs %v", result.Metadata)
+	}
+
+	// The sdist listed with the hash of the wheel does not verify
+	config.Filename = "my_pkg-1.0.0.tar.gz"
+	if _, err := handler.Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "my_pkg-1.0.0.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("expected mismatching file to be removed, got %v", err)
+	}
+
+	config.Filename = ""
+	config.Version = "3.0"
+	if _, err := handler.Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "version '3.0' of project 'My_Pkg' not found") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+	config.Name = "other"
+	if _, err := handler.Pull(context.Background(), config); err == nil || !strings.Contains(err.Error(), "project 'other' not found") {
+		t.Errorf("expected missing project error, got %v", err)
+	}
+}
+
+func TestPythonHandler_Get(t *testing.T) {
+	captureLogs(t)
+	server := newSimpleIndex(t, "abc", "my_pkg-1.10.0.tar.gz", "my_pkg-1.9.0-py3-none-any.whl",
+		"my_pkg-1.9.0.tar.gz", "my_pkg-2.0.0rc1.tar.gz", "other-1.0.tar.gz")
+	config := Config{
+		Token:    "pat.test",
+		Account:  "acct",
+		Registry: "python-local",
+		PkgURL:   server.URL,
+		Name:     "My.Pkg",
+	}
+	handler := NewPythonHandler(NewHTTPBackend(server.Client()))
+
+	result, err := handler.Get(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary pythonProjectSummary
+	if err := json.Unmarshal(result.Raw, &summary); err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, entry := range summary.Versions {
+		versions = append(versions, entry.Version)
+	}
+	if want := []string{"1.9.0", "1.10.0", "2.0.0rc1"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("unexpected versions %v, want %v", versions, want)
+	}
+	if files := summary.Versions[0].Files; len(files) != 2 || files[0].Kind != "wheel" || files[0].SHA256 != "abc" {
+		t.Errorf("unexpected files %+v", files)
+	}
+	outputs := map[string]string{"PYTHON_PACKAGE_NAME": "my-pkg", "PYTHON_VERSION_COUNT": "3", "PYTHON_LATEST_VERSION": "1.10.0"}
+	if result.Version != "1.10.0" || !reflect.DeepEqual(result.Metadata, outputs) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	config.Version = "2.0.0rc1"
+	if result, err = handler.Get(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "2.0.0rc1" || result.Metadata["PYTHON_VERSION_COUNT"] != "1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestPythonSummary_EquivalentVersions(t *testing.T) {
+	files := []pythonFile{
+		{Filename: "my_pkg-1.0.tar.gz", Version: "1.0"},
+		{Filename: "my_pkg-1.0.0-py3-none-any.whl", Version: "1.0.0"},
+		{Filename: "my_pkg-1.0.0.post0.tar.gz", Version: "1.0.0.post0"},
+		{Filename: "my_pkg-1.1-RC1.tar.gz", Version: "1.1-RC1"},
+		{Filename: "my_pkg-1.1.0rc1-py3-none-any.whl", Version: "1.1.0rc1"},
+	}
+	summary, err := pythonSummary("my-pkg", files, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Versions) != 1 || summary.Versions[0].Version != "1.0" || len(summary.Versions[0].Files) != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	if summary, err = pythonSummary("my-pkg", files, ""); err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, entry := range summary.Versions {
+		versions = append(versions, fmt.Sprintf("%s:%d", entry.Version, len(entry.Files)))
+	}
+	if want := []string{"1.0:2", "1.0.0.post0:1", "1.1rc1:2"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("unexpected versions %v, want %v", versions, want)
+	}
+}
+
+func TestCLIBackend_PythonDelete(t *testing.T) {
+	captureLogs(t)
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("python")
+
+	config := testConfig()
+	config.Name = "My.Pkg"
+	config.Version = "v1.0.0"
+	if _, err := handler.Delete(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{getHarnessBin(), "artifact", "delete", "my-pkg",
+		"--registry", "test-registry", "--token", "test-token", "--account", "test-account",
+		"--org", "test-org", "--project", "test-project", "--api-url", "https://app.harness.io",
+		"--version", "1.0.0", "--format", "json"}
+	if got := runner.args(); len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("unexpected commands %q", got)
+	}
+
+	config.Version = ""
+	if _, err := handler.Delete(context.Background(), config); err == nil || !strings.Contains(err.Error(), "package version must be set") {
+		t.Errorf("expected missing version error, got %v", err)
+	}
+}
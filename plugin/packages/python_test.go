@@ -0,0 +1,118 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPythonHandler_PushDirectory(t *testing.T) {
+	captureLogs(t)
+	root := writeTree(t, "dist/README.txt")
+	t.Chdir(root)
+	metadata := pythonMetadataFor("My.Pkg", "2.0.0")
+	writeWheel(t, "dist/my_pkg-2.0.0-py3-none-any.whl", metadata)
+	writeWheel(t, "dist/my_pkg-2.0.0-cp311-cp311-win_amd64.whl", metadata)
+	writeSdist(t, "dist/my_pkg-2.0.0.tar.gz", "my_pkg-2.0.0", metadata)
+
+	factory, runner := newTestFactory(t)
+	handler, _ := factory.GetHandler("python")
+
+	config := testConfig()
+	config.Source = "dist"
+	config.Name = "my-pkg"
+	config.Version = "2.0.0"
+
+	result, err := handler.Push(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pushed []string
+	for _, args := range runner.args() {
+		pushed = append(pushed, args[9])
+	}
+	want := []string{
+		filepath.Join("dist", "my_pkg-2.0.0-cp311-cp311-win_amd64.whl"),
+		filepath.Join("dist", "my_pkg-2.0.0-py3-none-any.whl"),
+		filepath.Join("dist", "my_pkg-2.0.0.tar.gz"),
+	}
+	if !reflect.DeepEqual(pushed, want) {
+		t.Errorf("unexpected pushes\n got: %q\nwant: %q", pushed, want)
+	}
+
+	outputs := map[string]string{
+		"PYTHON_PACKAGE_NAME":    "My.Pkg",
+		"PYTHON_PACKAGE_VERSION": "2.0.0",
+		"PYTHON_DISTRIBUTIONS":   "my_pkg-2.0.0-cp311-cp311-win_amd64.whl,my_pkg-2.0.0-py3-none-any.whl,my_pkg-2.0.0.tar.gz",
+	}
+	if !reflect.DeepEqual(result.Metadata, outputs) {
+		t.Errorf("unexpected outputs %v", result.Metadata)
+	}
+	if len(result.Files) != 3 {
+		t.Errorf("expected 3 file results, got %d", len(result.Files))
+	}
+}
+
+func TestPythonHandler_PushErrors(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "empty/README.txt"))
+	writeWheel(t, "mixed/pkg-1.0-py3-none-any.whl", pythonMetadataFor("pkg", "1.0"))
+	writeSdist(t, "mixed/pkg-1.1.tar.gz", "pkg-1.1", pythonMetadataFor("pkg", "1.1"))
+	writeWheel(t, "single/pkg-1.0-py3-none-any.whl", pythonMetadataFor("pkg", "1.0"))
+
+	tests := []struct {
+		source, name, version string
+		err                   string
+	}{
+		{source: "empty", err: "no wheel (.whl) or sdist (.tar.gz) found"},
+		{source: "mixed", err: "distributions belong to different projects"},
+		{source: "single", name: "other", err: "name 'other' does not match"},
+		{source: "single", version: "2.0", err: "version '2.0' does not match"},
+		{source: "missing.whl", err: "failed to access source"},
+	}
+	for _, test := range tests {
+		factory, runner := newTestFactory(t)
+		handler, _ := factory.GetHandler("python")
+
+		config := testConfig()
+		config.Source = test.source
+		config.Name = test.name
+		config.Version = test.version
+
+		if _, err := handler.Push(context.Background(), config); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %v", test.source, test.err, err)
+		}
+		if len(runner.args()) != 0 {
+			t.Errorf("%s: unexpected pushes %v", test.source, runner.args())
+		}
+	}
+}
+
+func TestCheckPythonCoordinates(t *testing.T) {
+	tests := []struct {
+		version, metadata string
+		ok                bool
+	}{
+		{"1.0", "1.0.0", true},
+		{"v1.0.0", "1.0.0", true},
+		{"1.0.0-rc1", "1.0.0rc1", true},
+		{"1.0.0.post1", "1.0.0-1", true},
+		{"1.0.0.dev0", "1.0.0dev", true},
+		{"1.0.1", "1.0.0", false},
+		{"1.0.0rc1", "1.0.0", false},
+		{"1.0.0+local", "1.0.0", false},
+	}
+	for _, test := range tests {
+		err := checkPythonCoordinates(Config{Version: test.version}, &pythonMetadata{Name: "pkg", Version: test.metadata})
+		if (err == nil) != test.ok {
+			t.Errorf("version %q against %q: unexpected result %v", test.version, test.metadata, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces every secret in logged output
+const redactedValue = "********"
+
+// secretFlags lists command line flags whose value is a secret
+var secretFlags = map[string]bool{
+	"--token":    true,
+	"--api-key":  true,
+	"--password": true,
+	"--secret":   true,
+}
+
+// secretPattern matches authentication headers and key/value pairs holding
+// credentials, keeping the key and the authentication scheme readable
+var secretPattern = regexp.MustCompile(`(?i)((?:authorization|x-api-key|token|api[_-]?key|password|secret)["']?\s*[:=]\s*["']?(?:(?:bearer|basic|cimanager|apikey)\s+)?)[^\s"',;&]+`)
+
+// Redactor masks secrets in command lines and output
+type Redactor struct {
+	secrets []string
+}
+
+// newRedactor creates a redactor for the secret values of the configuration
+func newRedactor(config Config) *Redactor {
+	var secrets []string
+	for _, secret := range append([]string{config.Token}, config.Secrets...) {
+		// Very short values would mask unrelated text
+		if secret = strings.TrimSpace(secret); len(secret) >= 4 {
+			secrets = append(secrets, secret)
+		}
+	}
+	// Mask longer secrets first in case one secret contains another
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return &Redactor{secrets: secrets}
+}
+
+// Args returns a copy of the command line with secret values masked
+func (r *Redactor) Args(cmdArgs []string) []string {
+	redacted := make([]string, len(cmdArgs))
+	for i, arg := range cmdArgs {
+		switch {
+		case i > 0 && secretFlags[cmdArgs[i-1]]:
+			redacted[i] = redactedValue
+		case strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") && secretFlags[arg[:strings.Index(arg, "=")]]:
+			redacted[i] = arg[:strings.Index(arg, "=")+1] + redactedValue
+		default:
+			redacted[i] = r.String(arg)
+		}
+	}
+	return redacted
+}
+
+// String masks configured secrets, authentication headers and credential
+// key/value pairs in s
+func (r *Redactor) String(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return secretPattern.ReplaceAllString(s, "${1}"+redactedValue)
+}
+
+// Writer returns a writer that masks secrets line by line before passing
+// the output to w. Close must be called to flush an unterminated last line.
+func (r *Redactor) Writer(w io.Writer) io.WriteCloser {
+	return &redactWriter{redactor: r, out: w}
+}
+
+// redactWriter buffers partial lines so secrets split across writes are masked
+type redactWriter struct {
+	mu       sync.Mutex
+	redactor *Redactor
+	out      io.Writer
+	buf      []byte
+}
+
+func (w *redactWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.redactor.String(string(w.buf[:i+1]))
+		w.buf = w.buf[i+1:]
+		if _, err := io.WriteString(w.out, line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *redactWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.out, w.redactor.String(string(w.buf)))
+	w.buf = nil
+	return err
+}
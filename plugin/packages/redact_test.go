@@ -0,0 +1,132 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedactor_Args(t *testing.T) {
+	redactor := newRedactor(Config{Token: "pat.secret-value", Secrets: []string{"acct-secret"}})
+	got := redactor.Args([]string{
+		"hc", "artifact", "get", "app",
+		"--token", "anything",
+		"--api-key=other",
+		"--account", "acct-secret",
+		"--registry", "reg",
+	})
+	want := []string{
+		"hc", "artifact", "get", "app",
+		"--token", redactedValue,
+		"--api-key=" + redactedValue,
+		"--account", redactedValue,
+		"--registry", "reg",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %q, want %q", got, want)
+	}
+}
+
+func TestRedactor_String(t *testing.T) {
+	redactor := newRedactor(Config{Token: "pat.secret-value"})
+	tests := map[string]string{
+		"Authorization: CIManager abc.def":         "Authorization: CIManager " + redactedValue,
+		"authorization=Bearer xyz":                 "authorization=Bearer " + redactedValue,
+		"x-api-key: pat.other":                     "x-api-key: " + redactedValue,
+		`{"token": "hidden", "name": "app"}`:       `{"token": "` + redactedValue + `", "name": "app"}`,
+		"using pat.secret-value for upload":        "using " + redactedValue + " for upload",
+		"no secrets here, just a tokenizer remark": "no secrets here, just a tokenizer remark",
+	}
+	for input, want := range tests {
+		if got := redactor.String(input); got != want {
+			t.Errorf("String(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRedactor_WriterSplitWrites(t *testing.T) {
+	var out bytes.Buffer
+	w := newRedactor(Config{Token: "pat.secret-value"}).Writer(&out)
+	io.WriteString(w, "token in pat.sec")
+	io.WriteString(w, "ret-value two parts\nlast line pat.secret-value")
+	w.Close()
+
+	if strings.Contains(out.String(), "secret-value") {
+		t.Errorf("secret leaked through writer: %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "last line "+redactedValue) {
+		t.Errorf("unterminated line was not flushed: %q", out.String())
+	}
+}
+
+func TestCLIBackend_NoSecretsOnStdout(t *testing.T) {
+	const token = "pat.super-secret-token"
+	const account = "secret-account-id"
+
+	// Capture everything written to stdout and the logs, with tracing enabled
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	logs := captureLogs(t)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.TraceLevel)
+	defer logrus.SetLevel(level)
+
+	factory, runner := newTestFactory(t)
+	runner.run = func(cmd Command) error {
+		// Simulate a CLI that echoes its credentials
+		fmt.Fprintf(cmd.Stdout, "debug: sending Authorization: CIManager %s\n", token)
+		fmt.Fprintf(cmd.Stderr, "account %s rejected token %s", account, token)
+		return nil
+	}
+
+	config := testConfig()
+	config.Token = token
+	config.Account = account
+	config.Secrets = []string{account}
+	config.Filename = "artifact.bin"
+	config.Destination = "downloads"
+
+	handler, _ := factory.GetHandler("generic")
+	for _, op := range []func(context.Context, Config) (*Result, error){handler.Pull, handler.Get, handler.Delete} {
+		if _, err := op(context.Background(), config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer.Close()
+	captured, _ := io.ReadAll(reader)
+	output := string(captured) + logs.String()
+
+	if !strings.Contains(output, "+ hc") && !strings.Contains(output, "+ "+getHarnessBin()) {
+		t.Fatalf("expected trace output, got:\n%s", output)
+	}
+	for _, secret := range []string{token, account} {
+		if strings.Contains(output, secret) {
+			t.Errorf("secret %q leaked:\n%s", secret, output)
+		}
+	}
+
+	// The real values must still be passed to the command
+	for _, args := range runner.args() {
+		if !strings.Contains(strings.Join(args, " "), "--token "+token) {
+			t.Errorf("command did not receive the token: %q", args)
+		}
+	}
+}
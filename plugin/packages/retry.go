@@ -0,0 +1,265 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Default retry delays, used when the configuration leaves them unset
+const (
+	defaultRetryDelay    = time.Second
+	defaultRetryMaxDelay = 30 * time.Second
+)
+
+// RetryPolicy controls how failed registry operations are retried
+type RetryPolicy struct {
+	// Retries is the number of additional attempts after the first failure
+	Retries int
+
+	// BaseDelay is the delay before the first retry, doubled on every attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+}
+
+// newRetryPolicy builds the retry policy from the configuration
+func newRetryPolicy(config Config) RetryPolicy {
+	policy := RetryPolicy{
+		Retries:   config.Retries,
+		BaseDelay: config.RetryDelay,
+		MaxDelay:  config.RetryMaxDelay,
+	}
+	if policy.Retries < 0 {
+		policy.Retries = 0
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = defaultRetryDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = defaultRetryMaxDelay
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+	return policy
+}
+
+// delay returns the wait before the given retry (1-based) using exponential
+// backoff with full jitter, so concurrent clients do not retry in lockstep
+func (p RetryPolicy) delay(retry int) time.Duration {
+	backoff := p.BaseDelay
+	for i := 1; i < retry && backoff < p.MaxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// withRetry runs fn until it succeeds, fails with a permanent error, the
+// context is done or the policy is exhausted. Every failed attempt is logged with its reason.
+func withRetry(ctx context.Context, policy RetryPolicy, operation string, fn func() error) error {
+	attempts := policy.Retries + 1
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		// Never retry once the operation is cancelled or timed out
+		if ctx.Err() != nil {
+			return err
+		}
+
+		transient, reason := classifyError(err)
+		if !transient {
+			if attempt > 1 {
+				loggerFrom(ctx).Printf("Attempt %d/%d to %s failed (%s), not retrying", attempt, attempts, operation, reason)
+			}
+			return err
+		}
+		if attempt >= attempts {
+			if attempts > 1 {
+				loggerFrom(ctx).Printf("Attempt %d/%d to %s failed (%s), no retries left", attempt, attempts, operation, reason)
+			}
+			return err
+		}
+
+		wait := policy.delay(attempt)
+		loggerFrom(ctx).Printf("Attempt %d/%d to %s failed (%s), retrying in %s", attempt, attempts, operation, reason, wait.Round(time.Millisecond))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// CommandError is returned when a Harness CLI command fails. It keeps the
+// tail of the command's stderr so the failure can be classified.
+type CommandError struct {
+	Err      error
+	ExitCode int
+	Stderr   string
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// permanentPattern matches validation and authentication failures
+	permanentPattern = regexp.MustCompile(`(?i)\b(400|401|403|404|409|422)\b|unauthori[sz]ed|forbidden|bad request|invalid|not found|already exists|conflict|permission denied|authentication`)
+
+	// transientPattern matches network failures, server errors and throttling
+	transientPattern = regexp.MustCompile(`(?i)\b(5\d\d|429)\b|too many requests|rate limit|internal server error|bad gateway|service unavailable|gateway timeout|connection refused|connection reset|broken pipe|i/o timeout|timed out|timeout|tls handshake|temporary failure|unexpected eof|\beof\b`)
+)
+
+// classifyError reports whether err is a transient failure worth retrying,
+// together with a short reason for the logs
+func classifyError(err error) (bool, string) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, "operation cancelled"
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		reason := fmt.Sprintf("HTTP %d", statusErr.StatusCode)
+		return statusErr.StatusCode == 429 || statusErr.StatusCode >= 500, reason
+	}
+
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		if errors.Is(cmdErr.Err, exec.ErrNotFound) {
+			return false, "command not found"
+		}
+		if cmdErr.ExitCode < 0 {
+			// The command could not be started or was terminated by a signal
+			return false, cmdErr.Err.Error()
+		}
+		return classifyMessage(cmdErr.Stderr, fmt.Sprintf("exit status %d", cmdErr.ExitCode))
+	}
+
+	if reason := permanentNetworkError(err); reason != "" {
+		return false, reason
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, "network timeout: " + netErr.Error()
+	}
+	var opErr *net.OpError
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.As(err, &opErr) {
+		return true, "network error: " + err.Error()
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true, "connection closed unexpectedly"
+	}
+
+	// Any other request failure, such as a malformed URL or an unsupported
+	// protocol scheme, fails the same way on every attempt
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return false, "request failed: " + urlErr.Error()
+	}
+
+	return classifyMessage(err.Error(), "unclassified error")
+}
+
+// permanentNetworkError describes network failures retries cannot fix, such
+// as an untrusted certificate or an unknown host, or returns an empty string
+func permanentNetworkError(err error) string {
+	var (
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		verifyErr    *tls.CertificateVerificationError
+		recordErr    tls.RecordHeaderError
+		dnsErr       *net.DNSError
+	)
+	switch {
+	case errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr), errors.As(err, &verifyErr):
+		return "certificate error: " + err.Error()
+	case errors.As(err, &recordErr):
+		return "TLS error: " + err.Error()
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return "unknown host: " + err.Error()
+	}
+	return ""
+}
+
+// classifyMessage classifies an error message, permanent failures win over
+// transient ones and unknown failures are not retried
+func classifyMessage(message, fallback string) (bool, string) {
+	lastLine := lastNonEmptyLine(message)
+	if match := permanentPattern.FindString(message); match != "" {
+		return false, fmt.Sprintf("%s: %s", fallback, describe(lastLine, match))
+	}
+	if match := transientPattern.FindString(message); match != "" {
+		return true, fmt.Sprintf("%s: %s", fallback, describe(lastLine, match))
+	}
+	if lastLine != "" {
+		return false, fmt.Sprintf("%s: %s", fallback, lastLine)
+	}
+	return false, fallback
+}
+
+func describe(line, match string) string {
+	if line != "" {
+		return line
+	}
+	return match
+}
+
+func lastNonEmptyLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.data)
+}
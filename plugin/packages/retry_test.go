@@ -0,0 +1,127 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// exitError mimics the exit status of a failed child process
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &CommandError{Err: exitError(1), ExitCode: 1, Stderr: "Error: 503 Service Unavailable"}, true},
+		{"throttled", &CommandError{Err: exitError(1), ExitCode: 1, Stderr: "upload failed: status 429"}, true},
+		{"connection reset", &CommandError{Err: exitError(1), ExitCode: 1, Stderr: "read tcp: connection reset by peer"}, true},
+		{"unauthorized", &CommandError{Err: exitError(1), ExitCode: 1, Stderr: "Error: 401 Unauthorized"}, false},
+		{"validation", &CommandError{Err: exitError(1), ExitCode: 1, Stderr: "Error: invalid registry name"}, false},
+		{"unknown failure", &CommandError{Err: exitError(2), ExitCode: 2, Stderr: "something broke"}, false},
+		{"binary missing", &CommandError{Err: exec.ErrNotFound, ExitCode: -1}, false},
+		{"killed", &CommandError{Err: errors.New("signal: killed"), ExitCode: -1}, false},
+		{"http 502", &StatusError{StatusCode: 502}, true},
+		{"http 429", &StatusError{StatusCode: 429}, true},
+		{"http 403", &StatusError{StatusCode: 403}, false},
+		{"unexpected eof", fmt.Errorf("download: %w", io.ErrUnexpectedEOF), true},
+		{"cancelled", fmt.Errorf("push: %w", context.Canceled), false},
+		{"http connection refused", &url.Error{Op: "Get", URL: "https://pkg.harness.io", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"http connection reset", &url.Error{Op: "Put", URL: "https://pkg.harness.io", Err: syscall.ECONNRESET}, true},
+		{"http timeout", &url.Error{Op: "Get", URL: "https://pkg.harness.io", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, true},
+		{"unknown authority", &url.Error{Op: "Get", URL: "https://pkg.harness.io", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, false},
+		{"hostname mismatch", &url.Error{Op: "Get", URL: "https://pkg.harness.io", Err: x509.HostnameError{Certificate: &x509.Certificate{}, Host: "pkg.harness.io"}}, false},
+		{"plain http server", &url.Error{Op: "Get", URL: "https://pkg.harness.io", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}}, false},
+		{"unknown host", &url.Error{Op: "Get", URL: "https://pkg.harness.io", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "pkg.harness.io", IsNotFound: true}}}, false},
+		{"unsupported scheme", &url.Error{Op: "Get", URL: "ftp://pkg.harness.io", Err: errors.New(`unsupported protocol scheme "ftp"`)}, false},
+		{"malformed url", malformedURL(), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, reason := classifyError(test.err); got != test.want {
+				t.Errorf("classifyError(%v) = %v (%s), want %v", test.err, got, reason, test.want)
+			}
+		})
+	}
+}
+
+// malformedURL returns the error of parsing an invalid URL
+func malformedURL() error {
+	_, err := url.Parse("https://pkg.harness.io:port/")
+	return err
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := newRetryPolicy(Config{Retries: 5, RetryDelay: 100 * time.Millisecond, RetryMaxDelay: 300 * time.Millisecond})
+	for retry, max := range map[int]time.Duration{1: 100, 2: 200, 3: 300, 6: 300} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := policy.delay(retry); d < max/2 || d > max {
+				t.Fatalf("delay(%d) = %s, want between %s and %s", retry, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestCLIBackend_RetriesTransientFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		stderr   string
+		failures int
+		retries  int
+		wantErr  bool
+		wantRuns int
+	}{
+		{"recovers", "Error: 503 Service Unavailable", 2, 3, false, 3},
+		{"exhausted", "Error: 502 Bad Gateway", 5, 2, true, 3},
+		{"permanent", "Error: 401 Unauthorized", 1, 3, true, 1},
+		{"disabled", "Error: 503 Service Unavailable", 1, 0, true, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			factory, runner := newTestFactory(t)
+			runs := 0
+			runner.run = func(cmd Command) error {
+				runs++
+				if runs <= test.failures {
+					fmt.Fprintln(cmd.Stderr, test.stderr)
+					return exitError(1)
+				}
+				return nil
+			}
+
+			config := testConfig()
+			config.Retries = test.retries
+			config.RetryDelay = time.Millisecond
+			handler, _ := factory.GetHandler("generic")
+			_, err := handler.Delete(context.Background(), config)
+
+			if (err != nil) != test.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if runs != test.wantRuns {
+				t.Errorf("command ran %d times, want %d", runs, test.wantRuns)
+			}
+		})
+	}
+}
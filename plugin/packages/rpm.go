@@ -17,9 +17,9 @@ type RPMHandler struct {
 }
 
 // NewRPMHandler creates a new RPM package handler
-func NewRPMHandler() *RPMHandler {
+func NewRPMHandler(backend Backend) *RPMHandler {
 	return &RPMHandler{
-		BaseHandler: NewBaseHandler(RPM),
+		BaseHandler: NewBaseHandler(RPM, backend),
 	}
 }
 
@@ -44,44 +44,43 @@ func (h *RPMHandler) Validate(config Config) error {
 }
 
 // Push uploads RPM packages to the registry
-func (h *RPMHandler) Push(ctx context.Context, config Config) error {
+func (h *RPMHandler) Push(ctx context.Context, config Config) (*Result, error) {
 	logrus.Println("Executing RPM push command")
 
 	// Validate configuration
 	if err := h.Validate(config); err != nil {
-		return err
+		return nil, err
 	}
 
 	logrus.Printf("Source path: %s", config.Source)
 
-	return h.pushSingleFile(config, config.Source, config.Name)
+	return h.pushSingleFile(ctx, config, config.Source, config.Name)
 }
 
 // pushSingleFile handles pushing a single file for RPM packages
-func (h *RPMHandler) pushSingleFile(config Config, filePath, artifactName string) error {
-	// Build command using shared helper (no file path and version in command for RPM)
-	cmdArgs, err := buildPushCommand(RPM, config, "", filePath, artifactName, false)
-	if err != nil {
-		return err
-	}
-
-	return executeCommand(cmdArgs, fmt.Sprintf("push RPM artifact '%s' to registry '%s'", artifactName, config.Registry))
+func (h *RPMHandler) pushSingleFile(ctx context.Context, config Config, filePath, artifactName string) (*Result, error) {
+	return h.backend.Push(ctx, PushRequest{
+		PackageType: RPM,
+		Config:      config,
+		FilePath:    filePath,
+		Name:        artifactName,
+	})
 }
 
 // Pull downloads RPM packages from the registry
-func (h *RPMHandler) Pull(ctx context.Context, config Config) error {
+func (h *RPMHandler) Pull(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement RPM pull logic
-	return fmt.Errorf("RPM pull is not yet implemented")
+	return nil, fmt.Errorf("RPM pull is not yet implemented")
 }
 
 // Get retrieves RPM package information
-func (h *RPMHandler) Get(ctx context.Context, config Config) error {
+func (h *RPMHandler) Get(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement RPM get logic
-	return fmt.Errorf("RPM get is not yet implemented")
+	return nil, fmt.Errorf("RPM get is not yet implemented")
 }
 
 // Delete removes RPM packages from the registry
-func (h *RPMHandler) Delete(ctx context.Context, config Config) error {
+func (h *RPMHandler) Delete(ctx context.Context, config Config) (*Result, error) {
 	// TODO: Implement RPM delete logic
-	return fmt.Errorf("RPM delete is not yet implemented")
+	return nil, fmt.Errorf("RPM delete is not yet implemented")
 }
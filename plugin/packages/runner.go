@@ -0,0 +1,59 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// terminateGracePeriod is how long a cancelled command may take to exit
+// after being asked to terminate before it is killed
+const terminateGracePeriod = 10 * time.Second
+
+// Command describes a single external command invocation
+type Command struct {
+	// Args holds the command name followed by its arguments
+	Args []string
+
+	// Env holds the environment of the command in os.Environ form
+	Env []string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner executes external commands. It allows the CLI backend to be
+// exercised without a real Harness CLI binary.
+type Runner interface {
+	Run(ctx context.Context, cmd Command) error
+}
+
+// ExecRunner runs commands as child processes
+type ExecRunner struct{}
+
+// Run starts the command and waits for it to complete. When the context is
+// cancelled the process is asked to terminate and killed if it does not exit
+// within the grace period.
+func (ExecRunner) Run(ctx context.Context, command Command) error {
+	// Execute command directly without shell to avoid argument parsing issues
+	cmd := exec.CommandContext(ctx, command.Args[0], command.Args[1:]...)
+	cmd.Env = command.Env
+	cmd.Stdout = command.Stdout
+	cmd.Stderr = command.Stderr
+	cmd.Cancel = func() error {
+		// Sending signals other than kill is not supported on Windows
+		if runtime.GOOS == "windows" {
+			return cmd.Process.Kill()
+		}
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = terminateGracePeriod
+	return cmd.Run()
+}
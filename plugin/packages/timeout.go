@@ -0,0 +1,52 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned when a single file operation exceeds the
+// configured per-file timeout
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// runOperation runs a single file operation, retrying transient failures
+// according to the retry policy of the configuration. With a per-file
+// timeout the operation, including its retries, is cancelled once the
+// timeout expires and a TimeoutError is returned.
+func runOperation(ctx context.Context, config Config, operation string, fn func(ctx context.Context) error) error {
+	if config.FileTimeout <= 0 {
+		return withRetry(ctx, newRetryPolicy(config), operation, func() error {
+			return fn(ctx)
+		})
+	}
+
+	fileCtx, cancel := context.WithTimeout(ctx, config.FileTimeout)
+	defer cancel()
+
+	err := withRetry(fileCtx, newRetryPolicy(config), operation, func() error {
+		return fn(fileCtx)
+	})
+	// Only report the per-file timeout, an expired parent context is
+	// reported by the caller
+	if err != nil && ctx.Err() == nil && errors.Is(fileCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{Timeout: config.FileTimeout, Err: err}
+	}
+	return err
+}
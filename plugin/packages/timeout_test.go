@@ -0,0 +1,102 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runnerFunc adapts a function to the Runner interface
+type runnerFunc func(ctx context.Context, cmd Command) error
+
+func (f runnerFunc) Run(ctx context.Context, cmd Command) error {
+	return f(ctx, cmd)
+}
+
+func TestCLIBackend_FileTimeout(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "dist/artifact.bin"))
+
+	// Simulate a hung upload that only ends when cancelled
+	var attempts int32
+	backend := NewCLIBackend(runnerFunc(func(ctx context.Context, cmd Command) error {
+		atomic.AddInt32(&attempts, 1)
+		<-ctx.Done()
+		return errors.New("signal: terminated")
+	}))
+	defer backend.Close()
+
+	config := testConfig()
+	config.Retries = 3
+	config.RetryDelay = time.Millisecond
+	config.FileTimeout = 20 * time.Millisecond
+
+	start := time.Now()
+	_, err := NewGenericHandler(backend).Push(context.Background(), config)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout was not enforced, took %s", elapsed)
+	}
+	if attempts != 1 {
+		t.Errorf("timed out operations must not be retried, got %d attempts", attempts)
+	}
+}
+
+func TestCLIBackend_ParentCancelIsNotFileTimeout(t *testing.T) {
+	captureLogs(t)
+	t.Chdir(writeTree(t, "dist/artifact.bin"))
+
+	backend := NewCLIBackend(runnerFunc(func(ctx context.Context, cmd Command) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	defer backend.Close()
+
+	config := testConfig()
+	config.FileTimeout = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := NewGenericHandler(backend).Push(ctx, config)
+	var timeoutErr *TimeoutError
+	if err == nil || errors.As(err, &timeoutErr) {
+		t.Fatalf("expected the parent deadline to be reported by the caller, got %v", err)
+	}
+}
+
+func TestExecRunner_TerminatesOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := ExecRunner{}.Run(ctx, Command{Args: []string{"sleep", "30"}})
+	if err == nil {
+		t.Fatal("expected the command to be terminated")
+	}
+	if elapsed := time.Since(start); elapsed > terminateGracePeriod {
+		t.Errorf("command was not terminated, took %s", elapsed)
+	}
+}
@@ -0,0 +1,621 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML decodes a TOML document into nested maps. It supports what
+// pyproject.toml files use: tables, arrays of tables, dotted and quoted
+// keys, basic and literal strings including multi-line ones, integers,
+// floats, booleans, arrays and inline tables. Dates are kept as strings.
+func parseTOML(data string) (map[string]any, error) {
+	p := &tomlParser{src: data, line: 1}
+	root := map[string]any{}
+	current := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		var err error
+		if p.peek() == '[' {
+			current, err = p.parseHeader(root)
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err == nil {
+			err = p.expectLineEnd()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+	}
+}
+
+// tomlParser holds the state of parseTOML
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(p.src[p.pos:], prefix)
+}
+
+// skipSpace skips spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to the end of the line
+func (p *tomlParser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// expectLineEnd consumes the rest of a line, which may only hold a comment
+func (p *tomlParser) expectLineEnd() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return fmt.Errorf("unexpected '%c' after value", p.peek())
+	}
+	p.pos++
+	p.line++
+	return nil
+}
+
+// parseHeader parses a [table] or [[array of tables]] header and returns
+// the table that following key/value pairs belong to
+func (p *tomlParser) parseHeader(root map[string]any) (map[string]any, error) {
+	array := p.hasPrefix("[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipSpace()
+	key, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !p.hasPrefix(closing) {
+		return nil, fmt.Errorf("expected '%s' to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := tomlTable(root, key[:len(key)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := key[len(key)-1]
+	if !array {
+		return tomlTable(parent, []string{last})
+	}
+
+	table := map[string]any{}
+	switch existing := parent[last].(type) {
+	case nil:
+		parent[last] = []any{table}
+	case []any:
+		parent[last] = append(existing, table)
+	default:
+		return nil, fmt.Errorf("key '%s' is not an array of tables", strings.Join(key, "."))
+	}
+	return table, nil
+}
+
+// tomlTable returns the table at the dotted path, creating missing tables.
+// A path ending in an array of tables refers to its last table.
+func tomlTable(table map[string]any, path []string) (map[string]any, error) {
+	for _, name := range path {
+		switch next := table[name].(type) {
+		case nil:
+			child := map[string]any{}
+			table[name] = child
+			table = child
+		case map[string]any:
+			table = next
+		case []any:
+			if len(next) == 0 {
+				return nil, fmt.Errorf("key '%s' is not a table", name)
+			}
+			last, ok := next[len(next)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key '%s' is not a table", name)
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("key '%s' is not a table", name)
+		}
+	}
+	return table, nil
+}
+
+// parseKeyValue parses a key = value pair into the table
+func (p *tomlParser) parseKeyValue(table map[string]any) error {
+	key, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() != '=' {
+		return fmt.Errorf("expected '=' after key '%s'", strings.Join(key, "."))
+	}
+	p.pos++
+	p.skipSpace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	parent, err := tomlTable(table, key[:len(key)-1])
+	if err != nil {
+		return err
+	}
+	last := key[len(key)-1]
+	if _, exists := parent[last]; exists {
+		return fmt.Errorf("duplicate key '%s'", strings.Join(key, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey parses a bare, quoted or dotted key
+func (p *tomlParser) parseKey() ([]string, error) {
+	var key []string
+	for {
+		p.skipSpace()
+		var part string
+		switch c := p.peek(); {
+		case c == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		case c == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isTOMLBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("expected a key, found '%c'", c)
+			}
+			part = p.src[start:p.pos]
+		}
+		key = append(key, part)
+
+		p.skipSpace()
+		if p.peek() != '.' {
+			return key, nil
+		}
+		p.pos++
+	}
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseValue parses any value
+func (p *tomlParser) parseValue() (any, error) {
+	switch c := p.peek(); {
+	case p.hasPrefix(`"""`):
+		return p.parseMultilineBasicString()
+	case c == '"':
+		return p.parseBasicString()
+	case p.hasPrefix(`'''`):
+		return p.parseMultilineLiteralString()
+	case c == '\'':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case p.hasPrefix("true"):
+		p.pos += 4
+		return true, nil
+	case p.hasPrefix("false"):
+		p.pos += 5
+		return false, nil
+	case c == 0:
+		return nil, fmt.Errorf("missing value")
+	default:
+		return p.parseScalar()
+	}
+}
+
+// parseScalar parses a number or a date
+func (p *tomlParser) parseScalar() (any, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if !isTOMLBareKeyChar(c) && c != '.' && c != '+' && c != ':' {
+			break
+		}
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	if token == "" {
+		return nil, fmt.Errorf("unexpected '%c'", p.peek())
+	}
+
+	clean := strings.ReplaceAll(token, "_", "")
+	switch strings.TrimLeft(clean, "+-") {
+	case "inf":
+		if strings.HasPrefix(clean, "-") {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	// Only prefixed integers use another base, leading zeros are not octal
+	base := 10
+	if len(clean) > 2 && clean[0] == '0' && strings.IndexByte("xob", clean[1]) >= 0 {
+		base = 0
+	}
+	if i, err := strconv.ParseInt(clean, base, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil && !strings.HasPrefix(clean, "0x") {
+		return f, nil
+	}
+	// Dates and times start with four digits
+	if len(token) >= 5 && token[4] == '-' || len(token) >= 3 && token[2] == ':' {
+		return token, nil
+	}
+	return nil, fmt.Errorf("invalid value '%s'", token)
+}
+
+// parseArray parses an array, which may span several lines
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.pos++
+	values := []any{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// parseInlineTable parses a { key = value, ... } table
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	p.pos++
+	table := map[string]any{}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		p.skipSpace()
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// parseBasicString parses a "..." string with escapes
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// parseMultilineBasicString parses a """...""" string. A newline directly
+// after the opening delimiter is trimmed, a backslash at the end of a line
+// trims the following whitespace.
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.skipNewline()
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated multi-line string")
+		}
+		if p.hasPrefix(`"""`) {
+			// Up to two quotes may directly precede the closing delimiter
+			for p.hasPrefix(`""""`) {
+				b.WriteByte('"')
+				p.pos++
+			}
+			p.pos += 3
+			return b.String(), nil
+		}
+		c := p.peek()
+		switch {
+		case c == '\\' && p.isLineEndingBackslash():
+			p.pos++
+			for !p.eof() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+				if p.peek() == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+		case c == '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// isLineEndingBackslash reports whether the backslash at the current
+// position is only followed by whitespace up to the end of the line
+func (p *tomlParser) isLineEndingBackslash() bool {
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case ' ', '\t', '\r':
+		case '\n':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// parseLiteralString parses a '...' string without escapes
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", fmt.Errorf("unterminated literal string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// parseMultilineLiteralString parses a multi-line literal string, delimited
+// by three single quotes
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.skipNewline()
+	end := strings.Index(p.src[p.pos:], `'''`)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated multi-line literal string")
+	}
+	// Up to two quotes may directly precede the closing delimiter
+	for end+3 < len(p.src)-p.pos && p.src[p.pos+end+3] == '\'' {
+		end++
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.line += strings.Count(s, "\n")
+	p.pos += end + 3
+	return s, nil
+}
+
+// skipNewline skips a newline directly following a multi-line string delimiter
+func (p *tomlParser) skipNewline() {
+	if p.hasPrefix("\r\n") {
+		p.pos += 2
+		p.line++
+	} else if p.hasPrefix("\n") {
+		p.pos++
+		p.line++
+	}
+}
+
+// parseEscape decodes the escape sequence at the current position
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.pos+1 >= len(p.src) {
+		return fmt.Errorf("unterminated escape sequence")
+	}
+	c := p.src[p.pos+1]
+	p.pos += 2
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return fmt.Errorf("invalid unicode escape '\\%c%s'", c, p.src[p.pos:p.pos+size])
+		}
+		b.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return fmt.Errorf("invalid escape sequence '\\%c'", c)
+	}
+	return nil
+}
+
+// tomlFields reads typed fields of a TOML table, the first type mismatch is
+// kept in err
+type tomlFields struct {
+	table map[string]any
+	err   error
+}
+
+func (f *tomlFields) fail(key, want string) {
+	if f.err == nil {
+		f.err = fmt.Errorf("'%s' must be %s", key, want)
+	}
+}
+
+func (f *tomlFields) string(key string) string {
+	switch value := f.table[key].(type) {
+	case nil:
+	case string:
+		return value
+	default:
+		f.fail(key, "a string")
+	}
+	return ""
+}
+
+func (f *tomlFields) strings(key string) []string {
+	values, ok := f.table[key].([]any)
+	if !ok {
+		if f.table[key] != nil {
+			f.fail(key, "an array of strings")
+		}
+		return nil
+	}
+	var result []string
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			f.fail(key, "an array of strings")
+			return nil
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func (f *tomlFields) tables(key string) map[string]any {
+	table, ok := f.table[key].(map[string]any)
+	if !ok && f.table[key] != nil {
+		f.fail(key, "a table")
+	}
+	return table
+}
+
+func (f *tomlFields) sub(key string) *tomlFields {
+	return &tomlFields{table: f.tables(key)}
+}
+
+func (f *tomlFields) stringMap(key string) map[string]string {
+	result := map[string]string{}
+	for name, value := range f.tables(key) {
+		s, ok := value.(string)
+		if !ok {
+			f.fail(key+"."+name, "a string")
+			continue
+		}
+		result[name] = s
+	}
+	return result
+}
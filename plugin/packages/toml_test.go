@@ -0,0 +1,103 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	doc, err := parseTOML(`# pyproject
+[build-system]
+requires = ["hatchling>=1.0", 'setuptools']  # trailing comment
+
+[project]
+name = "my-pkg"
+"quoted key" = 'C:\path'
+version.major = 1
+description = """
+First \
+  line\twith tab \u00e9"""
+literal = '''
+raw \n'''
+readme = {file = "README.md", content-type = "text/markdown"}
+numbers = [1_000, 0x1f, 0o17, 0b11, +1.5e3, 010]
+flags = [true, false]
+released = 1979-05-27T07:32:00Z
+nested = [[1, 2], [
+  "a", # comment
+]]
+
+[[project.authors]]
+name = "A"
+
+[[project.authors]]
+email = "b@example.com"
+
+[project.urls]
+Homepage = "https://example.com"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"build-system": map[string]any{"requires": []any{"hatchling>=1.0", "setuptools"}},
+		"project": map[string]any{
+			"name":        "my-pkg",
+			"quoted key":  `C:\path`,
+			"version":     map[string]any{"major": int64(1)},
+			"description": "First line\twith tab é",
+			"literal":     `raw \n`,
+			"readme":      map[string]any{"file": "README.md", "content-type": "text/markdown"},
+			"numbers":     []any{int64(1000), int64(31), int64(15), int64(3), 1500.0, int64(10)},
+			"flags":       []any{true, false},
+			"released":    "1979-05-27T07:32:00Z",
+			"nested":      []any{[]any{int64(1), int64(2)}, []any{"a"}},
+			"authors": []any{
+				map[string]any{"name": "A"},
+				map[string]any{"email": "b@example.com"},
+			},
+			"urls": map[string]any{"Homepage": "https://example.com"},
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("unexpected document\n got: %#v\nwant: %#v", doc, want)
+	}
+}
+
+func TestParseTOML_Special(t *testing.T) {
+	doc, err := parseTOML("a = inf\nb = -inf\nc = nan\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(doc["a"].(float64), 1) || !math.IsInf(doc["b"].(float64), -1) || !math.IsNaN(doc["c"].(float64)) {
+		t.Errorf("unexpected special floats %v", doc)
+	}
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	for input, want := range map[string]string{
+		"a = 1\na = 2\n":       "line 2",
+		"a = \"open\n":         "line 1",
+		"a = 1 b = 2\n":        "unexpected 'b'",
+		"[a\n":                 "expected ']'",
+		"a = 1\n[a]\n":         "not a table",
+		"a = [1, 2\n":          "line",
+		"a = \"\\q\"\n":        "invalid escape",
+		"a = {b = 1,}\n":       "line 1",
+		"a = []\n[[a.b]]\n":    "not a table",
+		"= 1\n":                "line 1",
+		"a.b = 1\na.b.c = 2\n": "line 2",
+		"a = -0x1f\n":          "invalid value",
+	} {
+		if _, err := parseTOML(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", input, want, err)
+		}
+	}
+}
@@ -6,25 +6,49 @@ package packages
 
 import (
 	"context"
+	"time"
 )
 
 // PackageType represents the supported package types
 type PackageType string
 
 const (
-	Generic PackageType = "GENERIC"
-	NPM     PackageType = "NPM"
-	Dart    PackageType = "DART"
+	Generic  PackageType = "GENERIC"
+	NPM      PackageType = "NPM"
+	Dart     PackageType = "DART"
 	Composer PackageType = "COMPOSER"
-	RPM     PackageType = "RPM"
-	Python  PackageType = "PYTHON"
-	Go      PackageType = "GO"
-	Cargo   PackageType = "CARGO"
-	NuGet   PackageType = "NUGET"
-	Maven   PackageType = "MAVEN"
-	Conda   PackageType = "CONDA"
+	RPM      PackageType = "RPM"
+	Python   PackageType = "PYTHON"
+	Go       PackageType = "GO"
+	Cargo    PackageType = "CARGO"
+	NuGet    PackageType = "NUGET"
+	Maven    PackageType = "MAVEN"
+	Conda    PackageType = "CONDA"
 )
 
+// packageLabels holds the human readable name of each package type
+var packageLabels = map[PackageType]string{
+	Generic:  "generic",
+	NPM:      "NPM",
+	Dart:     "Dart",
+	Composer: "Composer",
+	RPM:      "RPM",
+	Python:   "Python",
+	Go:       "Go",
+	Cargo:    "Cargo",
+	NuGet:    "NuGet",
+	Maven:    "Maven",
+	Conda:    "Conda",
+}
+
+// Label returns the human readable name of the package type
+func (t PackageType) Label() string {
+	if label, ok := packageLabels[t]; ok {
+		return label
+	}
+	return string(t)
+}
+
 // Config holds the common configuration for all package handlers
 type Config struct {
 	// Authentication
@@ -35,6 +59,9 @@ type Config struct {
 	ApiURL  string
 	PkgURL  string
 
+	// Secrets holds additional values that must never appear in the logs
+	Secrets []string
+
 	// Registry and artifact details
 	Registry    string
 	Name        string
@@ -43,29 +70,73 @@ type Config struct {
 	Filename    string
 	PomFile     string
 
+	// Maven coordinates used to generate a POM for projects without one
+	GroupID      string
+	ArtifactID   string
+	Packaging    string
+	Dependencies []string
+
+	// Tag is the npm dist-tag set on publish, or changed by the dist-tag
+	// commands
+	Tag string
+
+	// PythonTags lists the wheel tags accepted by a Python pull, in order of
+	// preference
+	PythonTags []string
+
+	// GitTag and CommitSHA identify the commit of the pipeline, Go modules
+	// derive their version from them
+	GitTag    string
+	CommitSHA string
+
 	// Operation details
 	Source      string
 	Destination string
 	Retries     int
+
+	// Delay before the first retry and the cap for exponential backoff
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
+
+	// FileTimeout limits every single file operation, including its retries
+	FileTimeout time.Duration
+
+	// Checksum verification of pulled files, against an expected SHA-256
+	// checksum or the checksum stored in the registry
+	ExpectedSHA256 string
+	VerifyChecksum bool
+
+	// UploadChecksums pushes a SHA256SUMS file next to the pushed files
+	UploadChecksums bool
+
+	// Directory push filters, matched against paths relative to Source
+	Include       []string
+	Exclude       []string
+	IncludeHidden bool
+
+	// Multi-file uploads: number of concurrent uploads and whether the
+	// first failure cancels the remaining ones
+	Parallelism int
+	FailFast    bool
 }
 
 // PackageHandler defines the interface that all package type handlers must implement
 type PackageHandler interface {
 	// Push uploads artifacts to the registry
-	Push(ctx context.Context, config Config) error
-	
+	Push(ctx context.Context, config Config) (*Result, error)
+
 	// Pull downloads artifacts from the registry
-	Pull(ctx context.Context, config Config) error
-	
+	Pull(ctx context.Context, config Config) (*Result, error)
+
 	// Get retrieves artifact information
-	Get(ctx context.Context, config Config) error
-	
+	Get(ctx context.Context, config Config) (*Result, error)
+
 	// Delete removes artifacts from the registry
-	Delete(ctx context.Context, config Config) error
-	
+	Delete(ctx context.Context, config Config) (*Result, error)
+
 	// Validate checks if the configuration is valid for this package type
 	Validate(config Config) error
-	
+
 	// GetPackageType returns the package type this handler supports
 	GetPackageType() PackageType
 }
@@ -73,12 +144,14 @@ type PackageHandler interface {
 // BaseHandler provides common functionality for all package handlers
 type BaseHandler struct {
 	packageType PackageType
+	backend     Backend
 }
 
 // NewBaseHandler creates a new base handler
-func NewBaseHandler(packageType PackageType) BaseHandler {
+func NewBaseHandler(packageType PackageType, backend Backend) BaseHandler {
 	return BaseHandler{
 		packageType: packageType,
+		backend:     backend,
 	}
 }
 
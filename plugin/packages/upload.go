@@ -0,0 +1,149 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// uploadJob is a single file upload scheduled by runUploads
+type uploadJob struct {
+	// Label identifies the file in the logs
+	Label string
+
+	// Push uploads the file, logging through the logger of the given context
+	Push func(ctx context.Context) error
+}
+
+// uploadOutcome tracks the state of a scheduled upload
+type uploadOutcome struct {
+	output  bytes.Buffer
+	err     error
+	skipped bool
+	done    chan struct{}
+}
+
+// runUploads pushes the jobs with up to config.Parallelism concurrent uploads.
+// When uploads run in parallel their output is buffered and written in job
+// order once each upload completes, so the logs of two files never interleave.
+// With config.FailFast the first failure cancels the remaining uploads,
+// otherwise every file is attempted. source describes where the files come
+// from and is used in the summary error.
+func runUploads(ctx context.Context, config Config, source string, jobs []uploadJob) error {
+	logger := loggerFrom(ctx)
+
+	parallelism := config.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if parallelism > len(jobs) {
+		parallelism = len(jobs)
+	}
+	if parallelism > 1 {
+		logger.Printf("Uploading %d files with %d parallel uploads", len(jobs), parallelism)
+	}
+
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outcomes := make([]*uploadOutcome, len(jobs))
+	for i := range outcomes {
+		outcomes[i] = &uploadOutcome{done: make(chan struct{})}
+	}
+
+	run := func(i int) {
+		outcome := outcomes[i]
+		defer close(outcome.done)
+
+		// Do not start new uploads once cancelled or failed fast
+		if uploadCtx.Err() != nil {
+			outcome.skipped = true
+			return
+		}
+
+		jobCtx, jobLogger := uploadCtx, logger
+		if parallelism > 1 {
+			jobLogger = newBufferedLogger(&outcome.output)
+			jobCtx = withLogger(uploadCtx, jobLogger)
+		}
+
+		jobLogger.Printf("[%d/%d] Pushing file: %s", i+1, len(jobs), jobs[i].Label)
+		if err := jobs[i].Push(jobCtx); err != nil {
+			outcome.err = err
+			jobLogger.Errorf("✗ Failed to push file '%s': %v", jobs[i].Label, err)
+			if config.FailFast {
+				cancel()
+			}
+			return
+		}
+		jobLogger.Printf("✓ Successfully pushed file: %s", jobs[i].Label)
+	}
+
+	var wg sync.WaitGroup
+	if parallelism == 1 {
+		for i := range jobs {
+			run(i)
+		}
+	} else {
+		work := make(chan int)
+		for w := 0; w < parallelism; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range work {
+					run(i)
+				}
+			}()
+		}
+		go func() {
+			defer close(work)
+			for i := range jobs {
+				work <- i
+			}
+		}()
+	}
+
+	// Flush buffered output in job order and collect statistics
+	var successCount, failureCount, skippedCount int
+	for _, outcome := range outcomes {
+		<-outcome.done
+		if outcome.output.Len() > 0 {
+			logger.Out.Write(outcome.output.Bytes())
+		}
+		switch {
+		case outcome.skipped:
+			skippedCount++
+		case outcome.err != nil:
+			failureCount++
+		default:
+			successCount++
+		}
+	}
+	wg.Wait()
+
+	// Print final statistics
+	logger.Printf("=== UPLOAD SUMMARY ===")
+	logger.Printf("Total files: %d", len(jobs))
+	logger.Printf("✓ Successfully uploaded: %d", successCount)
+	if failureCount > 0 {
+		logger.Printf("✗ Failed uploads: %d", failureCount)
+	}
+	if skippedCount > 0 {
+		logger.Printf("⚠ Not attempted: %d", skippedCount)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("upload of %s cancelled after %d of %d files: %w", source, successCount, len(jobs), err)
+	}
+	if failureCount > 0 {
+		return fmt.Errorf("failed to push %d of %d files from %s", failureCount, len(jobs), source)
+	}
+
+	logger.Printf("✓ All files uploaded successfully!")
+	return nil
+}
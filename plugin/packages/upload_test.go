@@ -0,0 +1,149 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureLogs redirects the standard logger into a buffer for the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	std := logrus.StandardLogger()
+	out, formatter := std.Out, std.Formatter
+	logrus.SetOutput(&buf)
+	logrus.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
+	t.Cleanup(func() {
+		logrus.SetOutput(out)
+		logrus.SetFormatter(formatter)
+	})
+	return &buf
+}
+
+func TestRunUploads_ParallelOrderedOutput(t *testing.T) {
+	logs := captureLogs(t)
+
+	var running, peak int32
+	var jobs []uploadJob
+	for i := 0; i < 8; i++ {
+		label := fmt.Sprintf("file-%d", i)
+		delay := time.Duration(8-i) * time.Millisecond
+		jobs = append(jobs, uploadJob{
+			Label: label,
+			Push: func(ctx context.Context) error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(delay)
+				atomic.AddInt32(&running, -1)
+				loggerFrom(ctx).Printf("uploaded %s", label)
+				return nil
+			},
+		})
+	}
+
+	err := runUploads(context.Background(), Config{Parallelism: 4}, "test", jobs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peak < 2 || peak > 4 {
+		t.Errorf("expected between 2 and 4 concurrent uploads, got %d", peak)
+	}
+
+	// Output of each file must appear in job order, even though later jobs finish first
+	output := logs.String()
+	last := -1
+	for i := 0; i < 8; i++ {
+		idx := strings.Index(output, fmt.Sprintf("uploaded file-%d", i))
+		if idx < last {
+			t.Fatalf("output of file-%d is out of order:\n%s", i, output)
+		}
+		last = idx
+	}
+}
+
+func TestRunUploads_FailFast(t *testing.T) {
+	captureLogs(t)
+
+	for _, failFast := range []bool{true, false} {
+		t.Run(fmt.Sprintf("failFast=%v", failFast), func(t *testing.T) {
+			var attempted int32
+			var jobs []uploadJob
+			for i := 0; i < 10; i++ {
+				i := i
+				jobs = append(jobs, uploadJob{
+					Label: fmt.Sprintf("file-%d", i),
+					Push: func(ctx context.Context) error {
+						atomic.AddInt32(&attempted, 1)
+						if i == 0 {
+							return errors.New("boom")
+						}
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case <-time.After(5 * time.Millisecond):
+							return nil
+						}
+					},
+				})
+			}
+
+			err := runUploads(context.Background(), Config{Parallelism: 2, FailFast: failFast}, "test", jobs)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if failFast && attempted >= 10 {
+				t.Errorf("fail fast should stop scheduling uploads, %d attempted", attempted)
+			}
+			if !failFast && attempted != 10 {
+				t.Errorf("best effort should attempt every upload, %d attempted", attempted)
+			}
+		})
+	}
+}
+
+func TestRunUploads_Cancelled(t *testing.T) {
+	captureLogs(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	var once sync.Once
+	var jobs []uploadJob
+	for i := 0; i < 5; i++ {
+		jobs = append(jobs, uploadJob{
+			Label: fmt.Sprintf("file-%d", i),
+			Push: func(ctx context.Context) error {
+				once.Do(func() { close(started) })
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+	}
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	err := runUploads(ctx, Config{Parallelism: 2}, "test", jobs)
+	if err == nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/harness/drone-har/plugin/packages"
 	"github.com/sirupsen/logrus"
@@ -49,16 +50,56 @@ type Args struct {
 	Filename    string `envconfig:"PLUGIN_FILENAME"`
 	PkgURL      string `envconfig:"PLUGIN_PKG_URL"`
 	PomFile     string `envconfig:"PLUGIN_POM_FILE"`
+	Tag         string `envconfig:"PLUGIN_TAG"` // NPM dist-tag
+
+	// Wheel tags accepted by a Python pull, in order of preference
+	PythonTags []string `envconfig:"PLUGIN_PYTHON_TAGS"`
+
+	// Tag and commit of the pipeline, set by Drone
+	DroneTag       string `envconfig:"DRONE_TAG"`
+	DroneCommitSHA string `envconfig:"DRONE_COMMIT_SHA"`
+
+	// Maven coordinates used to generate a POM when pom_file is not set
+	GroupID      string   `envconfig:"PLUGIN_GROUP_ID"`
+	ArtifactID   string   `envconfig:"PLUGIN_ARTIFACT_ID"`
+	Packaging    string   `envconfig:"PLUGIN_PACKAGING"`
+	Dependencies []string `envconfig:"PLUGIN_DEPENDENCIES"`
 
 	// Package type for push operations
 	PackageType string `envconfig:"PLUGIN_PACKAGE_TYPE"`
 
+	// Directory push parameters
+	Include       []string `envconfig:"PLUGIN_INCLUDE"`
+	Exclude       []string `envconfig:"PLUGIN_EXCLUDE"`
+	IncludeHidden string   `envconfig:"PLUGIN_INCLUDE_HIDDEN"`
+
+	// Multi-file upload parameters
+	Parallelism int    `envconfig:"PLUGIN_PARALLELISM"`
+	FailFast    string `envconfig:"PLUGIN_FAIL_FAST"`
+
 	// Pull/Download parameters
-	Destination string `envconfig:"PLUGIN_DESTINATION"`
+	Destination    string `envconfig:"PLUGIN_DESTINATION"`
+	ExpectedSHA256 string `envconfig:"PLUGIN_EXPECTED_SHA256"`
+	VerifyChecksum string `envconfig:"PLUGIN_VERIFY_CHECKSUM"`
+
+	// Push a SHA256SUMS file next to the pushed files
+	UploadChecksums string `envconfig:"PLUGIN_UPLOAD_CHECKSUMS"`
 
 	// Additional parameters
-	Retries     int    `envconfig:"PLUGIN_RETRIES"`
-	EnableProxy string `envconfig:"PLUGIN_ENABLE_PROXY"`
+	Retries       int           `envconfig:"PLUGIN_RETRIES"`
+	RetryDelay    time.Duration `envconfig:"PLUGIN_RETRY_DELAY"`
+	RetryMaxDelay time.Duration `envconfig:"PLUGIN_RETRY_MAX_DELAY"`
+	EnableProxy   string        `envconfig:"PLUGIN_ENABLE_PROXY"`
+
+	// Timeouts for the whole operation and for every single file
+	Timeout     time.Duration `envconfig:"PLUGIN_TIMEOUT"`
+	FileTimeout time.Duration `envconfig:"PLUGIN_FILE_TIMEOUT"`
+
+	// Backend selects how registry operations are performed: cli (hc) or http
+	Backend string `envconfig:"PLUGIN_BACKEND"`
+
+	// SecretSettings names additional settings whose values are masked in the logs
+	SecretSettings []string `envconfig:"PLUGIN_SECRET_SETTINGS"`
 }
 
 // Exec executes the plugin using the new modular architecture.
@@ -79,8 +120,20 @@ func Exec(ctx context.Context, args Args) error {
 		setSecureConnectProxies()
 	}
 
+	// Select the backend used to talk to the registry
+	backend, err := packages.NewBackend(args.Backend)
+	if err != nil {
+		return err
+	}
+	// Remove credentials written by the backend, also when the operation fails
+	defer func() {
+		if err := backend.Close(); err != nil {
+			logrus.Printf("Warning: %v", err)
+		}
+	}()
+
 	// Create handler factory
-	factory := packages.NewHandlerFactory()
+	factory := packages.NewHandlerFactory(backend)
 
 	// Get package type, default to generic
 	packageType := args.PackageType
@@ -100,19 +153,57 @@ func Exec(ctx context.Context, args Args) error {
 	// Convert args to config
 	config := argsToConfig(args)
 
+	// Limit the duration of the whole operation if configured
+	if args.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, args.Timeout)
+		defer cancel()
+	}
+
 	// Route to appropriate command handler
+	var result *packages.Result
 	switch command {
 	case "push", "upload":
-		return handler.Push(ctx, config)
+		if config.UploadChecksums && handler.GetPackageType() != packages.Generic {
+			logrus.Printf("⚠ Warning: upload_checksums is ignored for %s packages, only generic pushes publish a SHA256SUMS file", handler.GetPackageType().Label())
+		}
+		result, err = handler.Push(ctx, config)
 	case "pull", "download":
-		return handler.Pull(ctx, config)
+		result, err = handler.Pull(ctx, config)
 	case "get", "info":
-		return handler.Get(ctx, config)
+		result, err = handler.Get(ctx, config)
 	case "delete", "remove":
-		return handler.Delete(ctx, config)
+		result, err = handler.Delete(ctx, config)
+	case "dist-tag-add", "dist-tag-move", "dist-tag-rm":
+		tagger, ok := handler.(packages.DistTagHandler)
+		if !ok {
+			return fmt.Errorf("%s packages do not support dist-tags", handler.GetPackageType().Label())
+		}
+		switch command {
+		case "dist-tag-add":
+			result, err = tagger.AddDistTag(ctx, config)
+		case "dist-tag-move":
+			result, err = tagger.MoveDistTag(ctx, config)
+		default:
+			result, err = tagger.RemoveDistTag(ctx, config)
+		}
 	default:
-		return fmt.Errorf("unsupported command: %s. Supported commands: push, pull, get, delete", command)
+		return fmt.Errorf("unsupported command: %s. Supported commands: push, pull, get, delete, dist-tag-add, dist-tag-move, dist-tag-rm", command)
 	}
+
+	// Report timeouts and interruptions distinctly from registry failures
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return fmt.Errorf("%s timed out after %s: %w", command, args.Timeout, err)
+		case context.Canceled:
+			return fmt.Errorf("%s interrupted: %w", command, err)
+		}
+		return err
+	}
+
+	// Export the result for downstream steps
+	return writeOutputs(os.Getenv(droneOutput), result)
 }
 
 // argsToConfig converts Args to packages.Config
@@ -130,6 +221,7 @@ func argsToConfig(args Args) packages.Config {
 		Project: args.Project,
 		ApiURL:  args.ApiURL,
 		PkgURL:  args.PkgURL,
+		Secrets: secretSettingValues(args.SecretSettings),
 
 		// Registry and artifact details
 		Registry:    registry,
@@ -138,12 +230,62 @@ func argsToConfig(args Args) packages.Config {
 		Description: args.Description,
 		Filename:    args.Filename,
 		PomFile:     args.PomFile,
+		Tag:         args.Tag,
+		PythonTags:  args.PythonTags,
+		GitTag:      args.DroneTag,
+		CommitSHA:   args.DroneCommitSHA,
+
+		// Generated Maven POM
+		GroupID:      args.GroupID,
+		ArtifactID:   args.ArtifactID,
+		Packaging:    args.Packaging,
+		Dependencies: args.Dependencies,
 
 		// Operation details
 		Source:      args.Source,
 		Destination: args.Destination,
 		Retries:     args.Retries,
+
+		// Retry backoff
+		RetryDelay:    args.RetryDelay,
+		RetryMaxDelay: args.RetryMaxDelay,
+
+		// Per-file timeout
+		FileTimeout: args.FileTimeout,
+
+		// Checksums
+		ExpectedSHA256:  args.ExpectedSHA256,
+		VerifyChecksum:  parseBoolOrDefault(false, args.VerifyChecksum),
+		UploadChecksums: parseBoolOrDefault(false, args.UploadChecksums),
+
+		// Directory push filters
+		Include:       args.Include,
+		Exclude:       args.Exclude,
+		IncludeHidden: parseBoolOrDefault(false, args.IncludeHidden),
+
+		// Multi-file uploads
+		Parallelism: args.Parallelism,
+		FailFast:    parseBoolOrDefault(false, args.FailFast),
+	}
+}
+
+// secretSettingValues returns the values of the named plugin settings, e.g.
+// "account" resolves to the value of PLUGIN_ACCOUNT
+func secretSettingValues(names []string) []string {
+	var values []string
+	for _, name := range names {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !strings.HasPrefix(name, "PLUGIN_") {
+			name = "PLUGIN_" + name
+		}
+		if value := os.Getenv(name); value != "" {
+			values = append(values, value)
+		}
 	}
+	return values
 }
 
 func parseBoolOrDefault(defaultValue bool, s string) bool {
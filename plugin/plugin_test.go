@@ -5,10 +5,15 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/harness/drone-har/plugin/packages"
+	"github.com/sirupsen/logrus"
 )
 
 func TestExec_MissingRegistry(t *testing.T) {
@@ -201,6 +206,40 @@ func TestExec_UnsupportedCommand(t *testing.T) {
 	}
 }
 
+func TestExec_DistTagUnsupportedPackageType(t *testing.T) {
+	args := Args{
+		Command:     "dist-tag-add",
+		PackageType: "generic",
+		Token:       "test-token",
+		Account:     "test-account",
+	}
+
+	err := Exec(context.Background(), args)
+	if err == nil || !strings.Contains(err.Error(), "do not support dist-tags") {
+		t.Errorf("Expected unsupported dist-tags error, got %v", err)
+	}
+}
+
+func TestExec_UploadChecksumsUnsupportedPackageType(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	args := Args{
+		Command:         "push",
+		PackageType:     "npm",
+		Registry:        "test-registry",
+		Token:           "test-token",
+		Account:         "test-account",
+		UploadChecksums: "true",
+	}
+
+	Exec(context.Background(), args)
+	if !strings.Contains(logs.String(), "upload_checksums is ignored for NPM packages") {
+		t.Errorf("Expected upload_checksums warning, got %q", logs.String())
+	}
+}
+
 func TestExec_DefaultCommand(t *testing.T) {
 	args := Args{
 		// No command specified - should default to push
@@ -371,3 +410,49 @@ func TestExec_DeleteCommand_MissingName(t *testing.T) {
 		t.Errorf("Expected 'artifact name must be set', got '%s'", err.Error())
 	}
 }
+
+func TestWriteOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.env")
+	if err := os.WriteFile(path, []byte("EXISTING=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &packages.Result{
+		PackageType: packages.Generic,
+		Registry:    "test-registry",
+		Name:        "test-artifact",
+		Version:     "1.0.0",
+		Filename:    "app.zip",
+		URL:         "https://pkg.harness.io/pkg/acct/test-registry/generic/test-artifact/1.0.0/app.zip",
+		Size:        42,
+		SHA256:      "abc123",
+		Metadata:    map[string]string{"build_info": "line one\nline two"},
+	}
+	if err := writeOutputs(path, result); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "EXISTING=1\n" +
+		"ARTIFACT_FILENAME=app.zip\n" +
+		"ARTIFACT_NAME=test-artifact\n" +
+		"ARTIFACT_PACKAGE_TYPE=generic\n" +
+		"ARTIFACT_REGISTRY=test-registry\n" +
+		"ARTIFACT_SHA256=abc123\n" +
+		"ARTIFACT_SIZE=42\n" +
+		"ARTIFACT_URL=https://pkg.harness.io/pkg/acct/test-registry/generic/test-artifact/1.0.0/app.zip\n" +
+		"ARTIFACT_VERSION=1.0.0\n" +
+		"BUILD_INFO=line one line two\n"
+	if string(data) != want {
+		t.Errorf("unexpected output file\n got: %q\nwant: %q", data, want)
+	}
+}
+
+func TestWriteOutputs_NotConfigured(t *testing.T) {
+	if err := writeOutputs("", &packages.Result{Name: "test-artifact"}); err != nil {
+		t.Errorf("expected no error without output file, got %v", err)
+	}
+}